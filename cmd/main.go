@@ -1,53 +1,106 @@
-package main
-
-import (
-	"fmt"
-	"log"
-	"net/http"
-
-	"github.com/gorilla/mux"
-	"github.com/rs/cors"
-
-	"github.com/mgdavidd/server-Eme-Mar/internal/db"
-	"github.com/mgdavidd/server-Eme-Mar/internal/handlers"
-	"github.com/mgdavidd/server-Eme-Mar/internal/routes"
-	"github.com/mgdavidd/server-Eme-Mar/internal/services"
-)
-
-func main() {
-	fmt.Println("Starting server...")
-
-	// DB
-	database := db.ConnectDB()
-	db.RunMigrations(database)
-
-	// Services
-	clientService := services.NewClientService(database)
-	insumoService := services.NewInsumoService(database)
-	moveService := services.NewMoveService(database)
-	productService := services.NewProductService(database)
-
-	// Handlers
-	clientHandler := handlers.NewClientHandler(clientService)
-	insumoHandler := handlers.NewInsumoHandler(insumoService)
-	moveHandler := handlers.NewMoveHandler(moveService)
-	productHandler := handlers.NewProductHandler(productService)
-
-	// Router
-	r := mux.NewRouter()
-	routes.RegisterRoutes(r, clientHandler, insumoHandler, moveHandler, productHandler)
-
-	// CORS
-	c := cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"*"},
-		AllowCredentials: false, // IMPORTANTE: con "*" no puedes usar credenciales
-	})
-
-	handler := c.Handler(r)
-
-	// Start server
-	log.Println("Server running on http://localhost:3000")
-	http.ListenAndServe(":3000", handler)
-}
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/cors"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/auth"
+	"github.com/mgdavidd/server-Eme-Mar/internal/db"
+	"github.com/mgdavidd/server-Eme-Mar/internal/handlers"
+	"github.com/mgdavidd/server-Eme-Mar/internal/routes"
+	"github.com/mgdavidd/server-Eme-Mar/internal/services"
+)
+
+func main() {
+	// DB
+	database := db.ConnectDB()
+
+	// Subcomandos de línea de comandos
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "migrate":
+			err = runMigrate(database, os.Args[2:])
+		case "user":
+			err = runUser(database, os.Args[2:])
+		case "reconcile":
+			err = runReconcile(database, os.Args[2:])
+		default:
+			err = fmt.Errorf("comando desconocido: %s", os.Args[1])
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	fmt.Println("Starting server...")
+	db.RunMigrations(database)
+
+	// Services
+	clientService := services.NewClientService(database)
+	insumoService := services.NewInsumoService(database)
+	moveService := services.NewMoveService(database)
+	productService := services.NewProductService(database)
+	saleService := services.NewSaleService(database)
+	userService := services.NewUserService(database)
+	cashService := services.NewCashSessionService(database)
+	accountService := services.NewAccountService(database)
+	supplierService := services.NewSupplierService(database)
+	orderService := services.NewPurchaseOrderService(database)
+	payableService := services.NewPayableService(database)
+	reconcileService := services.NewReconcileService(database)
+	auditService := services.NewAuditService(database)
+	expenseService := services.NewExpenseService(database)
+	recurringService := services.NewRecurringExpenseService(database)
+	reportService := services.NewReportService(database)
+
+	// Auth
+	authenticator := auth.NewAuthenticator(userService)
+	bootstrapOwner(userService)
+
+	// Handlers
+	authHandler := handlers.NewAuthHandler(userService, authenticator)
+	clientHandler := handlers.NewClientHandler(clientService)
+	insumoHandler := handlers.NewInsumoHandler(insumoService)
+	moveHandler := handlers.NewMoveHandler(moveService)
+	productHandler := handlers.NewProductHandler(productService)
+	saleHandler := handlers.NewSaleHandler(saleService)
+	cashHandler := handlers.NewCashSessionHandler(cashService)
+	accountHandler := handlers.NewAccountHandler(accountService)
+	supplierHandler := handlers.NewSupplierHandler(supplierService)
+	orderHandler := handlers.NewPurchaseOrderHandler(orderService)
+	payableHandler := handlers.NewPayableHandler(payableService)
+	reconcileHandler := handlers.NewReconcileHandler(reconcileService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	expenseHandler := handlers.NewExpenseHandler(expenseService)
+	recurringHandler := handlers.NewRecurringExpenseHandler(recurringService)
+	reportHandler := handlers.NewReportHandler(reportService)
+
+	// Router
+	r := mux.NewRouter()
+	routes.RegisterRoutes(r, authenticator, authHandler, clientHandler, insumoHandler, moveHandler, productHandler, saleHandler, cashHandler, accountHandler, supplierHandler, orderHandler, payableHandler, reconcileHandler, auditHandler, expenseHandler, recurringHandler, reportHandler)
+
+	// CORS
+	c := cors.New(cors.Options{
+		AllowedOrigins:   []string{"*"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"*"},
+		AllowCredentials: false, // IMPORTANTE: con "*" no puedes usar credenciales
+	})
+
+	handler := c.Handler(r)
+
+	// Gastos recurrentes: se revisan al arrancar y cada hora
+	go recurringService.RunScheduler(time.Hour)
+
+	// Start server
+	log.Println("Server running on http://localhost:3000")
+	http.ListenAndServe(":3000", handler)
+}
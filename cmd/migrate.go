@@ -0,0 +1,76 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/db"
+)
+
+const migrateUsage = "uso: migrate status | migrate up [version] | migrate down [pasos]"
+
+// runMigrate implementa `migrate status|up|down`.
+func runMigrate(database *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "status":
+		states, err := db.MigrationStatus(database)
+		if err != nil {
+			return err
+		}
+		for _, s := range states {
+			applied := "pendiente"
+			if s.AppliedAt != "" {
+				applied = "aplicada " + s.AppliedAt
+			}
+			fmt.Printf("%03d  %-40s %s\n", s.Version, s.Description, applied)
+		}
+		return nil
+
+	case "up":
+		target, err := optionalInt(args[1:], 0)
+		if err != nil {
+			return err
+		}
+		applied, err := db.MigrateUp(database, target)
+		for _, m := range applied {
+			fmt.Printf("↑ %03d %s\n", m.Version, m.Description)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("no hay migraciones pendientes")
+		}
+		return nil
+
+	case "down":
+		steps, err := optionalInt(args[1:], 1)
+		if err != nil {
+			return err
+		}
+		reverted, err := db.MigrateDown(database, steps)
+		for _, m := range reverted {
+			fmt.Printf("↓ %03d %s\n", m.Version, m.Description)
+		}
+		return err
+	}
+
+	return errors.New(migrateUsage)
+}
+
+func optionalInt(args []string, def int) (int, error) {
+	if len(args) == 0 {
+		return def, nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("número inválido: %q", args[0])
+	}
+	return n, nil
+}
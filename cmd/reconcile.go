@@ -0,0 +1,63 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os/user"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/db"
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+	"github.com/mgdavidd/server-Eme-Mar/internal/services"
+)
+
+const reconcileUsage = "uso: reconcile [--fix]"
+
+// runReconcile implementa `reconcile`: muestra los saldos descuadrados y con
+// --fix corrige los de clientes y cuentas.
+func runReconcile(database *sql.DB, args []string) error {
+	fix := false
+	switch {
+	case len(args) == 0:
+	case len(args) == 1 && args[0] == "--fix":
+		fix = true
+	default:
+		return errors.New(reconcileUsage)
+	}
+
+	db.RunMigrations(database)
+
+	svc := services.NewReconcileService(database)
+	var report models.ReconcileReport
+	var err error
+	if fix {
+		actor := "cli"
+		if u, uerr := user.Current(); uerr == nil {
+			actor = "cli:" + u.Username
+		}
+		report, err = svc.Repair(actor)
+	} else {
+		report, err = svc.Check()
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(report.Drifts) == 0 {
+		fmt.Println("todo cuadra ✔")
+		return nil
+	}
+
+	for _, d := range report.Drifts {
+		status := "solo reporte"
+		switch {
+		case d.Fixable && fix:
+			status = "corregido"
+		case d.Fixable:
+			status = "se corrige con --fix"
+		}
+		fmt.Printf("%-8s %5d  %-30s guardado %12s  calculado %12s  dif %12s  %s\n",
+			d.Entity, d.EntityID, d.Name, d.Stored, d.Computed, d.Diff, status)
+	}
+	return nil
+}
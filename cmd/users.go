@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/db"
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+	"github.com/mgdavidd/server-Eme-Mar/internal/services"
+)
+
+const userUsage = "uso: user create <usuario> <owner|cashier|readonly>  (la contraseña se lee de la entrada estándar)"
+
+// runUser implementa `user create`, útil para crear el primer dueño.
+func runUser(database *sql.DB, args []string) error {
+	if len(args) != 3 || args[0] != "create" {
+		return errors.New(userUsage)
+	}
+
+	db.RunMigrations(database)
+
+	fmt.Print("contraseña: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return err
+	}
+
+	u := models.User{
+		Username: args[1],
+		Role:     args[2],
+		Password: strings.TrimRight(password, "\r\n"),
+	}
+	if err := services.NewUserService(database).Create(&u); err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			return errors.New("usuario repetido, rol inválido o contraseña de menos de 6 caracteres")
+		}
+		return err
+	}
+
+	fmt.Printf("usuario %s (%s) creado\n", u.Username, u.Role)
+	return nil
+}
+
+// bootstrapOwner crea el dueño inicial desde ADMIN_USERNAME/ADMIN_PASSWORD
+// cuando todavía no hay ningún usuario.
+func bootstrapOwner(users *services.UserService) {
+	n, err := users.Count()
+	if err != nil {
+		log.Fatal("Error contando usuarios: ", err)
+	}
+	if n > 0 {
+		return
+	}
+
+	username := os.Getenv("ADMIN_USERNAME")
+	password := os.Getenv("ADMIN_PASSWORD")
+	if username == "" || password == "" {
+		log.Println("⚠ No hay usuarios: crea uno con `user create` o define ADMIN_USERNAME y ADMIN_PASSWORD")
+		return
+	}
+
+	u := models.User{Username: username, Password: password, Role: models.RoleOwner}
+	if err := users.Create(&u); err != nil {
+		log.Fatal("Error creando el usuario inicial: ", err)
+	}
+	log.Printf("Usuario dueño %s creado ✔", u.Username)
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
+)
+
+type ctxKey struct{}
+
+// FromContext devuelve el usuario autenticado de la petición.
+func FromContext(ctx context.Context) (Claims, bool) {
+	c, ok := ctx.Value(ctxKey{}).(Claims)
+	return c, ok
+}
+
+// Middleware exige un token Bearer válido de un usuario que todavía existe.
+// El rol se toma de la base y no del token, así que un cambio de rol aplica
+// en la siguiente petición. Los usuarios de solo lectura únicamente pueden
+// hacer GET.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || token == "" {
+			utils.RespondError(w, 401, "no autenticado")
+			return
+		}
+
+		claims, err := a.Parse(token)
+		if err != nil {
+			utils.RespondError(w, 401, err.Error())
+			return
+		}
+
+		u, err := a.users.Lookup(claims.UserID)
+		if errors.Is(err, ErrUserGone) {
+			utils.RespondError(w, 401, err.Error())
+			return
+		}
+		if err != nil {
+			utils.RespondError(w, 500, "error verificando usuario")
+			return
+		}
+		claims.Username = u.Username
+		claims.Role = u.Role
+
+		if claims.Role == models.RoleReadOnly && r.Method != http.MethodGet {
+			utils.RespondError(w, 403, "permiso denegado")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxKey{}, claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Require deja pasar solo a los roles indicados. Va dentro de Middleware.
+func Require(roles ...string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := FromContext(r.Context())
+			if !ok {
+				utils.RespondError(w, 401, "no autenticado")
+				return
+			}
+			for _, role := range roles {
+				if claims.Role == role {
+					next(w, r)
+					return
+				}
+			}
+			utils.RespondError(w, 403, "permiso denegado")
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	hashIterations = 210000
+	hashKeyLen     = 32
+)
+
+var ErrBadHash = errors.New("hash de contraseña con formato desconocido")
+
+// HashPassword devuelve "pbkdf2-sha256$<iteraciones>$<sal>$<hash>".
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, hashIterations, hashKeyLen)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s",
+		hashIterations,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// CheckPassword compara en tiempo constante.
+func CheckPassword(hash, password string) (bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false, ErrBadHash
+	}
+
+	iter, err := strconv.Atoi(parts[1])
+	if err != nil || iter <= 0 {
+		return false, ErrBadHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false, ErrBadHash
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false, ErrBadHash
+	}
+
+	got, err := pbkdf2.Key(sha256.New, password, salt, iter, len(want))
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(got, want) == 1, nil
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+var (
+	ErrInvalidToken = errors.New("token inválido o vencido")
+	ErrUserGone     = errors.New("el usuario ya no existe")
+)
+
+// UserLookup da el usuario tal como está hoy en la base, para que un token
+// de un usuario borrado o con otro rol no siga valiendo. Si el usuario ya no
+// existe devuelve ErrUserGone.
+type UserLookup interface {
+	Lookup(id int64) (models.User, error)
+}
+
+// Claims es lo que viaja firmado dentro del token.
+type Claims struct {
+	UserID   int64  `json:"uid"`
+	Username string `json:"usr"`
+	Role     string `json:"role"`
+	Expires  int64  `json:"exp"`
+}
+
+// Authenticator firma y verifica tokens con HMAC-SHA256.
+// Formato: base64url(claims JSON) + "." + base64url(firma).
+type Authenticator struct {
+	secret []byte
+	ttl    time.Duration
+	users  UserLookup
+}
+
+// NewAuthenticator toma la clave de AUTH_SECRET. Sin ella usa una clave
+// aleatoria, y los tokens dejan de servir al reiniciar el servidor.
+func NewAuthenticator(users UserLookup) *Authenticator {
+	secret := []byte(os.Getenv("AUTH_SECRET"))
+	if len(secret) == 0 {
+		log.Println("⚠ AUTH_SECRET no definido, las sesiones se pierden al reiniciar")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatal("No se pudo generar AUTH_SECRET:", err)
+		}
+	}
+
+	return &Authenticator{secret: secret, ttl: 12 * time.Hour, users: users}
+}
+
+// Issue genera un token para el usuario y devuelve cuándo vence.
+func (a *Authenticator) Issue(u models.User) (string, time.Time, error) {
+	exp := time.Now().Add(a.ttl)
+	payload, err := json.Marshal(Claims{
+		UserID:   u.ID,
+		Username: u.Username,
+		Role:     u.Role,
+		Expires:  exp.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + a.sign(body), exp, nil
+}
+
+// Parse valida firma y vencimiento.
+func (a *Authenticator) Parse(token string) (Claims, error) {
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(a.sign(body))) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	var c Claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	if time.Now().Unix() > c.Expires {
+		return Claims{}, ErrInvalidToken
+	}
+
+	return c, nil
+}
+
+func (a *Authenticator) sign(body string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,350 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Migration es un cambio de esquema numerado. Up y Down se ejecutan dentro de
+// una transacción con las foreign keys desactivadas, para poder reconstruir
+// tablas al estilo de SQLite (crear nueva, copiar, borrar, renombrar).
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(tx *sql.Tx) error
+	Down        func(tx *sql.Tx) error
+}
+
+// MigrationState describe una migración conocida y si ya fue aplicada.
+type MigrationState struct {
+	Version     int
+	Description string
+	AppliedAt   string // vacío si está pendiente
+}
+
+var ErrMigrationLocked = errors.New("otra instancia está ejecutando migraciones")
+
+const (
+	lockWait  = 30 * time.Second
+	lockStale = 10 * time.Minute
+)
+
+// RunMigrations aplica todas las migraciones pendientes. Se usa al arrancar el servidor.
+func RunMigrations(db *sql.DB) {
+	applied, err := MigrateUp(db, 0)
+	if err != nil {
+		log.Fatal("Error ejecutando migraciones: ", err)
+	}
+
+	for _, m := range applied {
+		log.Printf("Migración %03d aplicada: %s", m.Version, m.Description)
+	}
+	log.Println("Migraciones ejecutadas ✔")
+}
+
+// MigrateUp aplica las migraciones pendientes hasta target (0 = la última).
+func MigrateUp(db *sql.DB, target int) ([]Migration, error) {
+	var applied []Migration
+
+	err := withMigrationLock(db, func(conn *sql.Conn) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range sortedMigrations() {
+			if target > 0 && m.Version > target {
+				break
+			}
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+
+			err := runMigration(conn, m, func(tx *sql.Tx) error {
+				if err := m.Up(tx); err != nil {
+					return err
+				}
+				_, err := tx.Exec(`
+					INSERT INTO schema_migrations (version, description, applied_at)
+					VALUES (?, ?, ?)
+				`, m.Version, m.Description, time.Now().Format("2006-01-02 15:04:05"))
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migración %03d (%s): %w", m.Version, m.Description, err)
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// MigrateDown revierte las últimas steps migraciones aplicadas.
+func MigrateDown(db *sql.DB, steps int) ([]Migration, error) {
+	var reverted []Migration
+
+	err := withMigrationLock(db, func(conn *sql.Conn) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		list := sortedMigrations()
+		for i := len(list) - 1; i >= 0 && len(reverted) < steps; i-- {
+			m := list[i]
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+			if m.Down == nil {
+				return fmt.Errorf("migración %03d (%s) no se puede revertir", m.Version, m.Description)
+			}
+
+			err := runMigration(conn, m, func(tx *sql.Tx) error {
+				if err := m.Down(tx); err != nil {
+					return err
+				}
+				_, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migración %03d (%s): %w", m.Version, m.Description, err)
+			}
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// MigrationStatus lista todas las migraciones conocidas con su estado.
+func MigrationStatus(db *sql.DB) ([]MigrationState, error) {
+	if err := ensureMigrationTables(db); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := map[int]string{}
+	for rows.Next() {
+		var v int
+		var at string
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		done[v] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	states := []MigrationState{}
+	for _, m := range sortedMigrations() {
+		states = append(states, MigrationState{
+			Version:     m.Version,
+			Description: m.Description,
+			AppliedAt:   done[m.Version],
+		})
+	}
+	return states, nil
+}
+
+func sortedMigrations() []Migration {
+	list := make([]Migration, len(migrations))
+	copy(list, migrations)
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list
+}
+
+func ensureMigrationTables(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			description TEXT NOT NULL,
+			applied_at TEXT NOT NULL
+		);`)
+	if err != nil {
+		return err
+	}
+
+	// Una sola fila (id = 1) mientras alguien migra
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			owner TEXT NOT NULL,
+			locked_at TEXT NOT NULL
+		);`)
+	return err
+}
+
+func appliedVersions(conn *sql.Conn) (map[int]struct{}, error) {
+	rows, err := conn.QueryContext(context.Background(), `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := map[int]struct{}{}
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		done[v] = struct{}{}
+	}
+	return done, rows.Err()
+}
+
+// withMigrationLock toma el lock de migraciones y ejecuta fn sobre una sola
+// conexión, porque PRAGMA foreign_keys es por conexión.
+func withMigrationLock(db *sql.DB, fn func(conn *sql.Conn) error) (err error) {
+	if err := ensureMigrationTables(db); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d:%d", host, os.Getpid(), time.Now().UnixNano())
+
+	deadline := time.Now().Add(lockWait)
+	for {
+		_, err = conn.ExecContext(ctx, `
+			INSERT INTO schema_migrations_lock (id, owner, locked_at)
+			VALUES (1, ?, ?)
+		`, owner, time.Now().Format(time.RFC3339))
+		if err == nil {
+			break
+		}
+		if !strings.Contains(strings.ToLower(err.Error()), "constraint") {
+			return err
+		}
+
+		// Un lock viejo es de una instancia que murió a mitad de migración
+		_, err = conn.ExecContext(ctx, `
+			DELETE FROM schema_migrations_lock
+			WHERE id = 1 AND locked_at < ?
+		`, time.Now().Add(-lockStale).Format(time.RFC3339))
+		if err != nil {
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			return ErrMigrationLocked
+		}
+		time.Sleep(time.Second)
+	}
+
+	defer func() {
+		_, derr := conn.ExecContext(ctx, `DELETE FROM schema_migrations_lock WHERE owner = ?`, owner)
+		if err == nil {
+			err = derr
+		}
+	}()
+
+	if _, err = conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return err
+	}
+	defer func() {
+		_, perr := conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+		if err == nil {
+			err = perr
+		}
+	}()
+
+	return fn(conn)
+}
+
+func runMigration(conn *sql.Conn, m Migration, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	// Con las foreign keys apagadas nadie las valida; lo hacemos antes del commit
+	rows, err := tx.Query(`PRAGMA foreign_key_check`)
+	if err != nil {
+		return err
+	}
+	broken := rows.Next()
+	rows.Close()
+	if broken {
+		return errors.New("la migración deja foreign keys rotas")
+	}
+
+	return nil
+}
+
+// execAll devuelve un paso de migración que ejecuta las sentencias en orden.
+func execAll(queries ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, q := range queries {
+			if _, err := tx.Exec(q); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func columnExists(tx *sql.Tx, table, column string) (bool, error) {
+	var n int
+	err := tx.QueryRow(`
+		SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?
+	`, table, column).Scan(&n)
+	return n > 0, err
+}
+
+// rebuildTable cambia la definición de una tabla como recomienda SQLite:
+// crear <table>_new, copiar, borrar la vieja y renombrar. sel son las
+// expresiones que se copian a cada columna de cols.
+func rebuildTable(tx *sql.Tx, table, create string, cols, sel []string) error {
+	queries := []string{
+		create,
+		fmt.Sprintf(`INSERT INTO %s_new (%s) SELECT %s FROM %s;`,
+			table, strings.Join(cols, ", "), strings.Join(sel, ", "), table),
+		fmt.Sprintf(`DROP TABLE %s;`, table),
+		fmt.Sprintf(`ALTER TABLE %s_new RENAME TO %s;`, table, table),
+	}
+	for _, q := range queries {
+		if _, err := tx.Exec(q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
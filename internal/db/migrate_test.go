@@ -0,0 +1,171 @@
+package db
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// legacyDB crea una base como la dejaba el servidor antes de las migraciones
+// versionadas: esquema inicial con el dinero en REAL y algunos datos.
+func legacyDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	database, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "legacy.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	seed := append([]string{}, initialSchema...)
+	seed = append(seed,
+		`INSERT INTO clientes (id, nombre, telefono, deuda) VALUES (1, 'Ana', '300', 782.0);`,
+		`INSERT INTO insumos (id, nombre, unidad_medida, stock_actual, minimo_sugerido, precio_unitario)
+		VALUES (1, 'Harina', 'g', 1000, 100, 12.345);`,
+		`INSERT INTO productos (id, nombre, costo_total, precio, foto) VALUES (1, 'Pan', 0, 2500.5, NULL);`,
+		`INSERT INTO producto_insumos (producto_id, insumo_id, cantidad_insumo) VALUES (1, 1, 2);`,
+		`UPDATE caja SET saldo = 1500.25 WHERE id = 1;`,
+		`INSERT INTO movimientos (descripcion, tipo, monto, fecha) VALUES ('Venta', 'ingreso', 99.99, '2024-01-02 10:00');`,
+		`INSERT INTO credit_sales (id, client_id, total, remaining_balance, date) VALUES (1, 1, 1000.0, 782.0, '2024-01-03 09:00');`,
+		`INSERT INTO credit_sale_items (credit_sale_id, product_id, quantity) VALUES (1, 1, 2);`,
+		`INSERT INTO credit_payments (credit_sale_id, amount, date) VALUES (1, 218.0, '2024-01-04 09:00');`,
+	)
+	for _, q := range seed {
+		if _, err := database.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	return database
+}
+
+func latestVersion() int {
+	list := sortedMigrations()
+	return list[len(list)-1].Version
+}
+
+func currentVersion(t *testing.T, database *sql.DB) int {
+	t.Helper()
+	var v int
+	if err := database.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// expectValue compara el resultado de una consulta de un solo valor.
+func expectValue(t *testing.T, database *sql.DB, query string, want any) {
+	t.Helper()
+	var got any
+	if err := database.QueryRow(query).Scan(&got); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	if got != want {
+		t.Errorf("%s = %v (%T), se esperaba %v (%T)", query, got, got, want, want)
+	}
+}
+
+func TestMigrateUpConvertsLegacyData(t *testing.T) {
+	database := legacyDB(t)
+
+	applied, err := MigrateUp(database, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(migrations) {
+		t.Errorf("se aplicaron %d migraciones, se esperaban %d", len(applied), len(migrations))
+	}
+	if v := currentVersion(t, database); v != latestVersion() || v < 20 {
+		t.Fatalf("versión %d, se esperaba %d", v, latestVersion())
+	}
+
+	// Dinero en centavos
+	expectValue(t, database, `SELECT deuda FROM clientes WHERE id = 1`, int64(78200))
+	expectValue(t, database, `SELECT typeof(deuda) FROM clientes WHERE id = 1`, "integer")
+	expectValue(t, database, `SELECT precio_unitario FROM insumos WHERE id = 1`, int64(1235))
+	expectValue(t, database, `SELECT precio FROM productos WHERE id = 1`, int64(250050))
+	expectValue(t, database, `SELECT monto FROM movimientos`, int64(9999))
+	expectValue(t, database, `SELECT remaining_balance FROM credit_sales WHERE id = 1`, int64(78200))
+	expectValue(t, database, `SELECT amount FROM credit_payments`, int64(21800))
+
+	// La caja vieja es la cuenta 1 y los movimientos quedan en ella
+	expectValue(t, database, `SELECT saldo FROM accounts WHERE id = 1`, int64(150025))
+	expectValue(t, database, `SELECT kind FROM accounts WHERE id = 1`, "efectivo")
+	expectValue(t, database, `SELECT cuenta_id FROM movimientos`, int64(1))
+	expectValue(t, database, `SELECT COUNT(*) FROM sqlite_master WHERE name = 'caja'`, int64(0))
+
+	// El fiado viejo queda como venta con sus líneas, a precio del producto
+	expectValue(t, database, `SELECT COUNT(*) FROM sales WHERE is_credit = 1 AND credit_sale_id = 1`, int64(1))
+	expectValue(t, database, `SELECT unit_price FROM sale_items`, int64(250050))
+	expectValue(t, database, `SELECT quantity FROM sale_items`, int64(2))
+
+	// El abono viejo no fue con saldo a favor: tuvo su propia fecha
+	expectValue(t, database, `SELECT from_credit FROM credit_payments`, int64(0))
+
+	// Volver a correr no aplica nada
+	again, err := MigrateUp(database, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 0 {
+		t.Errorf("la segunda corrida aplicó %d migraciones", len(again))
+	}
+}
+
+func TestMigrateUpToTarget(t *testing.T) {
+	database := legacyDB(t)
+
+	if _, err := MigrateUp(database, 3); err != nil {
+		t.Fatal(err)
+	}
+	if v := currentVersion(t, database); v != 3 {
+		t.Fatalf("versión %d, se esperaba 3", v)
+	}
+	expectValue(t, database, `SELECT deuda FROM clientes WHERE id = 1`, int64(78200))
+	expectValue(t, database, `SELECT saldo FROM caja WHERE id = 1`, int64(150025))
+}
+
+func TestMigrateDownRollsBackEverything(t *testing.T) {
+	database := legacyDB(t)
+
+	if _, err := MigrateUp(database, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Hasta la 2 los datos vuelven a pesos en REAL y la caja reaparece
+	reverted, err := MigrateDown(database, latestVersion()-2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reverted) != latestVersion()-2 {
+		t.Errorf("se revirtieron %d migraciones, se esperaban %d", len(reverted), latestVersion()-2)
+	}
+	if v := currentVersion(t, database); v != 2 {
+		t.Fatalf("versión %d, se esperaba 2", v)
+	}
+	expectValue(t, database, `SELECT deuda FROM clientes WHERE id = 1`, 782.0)
+	expectValue(t, database, `SELECT saldo FROM caja WHERE id = 1`, 1500.25)
+	expectValue(t, database, `SELECT amount FROM credit_payments`, 218.0)
+	expectValue(t, database, `SELECT COUNT(*) FROM sqlite_master WHERE name IN ('accounts', 'sales')`, int64(0))
+
+	// Y del todo: no queda ninguna tabla del esquema
+	if _, err := MigrateDown(database, len(migrations)); err != nil {
+		t.Fatal(err)
+	}
+	if v := currentVersion(t, database); v != 0 {
+		t.Fatalf("versión %d, se esperaba 0", v)
+	}
+	expectValue(t, database, `
+		SELECT COUNT(*) FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'schema_migrations%' AND name NOT LIKE 'sqlite_%'
+	`, int64(0))
+
+	// Desde vacío se puede volver a subir
+	if _, err := MigrateUp(database, 0); err != nil {
+		t.Fatal(err)
+	}
+	if v := currentVersion(t, database); v != latestVersion() {
+		t.Fatalf("versión %d, se esperaba %d", v, latestVersion())
+	}
+}
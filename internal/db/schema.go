@@ -1,159 +1,966 @@
-package db
-
-import (
-	"database/sql"
-	"log"
-)
-
-func RunMigrations(db *sql.DB) {
-	queries := []string{
-		// CLIENTES
-		`CREATE TABLE IF NOT EXISTS clientes (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			nombre TEXT NOT NULL,
-			telefono TEXT,
-			deuda REAL DEFAULT 0
-		);`,
-
-		// INSUMOS
-		`CREATE TABLE IF NOT EXISTS insumos (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			nombre TEXT NOT NULL,
-			unidad_medida TEXT NOT NULL,
-			stock_actual REAL NOT NULL DEFAULT 0,
-			minimo_sugerido REAL NOT NULL DEFAULT 0,
-			precio_unitario REAL NOT NULL
-		);`,
-
-		// MOVIMIENTOS
-		`CREATE TABLE IF NOT EXISTS movimientos (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			descripcion TEXT NOT NULL,
-			tipo TEXT NOT NULL,
-			monto REAL NOT NULL,
-			fecha TEXT NOT NULL,
-			cliente_id INTEGER NULL
-		);`,
-
-		// PRODUCTOS
-		`CREATE TABLE IF NOT EXISTS productos (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			nombre TEXT NOT NULL,
-			costo_total REAL NOT NULL,
-			precio REAL NOT NULL,
-			foto BLOB NULL
-		);`,
-
-		// PRODUCTO - INSUMOS
-		`CREATE TABLE IF NOT EXISTS producto_insumos (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			producto_id INTEGER NOT NULL,
-			insumo_id INTEGER NOT NULL,
-			cantidad_insumo REAL NOT NULL,
-
-			FOREIGN KEY (producto_id) REFERENCES productos(id) ON DELETE CASCADE,
-			FOREIGN KEY (insumo_id) REFERENCES insumos(id) ON DELETE CASCADE
-		);`,
-
-		// CAJA
-		`CREATE TABLE IF NOT EXISTS caja (
-			id INTEGER PRIMARY KEY CHECK (id = 1),
-			saldo REAL NOT NULL
-		);`,
-
-		/* ───────────────────────────────────────────── */
-		/*        NUEVAS TABLAS DE VENTAS A CRÉDITO       */
-		/* ───────────────────────────────────────────── */
-
-		// CREDIT SALES (ventas fiadas)
-		`CREATE TABLE IF NOT EXISTS credit_sales (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			client_id INTEGER NOT NULL,
-			total REAL NOT NULL,
-			remaining_balance REAL NOT NULL,
-			date TEXT NOT NULL,
-
-			FOREIGN KEY (client_id) REFERENCES clientes(id)
-		);`,
-
-		// ITEMS DE LAS VENTAS FIADAS
-		`CREATE TABLE IF NOT EXISTS credit_sale_items (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			credit_sale_id INTEGER NOT NULL,
-			product_id INTEGER NOT NULL,
-			quantity REAL NOT NULL,
-
-			FOREIGN KEY (credit_sale_id) REFERENCES credit_sales(id) ON DELETE CASCADE,
-			FOREIGN KEY (product_id) REFERENCES productos(id)
-		);`,
-
-		// ABONOS A VENTAS FIADAS
-		`CREATE TABLE IF NOT EXISTS credit_payments (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			credit_sale_id INTEGER NOT NULL,
-			amount REAL NOT NULL,
-			date TEXT NOT NULL,
-
-			FOREIGN KEY (credit_sale_id) REFERENCES credit_sales(id) ON DELETE CASCADE
-		);`,
-
-		`CREATE INDEX IF NOT EXISTS idx_prod_ins_producto ON producto_insumos(producto_id);`,
-		`CREATE INDEX IF NOT EXISTS idx_prod_ins_insumo ON producto_insumos(insumo_id);`,
-
-		`CREATE INDEX IF NOT EXISTS idx_credit_sales_client ON credit_sales(client_id);`,
-		`CREATE INDEX IF NOT EXISTS idx_credit_sale_items_sale ON credit_sale_items(credit_sale_id);`,
-		`CREATE INDEX IF NOT EXISTS idx_credit_payments_sale ON credit_payments(credit_sale_id);`,
-
-		`CREATE TRIGGER IF NOT EXISTS recalc_after_insert_prod_ins
-		AFTER INSERT ON producto_insumos
-		BEGIN
-			UPDATE productos SET costo_total = (
-				SELECT COALESCE(SUM(i.precio_unitario * pi.cantidad_insumo), 0)
-				FROM producto_insumos pi JOIN insumos i ON pi.insumo_id = i.id
-				WHERE pi.producto_id = NEW.producto_id
-			) WHERE id = NEW.producto_id;
-		END;`,
-
-		`CREATE TRIGGER IF NOT EXISTS recalc_after_update_prod_ins
-		AFTER UPDATE ON producto_insumos
-		BEGIN
-			UPDATE productos SET costo_total = (
-				SELECT COALESCE(SUM(i.precio_unitario * pi.cantidad_insumo), 0)
-				FROM producto_insumos pi JOIN insumos i ON pi.insumo_id = i.id
-				WHERE pi.producto_id = NEW.producto_id
-			) WHERE id = NEW.producto_id;
-		END;`,
-
-		`CREATE TRIGGER IF NOT EXISTS recalc_after_delete_prod_ins
-		AFTER DELETE ON producto_insumos
-		BEGIN
-			UPDATE productos SET costo_total = (
-				SELECT COALESCE(SUM(i.precio_unitario * pi.cantidad_insumo), 0)
-				FROM producto_insumos pi JOIN insumos i ON pi.insumo_id = i.id
-				WHERE pi.producto_id = OLD.producto_id
-			) WHERE id = OLD.producto_id;
-		END;`,
-
-		`CREATE TRIGGER IF NOT EXISTS recalc_after_update_insumo_precio
-		AFTER UPDATE OF precio_unitario ON insumos
-		BEGIN
-			UPDATE productos SET costo_total = (
-				SELECT COALESCE(SUM(i.precio_unitario * pi.cantidad_insumo), 0)
-				FROM producto_insumos pi JOIN insumos i ON pi.insumo_id = i.id
-				WHERE pi.producto_id = productos.id
-			) WHERE id IN (SELECT producto_id FROM producto_insumos WHERE insumo_id = NEW.id);
-		END;`,
-
-		`CREATE UNIQUE INDEX IF NOT EXISTS idx_producto_insumo_unique
-		ON producto_insumos(producto_id, insumo_id);`,
-	}
-
-	for _, q := range queries {
-		_, err := db.Exec(q)
-		if err != nil {
-			log.Fatal("Error creando tablas: ", err)
-		}
-	}
-
-	log.Println("Migraciones ejecutadas ✔")
-}
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// migrations es el historial del esquema. Nunca se edita una migración ya
+// publicada: cada cambio nuevo va en una migración nueva al final.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "esquema inicial",
+		Up:          execAll(initialSchema...),
+		Down: execAll(
+			`DROP TRIGGER IF EXISTS recalc_after_insert_prod_ins;`,
+			`DROP TRIGGER IF EXISTS recalc_after_update_prod_ins;`,
+			`DROP TRIGGER IF EXISTS recalc_after_delete_prod_ins;`,
+			`DROP TRIGGER IF EXISTS recalc_after_update_insumo_precio;`,
+			`DROP TABLE IF EXISTS credit_payments;`,
+			`DROP TABLE IF EXISTS credit_sale_items;`,
+			`DROP TABLE IF EXISTS credit_sales;`,
+			`DROP TABLE IF EXISTS caja;`,
+			`DROP TABLE IF EXISTS producto_insumos;`,
+			`DROP TABLE IF EXISTS productos;`,
+			`DROP TABLE IF EXISTS movimientos;`,
+			`DROP TABLE IF EXISTS insumos;`,
+			`DROP TABLE IF EXISTS clientes;`,
+		),
+	},
+	{
+		// Las bases creadas antes de guardar el cliente en cada movimiento no tienen la columna
+		Version:     2,
+		Description: "movimientos.cliente_id",
+		Up: func(tx *sql.Tx) error {
+			exists, err := columnExists(tx, "movimientos", "cliente_id")
+			if err != nil || exists {
+				return err
+			}
+			_, err = tx.Exec(`ALTER TABLE movimientos ADD COLUMN cliente_id INTEGER NULL;`)
+			return err
+		},
+		Down: execAll(`ALTER TABLE movimientos DROP COLUMN cliente_id;`),
+	},
+	{
+		// El dinero pasa de REAL en pesos a INTEGER en centavos (models.Money)
+		Version:     3,
+		Description: "dinero en centavos",
+		Up: func(tx *sql.Tx) error {
+			return convertMoneyColumns(tx, "INTEGER", "CAST(ROUND(%s * 100) AS INTEGER)", moneyCostExpr)
+		},
+		Down: func(tx *sql.Tx) error {
+			return convertMoneyColumns(tx, "REAL", "%s / 100.0", legacyCostExpr)
+		},
+	},
+	{
+		// Toda venta (contado o fiada) queda en sales con sus líneas. Las ventas
+		// fiadas anteriores se copian con el precio y costo actuales del producto;
+		// las de contado viejas solo existen como texto en movimientos y no se migran.
+		Version:     4,
+		Description: "ventas con líneas de detalle",
+		Up: execAll(
+			`CREATE TABLE sales (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				client_id INTEGER NOT NULL,
+				total INTEGER NOT NULL,
+				cost INTEGER NOT NULL,
+				is_credit INTEGER NOT NULL DEFAULT 0,
+				credit_sale_id INTEGER NULL,
+				date TEXT NOT NULL,
+
+				FOREIGN KEY (client_id) REFERENCES clientes(id),
+				FOREIGN KEY (credit_sale_id) REFERENCES credit_sales(id)
+			);`,
+
+			`CREATE TABLE sale_items (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				sale_id INTEGER NOT NULL,
+				product_id INTEGER NOT NULL,
+				quantity INTEGER NOT NULL,
+				unit_price INTEGER NOT NULL,
+				unit_cost INTEGER NOT NULL,
+
+				FOREIGN KEY (sale_id) REFERENCES sales(id) ON DELETE CASCADE,
+				FOREIGN KEY (product_id) REFERENCES productos(id)
+			);`,
+
+			`CREATE INDEX idx_sales_date ON sales(date);`,
+			`CREATE INDEX idx_sales_client ON sales(client_id);`,
+			`CREATE INDEX idx_sale_items_sale ON sale_items(sale_id);`,
+			`CREATE INDEX idx_sale_items_product ON sale_items(product_id);`,
+
+			`ALTER TABLE movimientos ADD COLUMN venta_id INTEGER NULL REFERENCES sales(id);`,
+
+			`INSERT INTO sales (client_id, total, cost, is_credit, credit_sale_id, date)
+			SELECT client_id, total, 0, 1, id, date FROM credit_sales ORDER BY id;`,
+
+			`INSERT INTO sale_items (sale_id, product_id, quantity, unit_price, unit_cost)
+			SELECT s.id, csi.product_id, CAST(csi.quantity AS INTEGER),
+				COALESCE(p.precio, 0), COALESCE(p.costo_total, 0)
+			FROM credit_sale_items csi
+			JOIN sales s ON s.credit_sale_id = csi.credit_sale_id
+			LEFT JOIN productos p ON p.id = csi.product_id;`,
+
+			`UPDATE sales SET cost = (
+				SELECT COALESCE(SUM(unit_cost * quantity), 0)
+				FROM sale_items WHERE sale_id = sales.id
+			);`,
+		),
+		Down: execAll(
+			`ALTER TABLE movimientos DROP COLUMN venta_id;`,
+			`DROP TABLE sale_items;`,
+			`DROP TABLE sales;`,
+		),
+	},
+	{
+		Version:     5,
+		Description: "anulaciones y devoluciones de ventas",
+		Up: execAll(
+			`ALTER TABLE sales ADD COLUMN voided_at TEXT NULL;`,
+			`ALTER TABLE sale_items ADD COLUMN returned_quantity INTEGER NOT NULL DEFAULT 0;`,
+
+			// Cada anulación o devolución parcial
+			`CREATE TABLE sale_returns (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				sale_id INTEGER NOT NULL,
+				kind TEXT NOT NULL, -- 'anulacion' | 'devolucion'
+				amount INTEGER NOT NULL, -- valor de lo devuelto a precio de venta
+				refunded INTEGER NOT NULL, -- lo que salió de caja
+				reason TEXT NOT NULL DEFAULT '',
+				date TEXT NOT NULL,
+
+				FOREIGN KEY (sale_id) REFERENCES sales(id)
+			);`,
+
+			`CREATE TABLE sale_return_items (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				return_id INTEGER NOT NULL,
+				sale_item_id INTEGER NOT NULL,
+				quantity INTEGER NOT NULL,
+
+				FOREIGN KEY (return_id) REFERENCES sale_returns(id) ON DELETE CASCADE,
+				FOREIGN KEY (sale_item_id) REFERENCES sale_items(id)
+			);`,
+
+			`CREATE INDEX idx_sale_returns_sale ON sale_returns(sale_id);`,
+		),
+		Down: execAll(
+			`DROP TABLE sale_return_items;`,
+			`DROP TABLE sale_returns;`,
+			`ALTER TABLE sale_items DROP COLUMN returned_quantity;`,
+			`ALTER TABLE sales DROP COLUMN voided_at;`,
+		),
+	},
+	{
+		Version:     6,
+		Description: "usuarios y roles",
+		Up: execAll(
+			`CREATE TABLE users (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				username TEXT NOT NULL UNIQUE,
+				password_hash TEXT NOT NULL,
+				role TEXT NOT NULL CHECK (role IN ('owner', 'cashier', 'readonly')),
+				created_at TEXT NOT NULL
+			);`,
+		),
+		Down: execAll(`DROP TABLE users;`),
+	},
+	{
+		// Sesiones de caja (apertura y cierre) y el origen de cada movimiento.
+		// El origen de los movimientos viejos se deduce de la descripción.
+		Version:     7,
+		Description: "sesiones de caja",
+		Up: execAll(
+			`CREATE TABLE cash_sessions (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				opened_at TEXT NOT NULL,
+				opened_by TEXT NOT NULL DEFAULT '',
+				opening_amount INTEGER NOT NULL, -- base contada al abrir
+				closed_at TEXT NULL,
+				closed_by TEXT NULL,
+				expected_amount INTEGER NULL,
+				counted_amount INTEGER NULL,
+				difference INTEGER NULL, -- contado - esperado
+				notes TEXT NOT NULL DEFAULT ''
+			);`,
+
+			// Solo una sesión abierta a la vez
+			`CREATE UNIQUE INDEX idx_cash_sessions_open
+			ON cash_sessions((closed_at IS NULL)) WHERE closed_at IS NULL;`,
+
+			`ALTER TABLE movimientos ADD COLUMN sesion_id INTEGER NULL REFERENCES cash_sessions(id);`,
+			`ALTER TABLE movimientos ADD COLUMN origen TEXT NOT NULL DEFAULT '';`,
+
+			`UPDATE movimientos SET origen = CASE
+				WHEN venta_id IS NOT NULL AND tipo = 'ingreso' THEN 'venta'
+				WHEN venta_id IS NOT NULL THEN 'devolucion'
+				WHEN descripcion = 'Abono a crédito' THEN 'abono'
+				WHEN descripcion LIKE 'Surtido de insumo:%' THEN 'surtido'
+				WHEN tipo = 'ingreso' AND descripcion LIKE '%- % x %' THEN 'venta'
+				ELSE 'ajuste'
+			END;`,
+
+			`CREATE INDEX idx_movimientos_sesion ON movimientos(sesion_id);`,
+		),
+		Down: execAll(
+			`DROP INDEX idx_movimientos_sesion;`,
+			`ALTER TABLE movimientos DROP COLUMN origen;`,
+			`ALTER TABLE movimientos DROP COLUMN sesion_id;`,
+			`DROP TABLE cash_sessions;`,
+		),
+	},
+	{
+		// caja (una sola fila) pasa a ser la cuenta 1 de accounts
+		Version:     8,
+		Description: "cuentas de dinero",
+		Up: execAll(
+			`CREATE TABLE accounts (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL UNIQUE,
+				kind TEXT NOT NULL CHECK (kind IN ('efectivo', 'banco', 'billetera')),
+				saldo INTEGER NOT NULL DEFAULT 0
+			);`,
+
+			`INSERT INTO accounts (id, name, kind, saldo)
+			VALUES (1, 'Caja', 'efectivo', COALESCE((SELECT saldo FROM caja WHERE id = 1), 0));`,
+
+			`DROP TABLE caja;`,
+
+			`ALTER TABLE movimientos ADD COLUMN cuenta_id INTEGER NULL REFERENCES accounts(id);`,
+			`UPDATE movimientos SET cuenta_id = 1;`,
+			`CREATE INDEX idx_movimientos_cuenta ON movimientos(cuenta_id);`,
+		),
+		Down: execAll(
+			`CREATE TABLE caja (
+				id INTEGER PRIMARY KEY CHECK (id = 1),
+				saldo INTEGER NOT NULL
+			);`,
+			`INSERT INTO caja (id, saldo) SELECT 1, COALESCE(SUM(saldo), 0) FROM accounts;`,
+			`DROP INDEX idx_movimientos_cuenta;`,
+			`DELETE FROM movimientos WHERE tipo = 'transferencia';`,
+			`ALTER TABLE movimientos DROP COLUMN cuenta_id;`,
+			`DROP TABLE accounts;`,
+		),
+	},
+	{
+		// Productos que se fabrican por tandas y se venden desde su propio stock
+		Version:     9,
+		Description: "inventario de producto terminado",
+		Up: execAll(
+			`ALTER TABLE productos ADD COLUMN stock_actual INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE productos ADD COLUMN usa_stock INTEGER NOT NULL DEFAULT 0;`,
+
+			`CREATE TABLE production_batches (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				product_id INTEGER NOT NULL,
+				quantity INTEGER NOT NULL, -- unidades según receta
+				yield INTEGER NOT NULL,    -- unidades que salieron de verdad
+				cost INTEGER NOT NULL,
+				unit_cost INTEGER NOT NULL,
+				notes TEXT NOT NULL DEFAULT '',
+				created_by TEXT NOT NULL DEFAULT '',
+				date TEXT NOT NULL,
+
+				FOREIGN KEY (product_id) REFERENCES productos(id)
+			);`,
+
+			`CREATE TABLE production_batch_insumos (
+				batch_id INTEGER NOT NULL,
+				insumo_id INTEGER NOT NULL,
+				quantity REAL NOT NULL,
+				unit_cost INTEGER NOT NULL,
+
+				PRIMARY KEY (batch_id, insumo_id),
+				FOREIGN KEY (batch_id) REFERENCES production_batches(id) ON DELETE CASCADE,
+				FOREIGN KEY (insumo_id) REFERENCES insumos(id)
+			);`,
+
+			`CREATE INDEX idx_production_batches_product ON production_batches(product_id);`,
+
+			// De dónde salió cada línea vendida, para que la devolución reponga lo mismo
+			`ALTER TABLE sale_items ADD COLUMN source TEXT NOT NULL DEFAULT 'insumos';`,
+		),
+		Down: execAll(
+			`ALTER TABLE sale_items DROP COLUMN source;`,
+			`DROP TABLE production_batch_insumos;`,
+			`DROP TABLE production_batches;`,
+			`ALTER TABLE productos DROP COLUMN usa_stock;`,
+			`ALTER TABLE productos DROP COLUMN stock_actual;`,
+		),
+	},
+	{
+		// Cada surtido queda como lote con su costo real; el precio del insumo
+		// se recalcula según su método de costeo
+		Version:     10,
+		Description: "lotes de insumos y costo promedio",
+		Up: execAll(
+			`ALTER TABLE insumos ADD COLUMN metodo_costo TEXT NOT NULL DEFAULT 'promedio'
+				CHECK (metodo_costo IN ('ultimo', 'promedio', 'fifo'));`,
+
+			`CREATE TABLE supplies (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				insumo_id INTEGER NOT NULL,
+				kind TEXT NOT NULL DEFAULT 'compra', -- compra, inicial, ajuste, devolucion
+				quantity REAL NOT NULL,
+				remaining REAL NOT NULL, -- lo que queda del lote, para FIFO
+				unit_cost INTEGER NOT NULL,
+				total INTEGER NOT NULL,
+				account_id INTEGER NULL,
+				date TEXT NOT NULL,
+
+				FOREIGN KEY (insumo_id) REFERENCES insumos(id),
+				FOREIGN KEY (account_id) REFERENCES accounts(id)
+			);`,
+
+			`CREATE INDEX idx_supplies_insumo ON supplies(insumo_id, date);`,
+
+			// El stock que ya había entra como un lote inicial al precio actual
+			`INSERT INTO supplies (insumo_id, kind, quantity, remaining, unit_cost, total, date)
+			SELECT id, 'inicial', stock_actual, stock_actual, precio_unitario,
+				CAST(ROUND(precio_unitario * stock_actual) AS INTEGER),
+				strftime('%Y-%m-%d %H:%M', 'now', 'localtime')
+			FROM insumos WHERE stock_actual > 0;`,
+
+			`CREATE TABLE insumo_price_history (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				insumo_id INTEGER NOT NULL,
+				previous_price INTEGER NOT NULL,
+				price INTEGER NOT NULL,
+				method TEXT NOT NULL, -- ultimo, promedio, fifo o manual
+				supply_id INTEGER NULL,
+				date TEXT NOT NULL,
+
+				FOREIGN KEY (insumo_id) REFERENCES insumos(id) ON DELETE CASCADE,
+				FOREIGN KEY (supply_id) REFERENCES supplies(id)
+			);`,
+
+			`CREATE INDEX idx_insumo_price_history_insumo ON insumo_price_history(insumo_id);`,
+		),
+		Down: execAll(
+			`DROP TABLE insumo_price_history;`,
+			`DROP TABLE supplies;`,
+			`ALTER TABLE insumos DROP COLUMN metodo_costo;`,
+		),
+	},
+	{
+		Version:     11,
+		Description: "proveedores",
+		Up: execAll(
+			`CREATE TABLE suppliers (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL,
+				phone TEXT NOT NULL DEFAULT '',
+				notes TEXT NOT NULL DEFAULT ''
+			);`,
+
+			`ALTER TABLE supplies ADD COLUMN supplier_id INTEGER NULL REFERENCES suppliers(id);`,
+			`CREATE INDEX idx_supplies_supplier ON supplies(supplier_id, date);`,
+		),
+		Down: execAll(
+			`DROP INDEX idx_supplies_supplier;`,
+			`ALTER TABLE supplies DROP COLUMN supplier_id;`,
+			`DROP TABLE suppliers;`,
+		),
+	},
+	{
+		Version:     12,
+		Description: "órdenes de compra",
+		Up: execAll(
+			`CREATE TABLE purchase_orders (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				supplier_id INTEGER NULL,
+				status TEXT NOT NULL DEFAULT 'abierta'
+					CHECK (status IN ('abierta', 'parcial', 'recibida', 'cancelada')),
+				notes TEXT NOT NULL DEFAULT '',
+				created_by TEXT NOT NULL DEFAULT '',
+				created_at TEXT NOT NULL,
+				closed_at TEXT NULL,
+
+				FOREIGN KEY (supplier_id) REFERENCES suppliers(id)
+			);`,
+
+			`CREATE TABLE purchase_order_items (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				order_id INTEGER NOT NULL,
+				insumo_id INTEGER NOT NULL,
+				quantity REAL NOT NULL,
+				unit_price INTEGER NOT NULL, -- precio esperado
+				received REAL NOT NULL DEFAULT 0,
+
+				FOREIGN KEY (order_id) REFERENCES purchase_orders(id) ON DELETE CASCADE,
+				FOREIGN KEY (insumo_id) REFERENCES insumos(id)
+			);`,
+
+			`CREATE INDEX idx_purchase_orders_status ON purchase_orders(status);`,
+			`CREATE INDEX idx_purchase_order_items_order ON purchase_order_items(order_id);`,
+
+			`ALTER TABLE supplies ADD COLUMN order_item_id INTEGER NULL REFERENCES purchase_order_items(id);`,
+		),
+		Down: execAll(
+			`ALTER TABLE supplies DROP COLUMN order_item_id;`,
+			`DROP TABLE purchase_order_items;`,
+			`DROP TABLE purchase_orders;`,
+		),
+	},
+	{
+		// Lo mismo que credit_sales/credit_payments pero con proveedores
+		Version:     13,
+		Description: "cuentas por pagar",
+		Up: execAll(
+			`CREATE TABLE credit_purchases (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				supplier_id INTEGER NOT NULL,
+				supply_id INTEGER NULL,
+				description TEXT NOT NULL DEFAULT '',
+				total INTEGER NOT NULL,
+				remaining_balance INTEGER NOT NULL,
+				date TEXT NOT NULL,
+				due_date TEXT NOT NULL, -- 2006-01-02
+
+				FOREIGN KEY (supplier_id) REFERENCES suppliers(id),
+				FOREIGN KEY (supply_id) REFERENCES supplies(id)
+			);`,
+
+			`CREATE TABLE credit_purchase_payments (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				credit_purchase_id INTEGER NOT NULL,
+				amount INTEGER NOT NULL,
+				account_id INTEGER NOT NULL DEFAULT 1,
+				date TEXT NOT NULL,
+
+				FOREIGN KEY (credit_purchase_id) REFERENCES credit_purchases(id) ON DELETE CASCADE,
+				FOREIGN KEY (account_id) REFERENCES accounts(id)
+			);`,
+
+			`CREATE INDEX idx_credit_purchases_supplier ON credit_purchases(supplier_id);`,
+			`CREATE INDEX idx_credit_purchases_due ON credit_purchases(due_date) WHERE remaining_balance > 0;`,
+		),
+		Down: execAll(
+			`DROP TABLE credit_purchase_payments;`,
+			`DROP TABLE credit_purchases;`,
+		),
+	},
+	{
+		Version:     14,
+		Description: "saldo a favor de clientes",
+		Up: execAll(
+			`ALTER TABLE clientes ADD COLUMN saldo_favor INTEGER NOT NULL DEFAULT 0;`,
+		),
+		Down: execAll(
+			`ALTER TABLE clientes DROP COLUMN saldo_favor;`,
+		),
+	},
+	{
+		Version:     15,
+		Description: "límites y plazos de fiado",
+		Up: execAll(
+			`ALTER TABLE clientes ADD COLUMN limite_credito INTEGER NULL;`, // NULL = sin límite
+			`ALTER TABLE clientes ADD COLUMN plazo_dias INTEGER NULL;`,
+			`ALTER TABLE credit_sales ADD COLUMN due_date TEXT NULL;`,
+
+			// Los fiados viejos vencen a los 30 días, el plazo por defecto
+			`UPDATE credit_sales SET due_date = date(date, '+30 days');`,
+			`CREATE INDEX idx_credit_sales_due ON credit_sales(client_id, due_date) WHERE remaining_balance > 0;`,
+		),
+		Down: execAll(
+			`DROP INDEX idx_credit_sales_due;`,
+			`ALTER TABLE credit_sales DROP COLUMN due_date;`,
+			`ALTER TABLE clientes DROP COLUMN plazo_dias;`,
+			`ALTER TABLE clientes DROP COLUMN limite_credito;`,
+		),
+	},
+	{
+		// Cada corrección que hace el comando reconcile
+		Version:     16,
+		Description: "registro de conciliaciones",
+		Up: execAll(
+			`CREATE TABLE reconcile_log (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				date TEXT NOT NULL,
+				actor TEXT NOT NULL DEFAULT '',
+				entity TEXT NOT NULL, -- 'cliente' | 'cuenta'
+				entity_id INTEGER NOT NULL,
+				before INTEGER NOT NULL,
+				after INTEGER NOT NULL
+			);`,
+		),
+		Down: execAll(
+			`DROP TABLE reconcile_log;`,
+		),
+	},
+	{
+		Version:     17,
+		Description: "bitácora de auditoría",
+		Up: execAll(
+			`CREATE TABLE audit_log (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				date TEXT NOT NULL,
+				actor TEXT NOT NULL DEFAULT '',
+				action TEXT NOT NULL, -- 'crear' | 'editar' | 'eliminar'
+				entity TEXT NOT NULL,
+				entity_id INTEGER NOT NULL,
+				before TEXT NULL, -- JSON
+				after TEXT NULL -- JSON
+			);`,
+			`CREATE INDEX idx_audit_log_entity ON audit_log(entity, entity_id);`,
+			`CREATE INDEX idx_audit_log_date ON audit_log(date);`,
+		),
+		Down: execAll(
+			`DROP TABLE audit_log;`,
+		),
+	},
+	{
+		Version:     18,
+		Description: "archivar clientes, productos e insumos",
+		Up: execAll(
+			`ALTER TABLE clientes ADD COLUMN archived_at TEXT NULL;`,
+			`ALTER TABLE productos ADD COLUMN archived_at TEXT NULL;`,
+			`ALTER TABLE insumos ADD COLUMN archived_at TEXT NULL;`,
+		),
+		Down: execAll(
+			`ALTER TABLE insumos DROP COLUMN archived_at;`,
+			`ALTER TABLE productos DROP COLUMN archived_at;`,
+			`ALTER TABLE clientes DROP COLUMN archived_at;`,
+		),
+	},
+	{
+		Version:     19,
+		Description: "gastos con categorías",
+		Up: execAll(
+			`CREATE TABLE expense_categories (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL UNIQUE
+			);`,
+
+			`INSERT INTO expense_categories (name) VALUES
+				('arriendo'), ('servicios'), ('nomina'), ('transporte'), ('otros');`,
+
+			`CREATE TABLE expenses (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				category_id INTEGER NOT NULL,
+				account_id INTEGER NOT NULL DEFAULT 1,
+				amount INTEGER NOT NULL,
+				notes TEXT NOT NULL DEFAULT '',
+				receipt BLOB NULL,
+				date TEXT NOT NULL,
+				created_by TEXT NOT NULL DEFAULT '',
+
+				FOREIGN KEY (category_id) REFERENCES expense_categories(id),
+				FOREIGN KEY (account_id) REFERENCES accounts(id)
+			);`,
+
+			`CREATE INDEX idx_expenses_date ON expenses(date);`,
+
+			`ALTER TABLE movimientos ADD COLUMN categoria_id INTEGER NULL REFERENCES expense_categories(id);`,
+		),
+		Down: execAll(
+			`ALTER TABLE movimientos DROP COLUMN categoria_id;`,
+			`DROP TABLE expenses;`,
+			`DROP TABLE expense_categories;`,
+		),
+	},
+	{
+		Version:     20,
+		Description: "gastos recurrentes",
+		Up: execAll(
+			`CREATE TABLE recurring_expenses (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				category_id INTEGER NOT NULL,
+				account_id INTEGER NOT NULL DEFAULT 1,
+				amount INTEGER NOT NULL,
+				notes TEXT NOT NULL DEFAULT '',
+				frequency TEXT NOT NULL CHECK (frequency IN ('semanal', 'mensual')),
+				day INTEGER NOT NULL, -- día del mes en que cae; los meses cortos usan el último
+				next_date TEXT NOT NULL, -- 2006-01-02
+				requires_confirmation INTEGER NOT NULL DEFAULT 1,
+				active INTEGER NOT NULL DEFAULT 1,
+				created_by TEXT NOT NULL DEFAULT '',
+
+				FOREIGN KEY (category_id) REFERENCES expense_categories(id),
+				FOREIGN KEY (account_id) REFERENCES accounts(id)
+			);`,
+
+			`CREATE TABLE scheduled_expenses (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				recurring_id INTEGER NOT NULL,
+				due_date TEXT NOT NULL,
+				amount INTEGER NOT NULL,
+				status TEXT NOT NULL CHECK (status IN ('pendiente', 'registrado', 'descartado')),
+				expense_id INTEGER NULL,
+				resolved_at TEXT NULL,
+				resolved_by TEXT NULL,
+
+				UNIQUE (recurring_id, due_date),
+				FOREIGN KEY (recurring_id) REFERENCES recurring_expenses(id),
+				FOREIGN KEY (expense_id) REFERENCES expenses(id)
+			);`,
+
+			`CREATE INDEX idx_scheduled_expenses_pending ON scheduled_expenses(due_date) WHERE status = 'pendiente';`,
+		),
+		Down: execAll(
+			`DROP TABLE scheduled_expenses;`,
+			`DROP TABLE recurring_expenses;`,
+		),
+	},
+	{
+		Version:     21,
+		Description: "abonos con saldo a favor",
+		Up: execAll(
+			// from_credit marca la parte de un fiado que se pagó con saldo a
+			// favor: al devolverla vuelve al saldo a favor, no sale de caja
+			`ALTER TABLE credit_payments ADD COLUMN from_credit INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE sale_returns ADD COLUMN credited INTEGER NOT NULL DEFAULT 0;`,
+
+			// Los de antes se reconocen porque la venta los guardaba con su
+			// misma fecha y sin movimiento de abono
+			`UPDATE credit_payments SET from_credit = 1
+			WHERE date = (SELECT cs.date FROM credit_sales cs WHERE cs.id = credit_payments.credit_sale_id)
+			AND NOT EXISTS (
+				SELECT 1 FROM movimientos m
+				JOIN credit_sales cs ON cs.id = credit_payments.credit_sale_id
+				WHERE m.origen = 'abono' AND m.cliente_id = cs.client_id AND m.fecha = credit_payments.date
+			);`,
+		),
+		Down: execAll(
+			`ALTER TABLE sale_returns DROP COLUMN credited;`,
+			`ALTER TABLE credit_payments DROP COLUMN from_credit;`,
+		),
+	},
+}
+
+// initialSchema es el esquema tal como lo creaba el servidor antes de tener
+// migraciones versionadas; por eso todo es IF NOT EXISTS.
+var initialSchema = []string{
+	// CLIENTES
+	`CREATE TABLE IF NOT EXISTS clientes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		nombre TEXT NOT NULL,
+		telefono TEXT,
+		deuda REAL DEFAULT 0
+	);`,
+
+	// INSUMOS
+	`CREATE TABLE IF NOT EXISTS insumos (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		nombre TEXT NOT NULL,
+		unidad_medida TEXT NOT NULL,
+		stock_actual REAL NOT NULL DEFAULT 0,
+		minimo_sugerido REAL NOT NULL DEFAULT 0,
+		precio_unitario REAL NOT NULL
+	);`,
+
+	// MOVIMIENTOS
+	`CREATE TABLE IF NOT EXISTS movimientos (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		descripcion TEXT NOT NULL,
+		tipo TEXT NOT NULL,
+		monto REAL NOT NULL,
+		fecha TEXT NOT NULL
+	);`,
+
+	// PRODUCTOS
+	`CREATE TABLE IF NOT EXISTS productos (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		nombre TEXT NOT NULL,
+		costo_total REAL NOT NULL,
+		precio REAL NOT NULL,
+		foto BLOB NULL
+	);`,
+
+	// PRODUCTO - INSUMOS
+	`CREATE TABLE IF NOT EXISTS producto_insumos (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		producto_id INTEGER NOT NULL,
+		insumo_id INTEGER NOT NULL,
+		cantidad_insumo REAL NOT NULL,
+
+		FOREIGN KEY (producto_id) REFERENCES productos(id) ON DELETE CASCADE,
+		FOREIGN KEY (insumo_id) REFERENCES insumos(id) ON DELETE CASCADE
+	);`,
+
+	// CAJA
+	`CREATE TABLE IF NOT EXISTS caja (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		saldo REAL NOT NULL
+	);`,
+	`INSERT OR IGNORE INTO caja (id, saldo) VALUES (1, 0);`,
+
+	/* ───────────────────────────────────────────── */
+	/*        NUEVAS TABLAS DE VENTAS A CRÉDITO       */
+	/* ───────────────────────────────────────────── */
+
+	// CREDIT SALES (ventas fiadas)
+	`CREATE TABLE IF NOT EXISTS credit_sales (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		client_id INTEGER NOT NULL,
+		total REAL NOT NULL,
+		remaining_balance REAL NOT NULL,
+		date TEXT NOT NULL,
+
+		FOREIGN KEY (client_id) REFERENCES clientes(id)
+	);`,
+
+	// ITEMS DE LAS VENTAS FIADAS
+	`CREATE TABLE IF NOT EXISTS credit_sale_items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		credit_sale_id INTEGER NOT NULL,
+		product_id INTEGER NOT NULL,
+		quantity REAL NOT NULL,
+
+		FOREIGN KEY (credit_sale_id) REFERENCES credit_sales(id) ON DELETE CASCADE,
+		FOREIGN KEY (product_id) REFERENCES productos(id)
+	);`,
+
+	// ABONOS A VENTAS FIADAS
+	`CREATE TABLE IF NOT EXISTS credit_payments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		credit_sale_id INTEGER NOT NULL,
+		amount REAL NOT NULL,
+		date TEXT NOT NULL,
+
+		FOREIGN KEY (credit_sale_id) REFERENCES credit_sales(id) ON DELETE CASCADE
+	);`,
+
+	`CREATE INDEX IF NOT EXISTS idx_prod_ins_producto ON producto_insumos(producto_id);`,
+	`CREATE INDEX IF NOT EXISTS idx_prod_ins_insumo ON producto_insumos(insumo_id);`,
+
+	`CREATE INDEX IF NOT EXISTS idx_credit_sales_client ON credit_sales(client_id);`,
+	`CREATE INDEX IF NOT EXISTS idx_credit_sale_items_sale ON credit_sale_items(credit_sale_id);`,
+	`CREATE INDEX IF NOT EXISTS idx_credit_payments_sale ON credit_payments(credit_sale_id);`,
+
+	`CREATE TRIGGER IF NOT EXISTS recalc_after_insert_prod_ins
+	AFTER INSERT ON producto_insumos
+	BEGIN
+		UPDATE productos SET costo_total = (
+			SELECT COALESCE(SUM(i.precio_unitario * pi.cantidad_insumo), 0)
+			FROM producto_insumos pi JOIN insumos i ON pi.insumo_id = i.id
+			WHERE pi.producto_id = NEW.producto_id
+		) WHERE id = NEW.producto_id;
+	END;`,
+
+	`CREATE TRIGGER IF NOT EXISTS recalc_after_update_prod_ins
+	AFTER UPDATE ON producto_insumos
+	BEGIN
+		UPDATE productos SET costo_total = (
+			SELECT COALESCE(SUM(i.precio_unitario * pi.cantidad_insumo), 0)
+			FROM producto_insumos pi JOIN insumos i ON pi.insumo_id = i.id
+			WHERE pi.producto_id = NEW.producto_id
+		) WHERE id = NEW.producto_id;
+	END;`,
+
+	`CREATE TRIGGER IF NOT EXISTS recalc_after_delete_prod_ins
+	AFTER DELETE ON producto_insumos
+	BEGIN
+		UPDATE productos SET costo_total = (
+			SELECT COALESCE(SUM(i.precio_unitario * pi.cantidad_insumo), 0)
+			FROM producto_insumos pi JOIN insumos i ON pi.insumo_id = i.id
+			WHERE pi.producto_id = OLD.producto_id
+		) WHERE id = OLD.producto_id;
+	END;`,
+
+	`CREATE TRIGGER IF NOT EXISTS recalc_after_update_insumo_precio
+	AFTER UPDATE OF precio_unitario ON insumos
+	BEGIN
+		UPDATE productos SET costo_total = (
+			SELECT COALESCE(SUM(i.precio_unitario * pi.cantidad_insumo), 0)
+			FROM producto_insumos pi JOIN insumos i ON pi.insumo_id = i.id
+			WHERE pi.producto_id = productos.id
+		) WHERE id IN (SELECT producto_id FROM producto_insumos WHERE insumo_id = NEW.id);
+	END;`,
+
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_producto_insumo_unique
+	ON producto_insumos(producto_id, insumo_id);`,
+}
+
+// moneyTables son las tablas con columnas de dinero. El CREATE lleva %[1]s
+// donde va el tipo de esas columnas.
+var moneyTables = []struct {
+	name   string
+	create string
+	cols   []string
+	money  []string
+	extra  []string // índices que se pierden al reconstruir la tabla
+}{
+	{
+		name: "clientes",
+		create: `CREATE TABLE clientes_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			nombre TEXT NOT NULL,
+			telefono TEXT,
+			deuda %[1]s DEFAULT 0
+		);`,
+		cols:  []string{"id", "nombre", "telefono", "deuda"},
+		money: []string{"deuda"},
+	},
+	{
+		name: "insumos",
+		create: `CREATE TABLE insumos_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			nombre TEXT NOT NULL,
+			unidad_medida TEXT NOT NULL,
+			stock_actual REAL NOT NULL DEFAULT 0,
+			minimo_sugerido REAL NOT NULL DEFAULT 0,
+			precio_unitario %[1]s NOT NULL
+		);`,
+		cols:  []string{"id", "nombre", "unidad_medida", "stock_actual", "minimo_sugerido", "precio_unitario"},
+		money: []string{"precio_unitario"},
+	},
+	{
+		name: "movimientos",
+		create: `CREATE TABLE movimientos_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			descripcion TEXT NOT NULL,
+			tipo TEXT NOT NULL,
+			monto %[1]s NOT NULL,
+			fecha TEXT NOT NULL,
+			cliente_id INTEGER NULL
+		);`,
+		cols:  []string{"id", "descripcion", "tipo", "monto", "fecha", "cliente_id"},
+		money: []string{"monto"},
+	},
+	{
+		name: "productos",
+		create: `CREATE TABLE productos_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			nombre TEXT NOT NULL,
+			costo_total %[1]s NOT NULL,
+			precio %[1]s NOT NULL,
+			foto BLOB NULL
+		);`,
+		cols:  []string{"id", "nombre", "costo_total", "precio", "foto"},
+		money: []string{"costo_total", "precio"},
+	},
+	{
+		name: "caja",
+		create: `CREATE TABLE caja_new (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			saldo %[1]s NOT NULL
+		);`,
+		cols:  []string{"id", "saldo"},
+		money: []string{"saldo"},
+	},
+	{
+		name: "credit_sales",
+		create: `CREATE TABLE credit_sales_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			client_id INTEGER NOT NULL,
+			total %[1]s NOT NULL,
+			remaining_balance %[1]s NOT NULL,
+			date TEXT NOT NULL,
+
+			FOREIGN KEY (client_id) REFERENCES clientes(id)
+		);`,
+		cols:  []string{"id", "client_id", "total", "remaining_balance", "date"},
+		money: []string{"total", "remaining_balance"},
+		extra: []string{`CREATE INDEX IF NOT EXISTS idx_credit_sales_client ON credit_sales(client_id);`},
+	},
+	{
+		name: "credit_payments",
+		create: `CREATE TABLE credit_payments_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			credit_sale_id INTEGER NOT NULL,
+			amount %[1]s NOT NULL,
+			date TEXT NOT NULL,
+
+			FOREIGN KEY (credit_sale_id) REFERENCES credit_sales(id) ON DELETE CASCADE
+		);`,
+		cols:  []string{"id", "credit_sale_id", "amount", "date"},
+		money: []string{"amount"},
+		extra: []string{`CREATE INDEX IF NOT EXISTS idx_credit_payments_sale ON credit_payments(credit_sale_id);`},
+	},
+}
+
+// Expresiones de costo_total para los triggers de recálculo
+const (
+	legacyCostExpr = `COALESCE(SUM(i.precio_unitario * pi.cantidad_insumo), 0)`
+	moneyCostExpr  = `CAST(ROUND(COALESCE(SUM(i.precio_unitario * pi.cantidad_insumo), 0)) AS INTEGER)`
+)
+
+// costTriggers son los triggers que mantienen productos.costo_total al día.
+func costTriggers(costExpr string) []string {
+	return []string{
+		`CREATE TRIGGER recalc_after_insert_prod_ins
+		AFTER INSERT ON producto_insumos
+		BEGIN
+			UPDATE productos SET costo_total = (
+				SELECT ` + costExpr + `
+				FROM producto_insumos pi JOIN insumos i ON pi.insumo_id = i.id
+				WHERE pi.producto_id = NEW.producto_id
+			) WHERE id = NEW.producto_id;
+		END;`,
+
+		`CREATE TRIGGER recalc_after_update_prod_ins
+		AFTER UPDATE ON producto_insumos
+		BEGIN
+			UPDATE productos SET costo_total = (
+				SELECT ` + costExpr + `
+				FROM producto_insumos pi JOIN insumos i ON pi.insumo_id = i.id
+				WHERE pi.producto_id = NEW.producto_id
+			) WHERE id = NEW.producto_id;
+		END;`,
+
+		`CREATE TRIGGER recalc_after_delete_prod_ins
+		AFTER DELETE ON producto_insumos
+		BEGIN
+			UPDATE productos SET costo_total = (
+				SELECT ` + costExpr + `
+				FROM producto_insumos pi JOIN insumos i ON pi.insumo_id = i.id
+				WHERE pi.producto_id = OLD.producto_id
+			) WHERE id = OLD.producto_id;
+		END;`,
+
+		`CREATE TRIGGER recalc_after_update_insumo_precio
+		AFTER UPDATE OF precio_unitario ON insumos
+		BEGIN
+			UPDATE productos SET costo_total = (
+				SELECT ` + costExpr + `
+				FROM producto_insumos pi JOIN insumos i ON pi.insumo_id = i.id
+				WHERE pi.producto_id = productos.id
+			) WHERE id IN (SELECT producto_id FROM producto_insumos WHERE insumo_id = NEW.id);
+		END;`,
+	}
+}
+
+var costTriggerNames = []string{
+	"recalc_after_insert_prod_ins",
+	"recalc_after_update_prod_ins",
+	"recalc_after_delete_prod_ins",
+	"recalc_after_update_insumo_precio",
+}
+
+// convertMoneyColumns reconstruye las tablas de dinero con el tipo colType,
+// copiando cada columna de dinero a través de convert (un formato con %s).
+// Los triggers se borran antes porque SQLite no deja renombrar tablas mientras
+// un trigger apunta a una que no existe.
+func convertMoneyColumns(tx *sql.Tx, colType, convert, costExpr string) error {
+	for _, name := range costTriggerNames {
+		if _, err := tx.Exec(`DROP TRIGGER IF EXISTS ` + name); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range moneyTables {
+		sel := make([]string, len(t.cols))
+		for i, c := range t.cols {
+			sel[i] = c
+			for _, m := range t.money {
+				if c == m {
+					sel[i] = fmt.Sprintf(convert, c)
+				}
+			}
+		}
+
+		err := rebuildTable(tx, t.name, fmt.Sprintf(t.create, colType), t.cols, sel)
+		if err != nil {
+			return err
+		}
+		for _, q := range t.extra {
+			if _, err := tx.Exec(q); err != nil {
+				return err
+			}
+		}
+	}
+
+	return execAll(costTriggers(costExpr)...)(tx)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+	"github.com/mgdavidd/server-Eme-Mar/internal/services"
+	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
+)
+
+type AccountHandler struct {
+	Service *services.AccountService
+}
+
+func NewAccountHandler(s *services.AccountService) *AccountHandler {
+	return &AccountHandler{Service: s}
+}
+
+func (h *AccountHandler) GetAccounts(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Service.GetAll()
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo cuentas")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var a models.MoneyAccount
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&a); err != nil {
+		utils.RespondError(w, 400, "json inválido")
+		return
+	}
+
+	err := h.Service.Create(&a)
+	if errors.Is(err, services.ErrInvalidInput) {
+		utils.RespondError(w, 400, "nombre repetido o tipo inválido (efectivo, banco, billetera)")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error creando cuenta")
+		return
+	}
+
+	utils.RespondJSON(w, 201, a)
+}
+
+func (h *AccountHandler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	var a models.MoneyAccount
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&a); err != nil {
+		utils.RespondError(w, 400, "json inválido")
+		return
+	}
+	a.ID = int64(id)
+
+	err = h.Service.Update(&a)
+	if errors.Is(err, services.ErrInvalidInput) {
+		utils.RespondError(w, 400, "nombre repetido o tipo inválido (efectivo, banco, billetera)")
+		return
+	}
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "cuenta no encontrada")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error actualizando cuenta")
+		return
+	}
+
+	utils.RespondJSON(w, 200, a)
+}
+
+// POST /accounts/transfers {"from_account_id": 1, "to_account_id": 2, "amount": 50000}
+func (h *AccountHandler) Transfer(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var t models.Transfer
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&t); err != nil {
+		if strings.Contains(err.Error(), "unknown field") {
+			utils.RespondError(w, 400, "campo desconocido en el cuerpo de la solicitud")
+			return
+		}
+		utils.RespondError(w, 400, "error al parsear el cuerpo de la solicitud")
+		return
+	}
+
+	err := h.Service.Transfer(t)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInput):
+			utils.RespondError(w, 400, "monto inválido o cuentas iguales")
+		case errors.Is(err, services.ErrAccountNotFound):
+			utils.RespondError(w, 404, err.Error())
+		default:
+			utils.RespondError(w, 500, "error realizando transferencia")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, 200, map[string]string{"message": "transferencia realizada con éxito"})
+}
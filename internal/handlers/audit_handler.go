@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+	"github.com/mgdavidd/server-Eme-Mar/internal/services"
+	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
+)
+
+type AuditHandler struct {
+	Service *services.AuditService
+}
+
+func NewAuditHandler(s *services.AuditService) *AuditHandler {
+	return &AuditHandler{Service: s}
+}
+
+// GET /audit?entity=insumo&entity_id=3&actor=admin&from=2025-01-01&to=2025-01-31&limit=100
+func (h *AuditHandler) GetAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := models.AuditFilter{
+		Entity: q.Get("entity"),
+		Actor:  q.Get("actor"),
+		From:   q.Get("from"),
+		To:     q.Get("to"),
+	}
+
+	if !validDates(f.From, f.To) {
+		utils.RespondError(w, 400, "fecha inválida, usa AAAA-MM-DD")
+		return
+	}
+
+	if v := q.Get("entity_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			utils.RespondError(w, 400, "entity_id inválido")
+			return
+		}
+		f.EntityID = id
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			utils.RespondError(w, 400, "limit inválido")
+			return
+		}
+		f.Limit = n
+	}
+
+	list, err := h.Service.GetAll(f)
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo bitácora")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/auth"
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+	"github.com/mgdavidd/server-Eme-Mar/internal/services"
+	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
+)
+
+type AuthHandler struct {
+	Service *services.UserService
+	Auth    *auth.Authenticator
+}
+
+func NewAuthHandler(s *services.UserService, a *auth.Authenticator) *AuthHandler {
+	return &AuthHandler{Service: s, Auth: a}
+}
+
+// POST /auth/login {"username": "...", "password": "..."}
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		utils.RespondError(w, 400, "json inválido")
+		return
+	}
+
+	user, err := h.Service.Authenticate(req.Username, req.Password)
+	if errors.Is(err, services.ErrBadCredentials) {
+		utils.RespondError(w, 401, err.Error())
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error iniciando sesión")
+		return
+	}
+
+	token, exp, err := h.Auth.Issue(user)
+	if err != nil {
+		utils.RespondError(w, 500, "error iniciando sesión")
+		return
+	}
+
+	utils.RespondJSON(w, 200, map[string]any{
+		"token":      token,
+		"expires_at": exp.Format("2006-01-02 15:04"),
+		"user":       user,
+	})
+}
+
+// GET /auth/me
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.FromContext(r.Context())
+	utils.RespondJSON(w, 200, models.User{
+		ID:       claims.UserID,
+		Username: claims.Username,
+		Role:     claims.Role,
+	})
+}
+
+func (h *AuthHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Service.GetAll()
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo usuarios")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+func (h *AuthHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var u models.User
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&u); err != nil {
+		utils.RespondError(w, 400, "json inválido")
+		return
+	}
+
+	err := h.Service.Create(&u)
+	if errors.Is(err, services.ErrInvalidInput) {
+		utils.RespondError(w, 400, "usuario repetido, rol inválido o contraseña de menos de 6 caracteres")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error creando usuario")
+		return
+	}
+
+	utils.RespondJSON(w, 201, u)
+}
+
+func (h *AuthHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	if claims.UserID == int64(id) {
+		utils.RespondError(w, 400, "no puedes eliminar tu propio usuario")
+		return
+	}
+
+	err = h.Service.Delete(id)
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "usuario no encontrado")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error eliminando usuario")
+		return
+	}
+
+	w.WriteHeader(204)
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/auth"
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+	"github.com/mgdavidd/server-Eme-Mar/internal/services"
+	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
+)
+
+type CashSessionHandler struct {
+	Service *services.CashSessionService
+}
+
+func NewCashSessionHandler(s *services.CashSessionService) *CashSessionHandler {
+	return &CashSessionHandler{Service: s}
+}
+
+func (h *CashSessionHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Service.GetAll()
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo sesiones de caja")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+func (h *CashSessionHandler) GetCurrent(w http.ResponseWriter, r *http.Request) {
+	cs, err := h.Service.GetCurrent()
+	if errors.Is(err, services.ErrNoOpenSession) {
+		utils.RespondError(w, 404, err.Error())
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error interno")
+		return
+	}
+
+	utils.RespondJSON(w, 200, cs)
+}
+
+// POST /cash/sessions/open {"amount": 50000, "notes": "..."}
+func (h *CashSessionHandler) Open(w http.ResponseWriter, r *http.Request) {
+	count, ok := decodeCashCount(w, r)
+	if !ok {
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	cs, err := h.Service.Open(count, claims.Username)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInput):
+			utils.RespondError(w, 400, "amount no puede ser menor a 0")
+		case errors.Is(err, services.ErrSessionOpen):
+			utils.RespondError(w, 409, err.Error())
+		default:
+			utils.RespondError(w, 500, "error abriendo caja")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, 201, cs)
+}
+
+// POST /cash/sessions/close {"amount": 183500, "notes": "..."}
+func (h *CashSessionHandler) Close(w http.ResponseWriter, r *http.Request) {
+	count, ok := decodeCashCount(w, r)
+	if !ok {
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	report, err := h.Service.Close(count, claims.Username)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInput):
+			utils.RespondError(w, 400, "amount no puede ser menor a 0")
+		case errors.Is(err, services.ErrNoOpenSession):
+			utils.RespondError(w, 409, err.Error())
+		default:
+			utils.RespondError(w, 500, "error cerrando caja")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, 200, report)
+}
+
+func (h *CashSessionHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	report, err := h.Service.Report(int64(id))
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "sesión de caja no encontrada")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error generando reporte de caja")
+		return
+	}
+
+	utils.RespondJSON(w, 200, report)
+}
+
+func decodeCashCount(w http.ResponseWriter, r *http.Request) (models.CashCount, bool) {
+	defer r.Body.Close()
+
+	var count models.CashCount
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&count); err != nil {
+		if strings.Contains(err.Error(), "unknown field") {
+			utils.RespondError(w, 400, "campo desconocido en el cuerpo de la solicitud")
+			return count, false
+		}
+		utils.RespondError(w, 400, "error al parsear el cuerpo de la solicitud")
+		return count, false
+	}
+	return count, true
+}
@@ -1,131 +1,402 @@
-package handlers
-
-import (
-	"encoding/json"
-	"errors"
-	"net/http"
-	"strconv"
-
-	"github.com/gorilla/mux"
-	"github.com/mgdavidd/server-Eme-Mar/internal/models"
-	"github.com/mgdavidd/server-Eme-Mar/internal/services"
-	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
-)
-
-type ClientHandler struct {
-	Service *services.ClientService
-}
-
-func NewClientHandler(s *services.ClientService) *ClientHandler {
-	return &ClientHandler{Service: s}
-}
-
-func (h *ClientHandler) GetClients(w http.ResponseWriter, r *http.Request) {
-	list, err := h.Service.GetAll()
-	if err != nil {
-		utils.RespondError(w, 500, "error obteniendo clientes")
-		return
-	}
-
-	utils.RespondJSON(w, 200, list)
-}
-
-func (h *ClientHandler) GetClientById(w http.ResponseWriter, r *http.Request) {
-	idStr := mux.Vars(r)["id"]
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
-		utils.RespondError(w, 400, "id inválido")
-		return
-	}
-
-	c, err := h.Service.GetById(id)
-	if errors.Is(err, services.ErrNotFound) {
-		utils.RespondError(w, 404, "cliente no encontrado")
-		return
-	}
-	if err != nil {
-		utils.RespondError(w, 500, "error interno")
-		return
-	}
-
-	utils.RespondJSON(w, 200, c)
-}
-
-func (h *ClientHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
-	defer r.Body.Close()
-
-	var c models.Client
-	dec := json.NewDecoder(r.Body)
-	dec.DisallowUnknownFields()
-
-	if err := dec.Decode(&c); err != nil {
-		utils.RespondError(w, 400, "json inválido")
-		return
-	}
-
-	if err := h.Service.Create(&c); err != nil {
-		utils.RespondError(w, 500, "error creando cliente")
-		return
-	}
-
-	utils.RespondJSON(w, 201, c)
-}
-
-func (h *ClientHandler) UpdateClient(w http.ResponseWriter, r *http.Request) {
-	idStr := mux.Vars(r)["id"]
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
-		utils.RespondError(w, 400, "id inválido")
-		return
-	}
-
-	var c models.Client
-	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
-		utils.RespondError(w, 400, "json inválido")
-		return
-	}
-	c.ID = int64(id)
-
-	updated, err := h.Service.UpdateClient(&c)
-	if errors.Is(err, services.ErrNotFound) {
-		utils.RespondError(w, 404, "cliente no encontrado")
-		return
-	}
-	if err != nil {
-		utils.RespondError(w, 500, "error actualizando cliente")
-		return
-	}
-
-	utils.RespondJSON(w, 200, updated)
-}
-
-func (h *ClientHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
-	idStr := mux.Vars(r)["id"]
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
-		utils.RespondError(w, 400, "id inválido")
-		return
-	}
-
-	err = h.Service.DeleteClient(id)
-	if errors.Is(err, services.ErrNotFound) {
-		utils.RespondError(w, 404, "cliente no encontrado")
-		return
-	}
-	if err != nil {
-		utils.RespondError(w, 500, "error eliminando cliente")
-		return
-	}
-
-	w.WriteHeader(204)
-}
-
-func (h *ClientHandler) GetIndebtedClient(w http.ResponseWriter, r *http.Request) {
-	list, err := h.Service.GetIndebtedClient()
-	if err != nil {
-		utils.RespondError(w, 500, "error obteniendo clientes deudores")
-		return
-	}
-
-	utils.RespondJSON(w, 200, list)
-}
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/mgdavidd/server-Eme-Mar/internal/auth"
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+	"github.com/mgdavidd/server-Eme-Mar/internal/services"
+	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
+)
+
+type ClientHandler struct {
+	Service *services.ClientService
+}
+
+func NewClientHandler(s *services.ClientService) *ClientHandler {
+	return &ClientHandler{Service: s}
+}
+
+// archivedParam lee ?archived=true, que cambia un listado a los archivados.
+func archivedParam(r *http.Request) (bool, error) {
+	v := r.URL.Query().Get("archived")
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+func (h *ClientHandler) GetClients(w http.ResponseWriter, r *http.Request) {
+	archived, err := archivedParam(r)
+	if err != nil {
+		utils.RespondError(w, 400, "archived debe ser true o false")
+		return
+	}
+
+	list, err := h.Service.GetAll(archived)
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo clientes")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+func (h *ClientHandler) GetClientById(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	c, err := h.Service.GetById(id)
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "cliente no encontrado")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error interno")
+		return
+	}
+
+	utils.RespondJSON(w, 200, c)
+}
+
+func (h *ClientHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var c models.Client
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&c); err != nil {
+		utils.RespondError(w, 400, "json inválido")
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	err := h.Service.Create(&c, claims.Username)
+	if errors.Is(err, services.ErrInvalidInput) {
+		utils.RespondError(w, 400, "credit_limit y term_days no pueden ser negativos")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error creando cliente")
+		return
+	}
+
+	utils.RespondJSON(w, 201, c)
+}
+
+func (h *ClientHandler) UpdateClient(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	var c models.Client
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		utils.RespondError(w, 400, "json inválido")
+		return
+	}
+	c.ID = int64(id)
+
+	claims, _ := auth.FromContext(r.Context())
+	updated, err := h.Service.UpdateClient(&c, claims.Username)
+	if errors.Is(err, services.ErrInvalidInput) {
+		utils.RespondError(w, 400, "credit_limit y term_days no pueden ser negativos")
+		return
+	}
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "cliente no encontrado")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error actualizando cliente")
+		return
+	}
+
+	utils.RespondJSON(w, 200, updated)
+}
+
+func (h *ClientHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	err = h.Service.DeleteClient(id, claims.Username)
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "cliente no encontrado")
+		return
+	}
+	if errors.Is(err, services.ErrHasDebt) {
+		utils.RespondError(w, 409, "el cliente tiene deuda pendiente, no se puede archivar")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error archivando cliente")
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (h *ClientHandler) RestoreClient(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	c, err := h.Service.Restore(id, claims.Username)
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "cliente no encontrado")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error restaurando cliente")
+		return
+	}
+
+	utils.RespondJSON(w, 200, c)
+}
+
+func (h *ClientHandler) GetIndebtedClient(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Service.GetIndebtedClient()
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo clientes deudores")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+// POST /clients/{id}/payments: un abono que se reparte entre varios fiados
+func (h *ClientHandler) ReceivePayment(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	var p models.ClientPayment
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		if strings.Contains(err.Error(), "unknown field") {
+			utils.RespondError(w, 400, "campo desconocido en el cuerpo de la solicitud")
+			return
+		}
+		utils.RespondError(w, 400, "error al parsear el cuerpo de la solicitud")
+		return
+	}
+
+	if p.Amount <= 0 {
+		utils.RespondError(w, 400, "amount inválido")
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	res, err := h.Service.ReceivePayment(int64(id), p, claims.Username)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			utils.RespondError(w, 404, "cliente no encontrado")
+		case errors.Is(err, services.ErrAccountNotFound):
+			utils.RespondError(w, 404, err.Error())
+		case errors.Is(err, services.ErrOverpayment):
+			utils.RespondError(w, 400, err.Error())
+		case errors.Is(err, services.ErrInvalidInput):
+			utils.RespondError(w, 400, "asignación inválida: cada fiado debe ser del cliente, sin repetir y sin pasar su saldo")
+		default:
+			utils.RespondError(w, 500, "error procesando abono")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, 200, res)
+}
+
+// GET /clients/debt/aging?as_of=2025-03-31
+func (h *ClientHandler) GetDebtAging(w http.ResponseWriter, r *http.Request) {
+	asOf := r.URL.Query().Get("as_of")
+	if !validDates(asOf) {
+		utils.RespondError(w, 400, "fecha inválida, usa AAAA-MM-DD")
+		return
+	}
+
+	report, err := h.Service.Aging(asOf)
+	if err != nil {
+		utils.RespondError(w, 500, "error generando antigüedad de cartera")
+		return
+	}
+
+	utils.RespondJSON(w, 200, report)
+}
+
+// GET /clients/{id}/statement?from=2025-01-01&to=2025-03-31&format=json|html|pdf
+func (h *ClientHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	q := r.URL.Query()
+	from, to := q.Get("from"), q.Get("to")
+	if !validDates(from, to) {
+		utils.RespondError(w, 400, "fecha inválida, usa AAAA-MM-DD")
+		return
+	}
+
+	format := q.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "html" && format != "pdf" {
+		utils.RespondError(w, 400, "format inválido (json, html, pdf)")
+		return
+	}
+
+	st, err := h.Service.Statement(int64(id), from, to)
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "cliente no encontrado")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error generando estado de cuenta")
+		return
+	}
+
+	switch format {
+	case "pdf":
+		name := fmt.Sprintf("estado-cuenta-%d.pdf", st.Client.ID)
+		utils.RespondFile(w, "application/pdf", name, utils.TextPDF(statementText(st)))
+	case "html":
+		var buf bytes.Buffer
+		if err := statementHTML.Execute(&buf, st); err != nil {
+			utils.RespondError(w, 500, "error generando estado de cuenta")
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(200)
+		w.Write(buf.Bytes())
+	default:
+		utils.RespondJSON(w, 200, st)
+	}
+}
+
+func statementPeriod(st models.ClientStatement) string {
+	from, to := st.From, st.To
+	if from == "" {
+		from = "inicio"
+	}
+	if to == "" {
+		to = "hoy"
+	}
+	return from + " a " + to
+}
+
+// statementText arma el estado de cuenta en columnas de ancho fijo para el PDF.
+func statementText(st models.ClientStatement) []string {
+	lines := []string{
+		"ESTADO DE CUENTA",
+		"Cliente:  " + st.Client.Name,
+		"Teléfono: " + st.Client.Phone,
+		"Periodo:  " + statementPeriod(st),
+		"",
+		pad("Fecha", 18) + pad("Detalle", 30) + padLeft("Cargo", 11) + padLeft("Abono", 11) + padLeft("Saldo", 12),
+		strings.Repeat("-", 82),
+		pad("", 18) + pad("Saldo inicial", 30) + padLeft("", 22) + padLeft(st.OpeningBalance.String(), 12),
+	}
+
+	for _, l := range st.Lines {
+		charge, credit := "", ""
+		if l.Charge != 0 {
+			charge = l.Charge.String()
+		}
+		if l.Credit != 0 {
+			credit = l.Credit.String()
+		}
+		lines = append(lines, pad(l.Date, 18)+pad(l.Description, 30)+
+			padLeft(charge, 11)+padLeft(credit, 11)+padLeft(l.Balance.String(), 12))
+
+		for _, it := range l.Items {
+			lines = append(lines, pad("", 20)+pad(fmt.Sprintf("%d x %s", it.Quantity, it.ProductName), 62))
+		}
+	}
+
+	lines = append(lines,
+		strings.Repeat("-", 82),
+		pad("", 18)+pad("Totales", 30)+padLeft(st.TotalCharges.String(), 11)+padLeft(st.TotalCredits.String(), 11),
+		padLeft("Saldo final: "+st.ClosingBalance.String(), 82),
+	)
+
+	return lines
+}
+
+var statementHTML = template.Must(template.New("statement").Funcs(template.FuncMap{
+	"period": statementPeriod,
+}).Parse(`<!DOCTYPE html>
+<html lang="es">
+<head>
+<meta charset="utf-8">
+<title>Estado de cuenta - {{.Client.Name}}</title>
+<style>
+	body { font-family: sans-serif; margin: 2em; }
+	table { border-collapse: collapse; width: 100%; }
+	th, td { border-bottom: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+	td.n, th.n { text-align: right; }
+	.items { color: #555; font-size: 0.9em; }
+	tfoot td { font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>Estado de cuenta</h1>
+<p>
+	<strong>Cliente:</strong> {{.Client.Name}}<br>
+	<strong>Teléfono:</strong> {{.Client.Phone}}<br>
+	<strong>Periodo:</strong> {{period .}}
+</p>
+<table>
+	<thead>
+		<tr><th>Fecha</th><th>Detalle</th><th class="n">Cargo</th><th class="n">Abono</th><th class="n">Saldo</th></tr>
+	</thead>
+	<tbody>
+		<tr><td></td><td>Saldo inicial</td><td></td><td></td><td class="n">{{.OpeningBalance}}</td></tr>
+		{{range .Lines}}
+		<tr>
+			<td>{{.Date}}</td>
+			<td>{{.Description}}{{if .Items}}<div class="items">{{range .Items}}{{.Quantity}} x {{.ProductName}}<br>{{end}}</div>{{end}}</td>
+			<td class="n">{{if .Charge}}{{.Charge}}{{end}}</td>
+			<td class="n">{{if .Credit}}{{.Credit}}{{end}}</td>
+			<td class="n">{{.Balance}}</td>
+		</tr>
+		{{end}}
+	</tbody>
+	<tfoot>
+		<tr><td></td><td>Totales</td><td class="n">{{.TotalCharges}}</td><td class="n">{{.TotalCredits}}</td><td class="n">{{.ClosingBalance}}</td></tr>
+	</tfoot>
+</table>
+</body>
+</html>
+`))
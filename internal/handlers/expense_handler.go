@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/auth"
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+	"github.com/mgdavidd/server-Eme-Mar/internal/services"
+	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
+)
+
+type ExpenseHandler struct {
+	Service *services.ExpenseService
+}
+
+func NewExpenseHandler(s *services.ExpenseService) *ExpenseHandler {
+	return &ExpenseHandler{Service: s}
+}
+
+// expenseFilter lee from, to, category_id y account_id de la query.
+func expenseFilter(r *http.Request) (models.ExpenseFilter, string) {
+	q := r.URL.Query()
+	f := models.ExpenseFilter{From: q.Get("from"), To: q.Get("to")}
+	if !validDates(f.From, f.To) {
+		return f, "fechas inválidas, usa el formato 2006-01-02"
+	}
+
+	if v := q.Get("category_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return f, "category_id inválido"
+		}
+		f.CategoryID = id
+	}
+	if v := q.Get("account_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return f, "account_id inválido"
+		}
+		f.AccountID = id
+	}
+	return f, ""
+}
+
+// GET /expenses?from=2025-01-01&to=2025-01-31&category_id=2&account_id=1
+func (h *ExpenseHandler) GetExpenses(w http.ResponseWriter, r *http.Request) {
+	f, msg := expenseFilter(r)
+	if msg != "" {
+		utils.RespondError(w, 400, msg)
+		return
+	}
+
+	list, err := h.Service.GetAll(f)
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo gastos")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+func (h *ExpenseHandler) GetExpenseById(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	e, err := h.Service.GetById(int64(id))
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "gasto no encontrado")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error interno")
+		return
+	}
+
+	utils.RespondJSON(w, 200, e)
+}
+
+func (h *ExpenseHandler) CreateExpense(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var e models.Expense
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&e); err != nil {
+		if strings.Contains(err.Error(), "unknown field") {
+			utils.RespondError(w, 400, "campo desconocido en el cuerpo de la solicitud")
+			return
+		}
+		utils.RespondError(w, 400, "error al parsear el cuerpo de la solicitud")
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	err := h.Service.Create(&e, claims.Username)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			utils.RespondError(w, 400, "amount y category_id son obligatorios; el soporte no puede pasar de 5 MB")
+			return
+		}
+		if errors.Is(err, services.ErrAccountNotFound) {
+			utils.RespondError(w, 404, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrNotFound) {
+			utils.RespondError(w, 404, "categoría no encontrada")
+			return
+		}
+		utils.RespondError(w, 500, "error registrando gasto")
+		return
+	}
+
+	e.Receipt = nil
+	utils.RespondJSON(w, 201, e)
+}
+
+// GET /expenses/{id}/receipt descarga el soporte tal como se subió.
+func (h *ExpenseHandler) GetReceipt(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	data, err := h.Service.GetReceipt(int64(id))
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "el gasto no tiene soporte")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo soporte")
+		return
+	}
+
+	ctype := http.DetectContentType(data)
+	ext := "bin"
+	switch {
+	case strings.HasPrefix(ctype, "image/jpeg"):
+		ext = "jpg"
+	case strings.HasPrefix(ctype, "image/png"):
+		ext = "png"
+	case strings.HasPrefix(ctype, "application/pdf"):
+		ext = "pdf"
+	}
+	utils.RespondFile(w, ctype, "gasto-"+strconv.Itoa(id)+"."+ext, data)
+}
+
+// GET /expenses/totals: lo gastado por mes y categoría, con los mismos filtros del listado
+func (h *ExpenseHandler) GetMonthlyTotals(w http.ResponseWriter, r *http.Request) {
+	f, msg := expenseFilter(r)
+	if msg != "" {
+		utils.RespondError(w, 400, msg)
+		return
+	}
+
+	list, err := h.Service.MonthlyTotals(f)
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo totales de gastos")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+func (h *ExpenseHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Service.GetCategories()
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo categorías")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+func (h *ExpenseHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var c models.ExpenseCategory
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		if strings.Contains(err.Error(), "unknown field") {
+			utils.RespondError(w, 400, "campo desconocido en el cuerpo de la solicitud")
+			return
+		}
+		utils.RespondError(w, 400, "error al parsear el cuerpo de la solicitud")
+		return
+	}
+
+	err := h.Service.CreateCategory(&c)
+	if errors.Is(err, services.ErrInvalidInput) {
+		utils.RespondError(w, 400, "name es obligatorio")
+		return
+	}
+	if errors.Is(err, services.ErrInUse) {
+		utils.RespondError(w, 409, "ya existe una categoría con ese nombre")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error creando categoría")
+		return
+	}
+
+	utils.RespondJSON(w, 201, c)
+}
@@ -1,156 +1,233 @@
-package handlers
-
-import (
-	"encoding/json"
-	"errors"
-	"net/http"
-	"strconv"
-
-	"github.com/gorilla/mux"
-	"github.com/mgdavidd/server-Eme-Mar/internal/models"
-	"github.com/mgdavidd/server-Eme-Mar/internal/services"
-	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
-)
-
-type InsumoHandler struct {
-	Service *services.InsumoService
-}
-
-func NewInsumoHandler(s *services.InsumoService) *InsumoHandler {
-	return &InsumoHandler{Service: s}
-}
-
-func (h *InsumoHandler) GetAllInsumos(w http.ResponseWriter, r *http.Request) {
-	data, err := h.Service.GetAll()
-	if err != nil {
-		utils.RespondError(w, 500, "error obteniendo insumos")
-		return
-	}
-
-	utils.RespondJSON(w, 200, data)
-}
-
-func (h *InsumoHandler) GetByIdInsumos(w http.ResponseWriter, r *http.Request) {
-	idStr := mux.Vars(r)["id"]
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
-		utils.RespondError(w, 400, "id inválido")
-		return
-	}
-
-	insumo, err := h.Service.GetById(id)
-	if errors.Is(err, services.ErrNotFound) {
-		utils.RespondError(w, 404, "insumo no encontrado")
-		return
-	}
-	if err != nil {
-		utils.RespondError(w, 500, "error interno")
-		return
-	}
-
-	utils.RespondJSON(w, 200, insumo)
-}
-
-func (h *InsumoHandler) CreateInsumo(w http.ResponseWriter, r *http.Request) {
-	defer r.Body.Close()
-
-	var in models.Insumo
-
-	dec := json.NewDecoder(r.Body)
-	dec.DisallowUnknownFields()
-
-	if err := dec.Decode(&in); err != nil {
-		utils.RespondError(w, 400, "json inválido")
-		return
-	}
-
-	if err := validateInsumo(in); err != nil {
-		utils.RespondError(w, 400, err.Error())
-		return
-	}
-
-	if err := h.Service.Create(&in); err != nil {
-		utils.RespondError(w, 500, "error creando insumo")
-		return
-	}
-
-	utils.RespondJSON(w, 201, in)
-}
-
-func (h *InsumoHandler) UpdateInsumo(w http.ResponseWriter, r *http.Request) {
-	idStr := mux.Vars(r)["id"]
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
-		utils.RespondError(w, 400, "id inválido")
-		return
-	}
-
-	var in models.Insumo
-
-	dec := json.NewDecoder(r.Body)
-	dec.DisallowUnknownFields()
-
-	if err := dec.Decode(&in); err != nil {
-		utils.RespondError(w, 400, "json inválido")
-		return
-	}
-
-	if err := validateInsumo(in); err != nil {
-		utils.RespondError(w, 400, err.Error())
-		return
-	}
-
-	in.ID = int64(id)
-	err = h.Service.Update(&in)
-	if errors.Is(err, services.ErrNotFound) {
-		utils.RespondError(w, 404, "insumo no encontrado")
-		return
-	}
-
-	if err != nil {
-		utils.RespondError(w, 500, "Error actualizando insumo")
-		return
-	}
-	utils.RespondJSON(w, 200, in)
-}
-
-func (h *InsumoHandler) DeleteInsumo(w http.ResponseWriter, r *http.Request) {
-	idStr := mux.Vars(r)["id"]
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
-		utils.RespondError(w, 400, "id inválido")
-		return
-	}
-
-	err = h.Service.Delete(id)
-	if errors.Is(err, services.ErrNotFound) {
-		utils.RespondError(w, 404, "insumo no encontrado")
-		return
-	}
-	if err != nil {
-		utils.RespondError(w, 500, "error eliminando insumo")
-		return
-	}
-
-	w.WriteHeader(204)
-}
-
-// VALIDACIÓN
-func validateInsumo(i models.Insumo) error {
-	if i.Name == "" {
-		return errors.New("el campo 'name' es obligatorio")
-	}
-	if i.Um == "" {
-		return errors.New("el campo 'um' es obligatorio")
-	}
-	if i.Stock < 0 {
-		return errors.New("el campo 'stock' no puede ser negativo")
-	}
-	if i.MinStock < 0 {
-		return errors.New("el campo 'min_stock' no puede ser negativo")
-	}
-	if i.UnitPrice <= 0 {
-		return errors.New("el campo 'unit_price' debe ser mayor a 0")
-	}
-	return nil
-}
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/mgdavidd/server-Eme-Mar/internal/auth"
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+	"github.com/mgdavidd/server-Eme-Mar/internal/services"
+	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
+)
+
+type InsumoHandler struct {
+	Service *services.InsumoService
+}
+
+func NewInsumoHandler(s *services.InsumoService) *InsumoHandler {
+	return &InsumoHandler{Service: s}
+}
+
+func (h *InsumoHandler) GetAllInsumos(w http.ResponseWriter, r *http.Request) {
+	archived, err := archivedParam(r)
+	if err != nil {
+		utils.RespondError(w, 400, "archived debe ser true o false")
+		return
+	}
+
+	data, err := h.Service.GetAll(archived)
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo insumos")
+		return
+	}
+
+	utils.RespondJSON(w, 200, data)
+}
+
+func (h *InsumoHandler) GetByIdInsumos(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	insumo, err := h.Service.GetById(id)
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "insumo no encontrado")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error interno")
+		return
+	}
+
+	utils.RespondJSON(w, 200, insumo)
+}
+
+func (h *InsumoHandler) CreateInsumo(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var in models.Insumo
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&in); err != nil {
+		utils.RespondError(w, 400, "json inválido")
+		return
+	}
+
+	if err := validateInsumo(in); err != nil {
+		utils.RespondError(w, 400, err.Error())
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	if err := h.Service.Create(&in, claims.Username); err != nil {
+		utils.RespondError(w, 500, "error creando insumo")
+		return
+	}
+
+	utils.RespondJSON(w, 201, in)
+}
+
+func (h *InsumoHandler) UpdateInsumo(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	var in models.Insumo
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&in); err != nil {
+		utils.RespondError(w, 400, "json inválido")
+		return
+	}
+
+	if err := validateInsumo(in); err != nil {
+		utils.RespondError(w, 400, err.Error())
+		return
+	}
+
+	in.ID = int64(id)
+	claims, _ := auth.FromContext(r.Context())
+	err = h.Service.Update(&in, claims.Username)
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "insumo no encontrado")
+		return
+	}
+
+	if err != nil {
+		utils.RespondError(w, 500, "Error actualizando insumo")
+		return
+	}
+	utils.RespondJSON(w, 200, in)
+}
+
+func (h *InsumoHandler) DeleteInsumo(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	err = h.Service.Delete(id, claims.Username)
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "insumo no encontrado")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error archivando insumo")
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (h *InsumoHandler) RestoreInsumo(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	err = h.Service.Restore(id, claims.Username)
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "insumo no encontrado")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error restaurando insumo")
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (h *InsumoHandler) GetLots(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	list, err := h.Service.GetLots(id)
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "insumo no encontrado")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo surtidos")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+func (h *InsumoHandler) GetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	list, err := h.Service.GetPriceHistory(id)
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "insumo no encontrado")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo historial de precios")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+// VALIDACIÓN
+func validateInsumo(i models.Insumo) error {
+	if i.Name == "" {
+		return errors.New("el campo 'name' es obligatorio")
+	}
+	if i.Um == "" {
+		return errors.New("el campo 'um' es obligatorio")
+	}
+	if i.Stock < 0 {
+		return errors.New("el campo 'stock' no puede ser negativo")
+	}
+	if i.MinStock < 0 {
+		return errors.New("el campo 'min_stock' no puede ser negativo")
+	}
+	if i.UnitPrice <= 0 {
+		return errors.New("el campo 'unit_price' debe ser mayor a 0")
+	}
+	if i.CostMethod != "" && !models.ValidCostMethod(i.CostMethod) {
+		return errors.New("el campo 'cost_method' debe ser ultimo, promedio o fifo")
+	}
+	return nil
+}
@@ -1,288 +1,407 @@
-package handlers
-
-import (
-	"encoding/json"
-	"errors"
-	"log"
-	"net/http"
-	"strconv"
-	"strings"
-
-	"github.com/gorilla/mux"
-
-	"github.com/mgdavidd/server-Eme-Mar/internal/models"
-	"github.com/mgdavidd/server-Eme-Mar/internal/services"
-	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
-)
-
-type MoveHandler struct {
-	Service *services.MovementService
-}
-
-func NewMoveHandler(s *services.MovementService) *MoveHandler {
-	return &MoveHandler{Service: s}
-}
-
-func (h *MoveHandler) GetAllMoves(w http.ResponseWriter, r *http.Request) {
-	data, err := h.Service.GetAll()
-	if err != nil {
-		utils.RespondError(w, 500, "error obteniendo movimientos")
-		return
-	}
-
-	utils.RespondJSON(w, 200, data)
-}
-
-func (h *MoveHandler) GetMovesByClient(w http.ResponseWriter, r *http.Request) {
-	clientStr := mux.Vars(r)["id"]
-	clientID, err := strconv.Atoi(clientStr)
-	if err != nil || clientID <= 0 {
-		utils.RespondError(w, 400, "id inválido")
-		return
-	}
-
-	list, err := h.Service.GetMovesByClient(clientID)
-	if err != nil {
-		// Loggear el error pero devolver array vacío para no romper el frontend
-		log.Printf("Error obteniendo movimientos del cliente %d: %v", clientID, err)
-		utils.RespondJSON(w, 200, []models.Move{})
-		return
-	}
-
-	utils.RespondJSON(w, 200, list)
-}
-
-func (h *MoveHandler) GetRecentMoves(w http.ResponseWriter, r *http.Request) {
-	data, err := h.Service.GetRecent()
-	if err != nil {
-		utils.RespondError(w, 500, "error obteniendo movimientos recientes")
-		return
-	}
-
-	utils.RespondJSON(w, 200, data)
-}
-
-func (h *MoveHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
-	data, err := h.Service.GetBalance()
-	if err != nil {
-		utils.RespondError(w, 500, "error obteniendo Saldo/Fiado")
-		return
-	}
-
-	utils.RespondJSON(w, 200, data)
-}
-
-func (h *MoveHandler) Supply(w http.ResponseWriter, r *http.Request) {
-	defer r.Body.Close()
-
-	var supply models.Supply
-
-	dec := json.NewDecoder(r.Body)
-	dec.DisallowUnknownFields()
-	if err := dec.Decode(&supply); err != nil {
-		if strings.Contains(err.Error(), "unknown field") {
-			utils.RespondError(w, 400, "campo desconocido en el cuerpo de la solicitud")
-			return
-		}
-		utils.RespondError(w, 400, "error al parsear el cuerpo de la solicitud")
-		return
-	}
-
-	err := h.Service.Supply(supply)
-	if err != nil {
-		if errors.Is(err, services.ErrInvalidInput) {
-			utils.RespondError(w, 400, "entrada inválida")
-			return
-		}
-		if errors.Is(err, services.ErrNotFound) {
-			utils.RespondError(w, 404, "insumo no encontrado")
-			return
-		}
-		utils.RespondError(w, 500, "error interno del servidor")
-		return
-	}
-
-	utils.RespondJSON(w, 200, map[string]string{"message": "surtido realizado con éxito"})
-}
-
-func (h *MoveHandler) Sell(w http.ResponseWriter, r *http.Request) {
-	defer r.Body.Close()
-
-	var sale models.Sale
-
-	dec := json.NewDecoder(r.Body)
-	dec.DisallowUnknownFields()
-	if err := dec.Decode(&sale); err != nil {
-		if strings.Contains(err.Error(), "unknown field") {
-			utils.RespondError(w, 400, "campo desconocido en el cuerpo de la solicitud")
-			return
-		}
-		utils.RespondError(w, 400, "error al parsear el cuerpo de la solicitud")
-		return
-	}
-
-	if sale.ClientId <= 0 {
-		utils.RespondError(w, 400, "client_id inválido")
-		return
-	}
-
-	if len(sale.Items) == 0 {
-		utils.RespondError(w, 400, "debes enviar al menos 1 producto")
-		return
-	}
-
-	for _, item := range sale.Items {
-		if item.ProductID <= 0 || item.Quantity <= 0 {
-			utils.RespondError(w, 400, "producto inválido en items")
-			return
-		}
-	}
-
-	err := h.Service.Sell(sale)
-
-	if err != nil {
-		if errors.Is(err, services.ErrNotFound) {
-			utils.RespondError(w, 404, "cliente o producto no encontrado")
-			return
-		}
-		if errors.Is(err, services.ErrInvalidInput) {
-			utils.RespondError(w, 400, "insumo desconocido o stock insuficiente")
-			return
-		}
-		utils.RespondError(w, 500, "error procesando venta")
-		return
-	}
-
-	utils.RespondJSON(w, 200, map[string]string{
-		"message": "venta procesada correctamente",
-	})
-}
-
-func (h *MoveHandler) AllCreditSales(w http.ResponseWriter, r *http.Request) {
-	list, err := h.Service.GetAllCreditSales()
-	if err != nil {
-		utils.RespondJSON(w, 200, make([]models.CreditSale, 0))
-		return
-	}
-
-	utils.RespondJSON(w, 200, list)
-}
-
-func (h *MoveHandler) PayCredit(w http.ResponseWriter, r *http.Request) {
-	defer r.Body.Close()
-
-	var req struct {
-		CreditSaleID int64   `json:"credit_sale_id"`
-		Amount       float64 `json:"amount"`
-	}
-
-	dec := json.NewDecoder(r.Body)
-	dec.DisallowUnknownFields()
-	if err := dec.Decode(&req); err != nil {
-		if strings.Contains(err.Error(), "unknown field") {
-			utils.RespondError(w, 400, "campo desconocido en el cuerpo de la solicitud")
-			return
-		}
-		utils.RespondError(w, 400, "error al parsear el cuerpo de la solicitud")
-		return
-	}
-
-	if req.CreditSaleID <= 0 {
-		utils.RespondError(w, 400, "credit_sale_id inválido")
-		return
-	}
-	if req.Amount <= 0 {
-		utils.RespondError(w, 400, "amount inválido")
-		return
-	}
-
-	err := h.Service.PayCredit(req.CreditSaleID, req.Amount)
-	if err != nil {
-		if errors.Is(err, services.ErrNotFound) {
-			utils.RespondError(w, 404, "venta a crédito no encontrada")
-			return
-		}
-		if errors.Is(err, services.ErrInvalidInput) {
-			utils.RespondError(w, 400, "monto inválido o supera el saldo pendiente")
-			return
-		}
-		utils.RespondError(w, 500, "error procesando abono")
-		return
-	}
-
-	utils.RespondJSON(w, 200, map[string]string{"message": "abono procesado correctamente"})
-}
-
-func (h *MoveHandler) GetClientCreditSales(w http.ResponseWriter, r *http.Request) {
-	idStr := mux.Vars(r)["id"]
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
-		utils.RespondError(w, 400, "id inválido")
-		return
-	}
-
-	list, err := h.Service.GetCreditSalesClients(id)
-	if errors.Is(err, services.ErrNotFound) {
-		utils.RespondError(w, 404, "cliente no encontrado")
-		return
-	}
-	if err != nil {
-		utils.RespondError(w, 500, "error obteniendo ventas a crédito")
-		return
-	}
-
-	utils.RespondJSON(w, 200, list)
-}
-
-func (h *MoveHandler) GetCreditPayments(w http.ResponseWriter, r *http.Request) {
-	idStr := mux.Vars(r)["sale_id"]
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
-		utils.RespondError(w, 400, "id inválido")
-		return
-	}
-
-	list, err := h.Service.GetCreditPayments(id)
-	if errors.Is(err, services.ErrNotFound) {
-		utils.RespondError(w, 404, "venta a crédito no encontrada")
-		return
-	}
-	if err != nil {
-		utils.RespondError(w, 500, "error obteniendo pagos")
-		return
-	}
-
-	utils.RespondJSON(w, 200, list)
-}
-
-func (h *MoveHandler) AdjustBalance(w http.ResponseWriter, r *http.Request) {
-	defer r.Body.Close()
-	var req models.BalanceAdjustment
-
-	dec := json.NewDecoder(r.Body)
-	dec.DisallowUnknownFields()
-	if err := dec.Decode(&req); err != nil {
-		if strings.Contains(err.Error(), "unknown field") {
-			utils.RespondError(w, 400, "campo desconocido en el cuerpo de la solicitud")
-			return
-		}
-		utils.RespondError(w, 400, "error al parsear el cuerpo de la solicitud")
-		return
-	}
-
-	if req.Amount < 0 {
-		utils.RespondError(w, 400, "amount no puede ser menor a 0")
-		return
-	}
-	err := h.Service.AdjustBalance(req)
-	if err != nil {
-		if errors.Is(err, services.ErrInvalidInput) {
-			utils.RespondError(w, 400, "entrada inválida")
-			return
-		}
-		utils.RespondError(w, 500, "error interno del servidor")
-		return
-	}
-
-	utils.RespondJSON(w, 200, map[string]string{"message": "saldo ajustado correctamente"})
-}
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/auth"
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+	"github.com/mgdavidd/server-Eme-Mar/internal/services"
+	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
+)
+
+type MoveHandler struct {
+	Service *services.MovementService
+}
+
+func NewMoveHandler(s *services.MovementService) *MoveHandler {
+	return &MoveHandler{Service: s}
+}
+
+// GET /moves?from=2025-01-01&to=2025-03-31&tipo=ingreso&cliente_id=3&q=abono&sort=monto&order=asc&limit=50&cursor=...
+func (h *MoveHandler) GetAllMoves(w http.ResponseWriter, r *http.Request) {
+	f, msg := parseMoveFilter(r)
+	if msg != "" {
+		utils.RespondError(w, 400, msg)
+		return
+	}
+
+	data, err := h.Service.GetAll(f)
+	if errors.Is(err, services.ErrInvalidInput) {
+		utils.RespondError(w, 400, "cursor inválido")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo movimientos")
+		return
+	}
+
+	utils.RespondJSON(w, 200, data)
+}
+
+// parseMoveFilter lee los filtros de la query; devuelve el mensaje de error si hay uno inválido.
+func parseMoveFilter(r *http.Request) (models.MoveFilter, string) {
+	q := r.URL.Query()
+
+	f := models.MoveFilter{
+		From:   q.Get("from"),
+		To:     q.Get("to"),
+		Type:   q.Get("tipo"),
+		Search: q.Get("q"),
+		Sort:   q.Get("sort"),
+		Cursor: q.Get("cursor"),
+	}
+
+	if !validDates(f.From, f.To) {
+		return f, "fecha inválida, usa AAAA-MM-DD"
+	}
+
+	switch f.Type {
+	case "", models.MoveIngreso, models.MoveEgreso, models.MoveTransfer:
+	default:
+		return f, "tipo inválido (ingreso, egreso, transferencia)"
+	}
+
+	switch f.Sort {
+	case "", "fecha", "monto":
+	default:
+		return f, "sort inválido (fecha, monto)"
+	}
+
+	switch q.Get("order") {
+	case "", "desc":
+	case "asc":
+		f.Asc = true
+	default:
+		return f, "order inválido (asc, desc)"
+	}
+
+	if v := q.Get("cliente_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return f, "cliente_id inválido"
+		}
+		f.ClientID = id
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return f, "limit inválido"
+		}
+		f.Limit = n
+	}
+
+	return f, ""
+}
+
+func (h *MoveHandler) GetMovesByClient(w http.ResponseWriter, r *http.Request) {
+	clientStr := mux.Vars(r)["id"]
+	clientID, err := strconv.Atoi(clientStr)
+	if err != nil || clientID <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	f, msg := parseMoveFilter(r)
+	if msg != "" {
+		utils.RespondError(w, 400, msg)
+		return
+	}
+
+	list, err := h.Service.GetMovesByClient(clientID, f)
+	if err != nil {
+		// Loggear el error pero devolver array vacío para no romper el frontend
+		log.Printf("Error obteniendo movimientos del cliente %d: %v", clientID, err)
+		utils.RespondJSON(w, 200, []models.Move{})
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+func (h *MoveHandler) GetRecentMoves(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			utils.RespondError(w, 400, "limit inválido")
+			return
+		}
+		limit = n
+	}
+
+	data, err := h.Service.GetRecent(limit)
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo movimientos recientes")
+		return
+	}
+
+	utils.RespondJSON(w, 200, data)
+}
+
+func (h *MoveHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
+	data, err := h.Service.GetBalance()
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo Saldo/Fiado")
+		return
+	}
+
+	utils.RespondJSON(w, 200, data)
+}
+
+func (h *MoveHandler) Supply(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var supply models.Supply
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&supply); err != nil {
+		if strings.Contains(err.Error(), "unknown field") {
+			utils.RespondError(w, 400, "campo desconocido en el cuerpo de la solicitud")
+			return
+		}
+		utils.RespondError(w, 400, "error al parsear el cuerpo de la solicitud")
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	err := h.Service.Supply(supply, claims.Username)
+	if err != nil {
+		if errors.Is(err, services.ErrSupplierNotFound) {
+			utils.RespondError(w, 404, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrAccountNotFound) {
+			utils.RespondError(w, 404, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrInvalidInput) {
+			utils.RespondError(w, 400, "entrada inválida (las compras a crédito requieren supplier_id y due_date AAAA-MM-DD)")
+			return
+		}
+		if errors.Is(err, services.ErrNotFound) {
+			utils.RespondError(w, 404, "insumo no encontrado")
+			return
+		}
+		utils.RespondError(w, 500, "error interno del servidor")
+		return
+	}
+
+	utils.RespondJSON(w, 200, map[string]string{"message": "surtido realizado con éxito"})
+}
+
+func (h *MoveHandler) Sell(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var sale models.Sale
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&sale); err != nil {
+		if strings.Contains(err.Error(), "unknown field") {
+			utils.RespondError(w, 400, "campo desconocido en el cuerpo de la solicitud")
+			return
+		}
+		utils.RespondError(w, 400, "error al parsear el cuerpo de la solicitud")
+		return
+	}
+
+	if sale.ClientId <= 0 {
+		utils.RespondError(w, 400, "client_id inválido")
+		return
+	}
+
+	if len(sale.Items) == 0 {
+		utils.RespondError(w, 400, "debes enviar al menos 1 producto")
+		return
+	}
+
+	for _, item := range sale.Items {
+		if item.ProductID <= 0 || item.Quantity <= 0 {
+			utils.RespondError(w, 400, "producto inválido en items")
+			return
+		}
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	if sale.Override && claims.Role != models.RoleOwner {
+		utils.RespondError(w, 403, "solo el dueño puede autorizar un fiado bloqueado")
+		return
+	}
+
+	saleID, err := h.Service.Sell(sale, claims.Username)
+
+	if err != nil {
+		if errors.Is(err, services.ErrCreditLimit) || errors.Is(err, services.ErrCreditOverdue) {
+			utils.RespondError(w, 409, err.Error()+"; el dueño puede autorizarlo con override")
+			return
+		}
+		if errors.Is(err, services.ErrAccountNotFound) {
+			utils.RespondError(w, 404, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrNotFound) {
+			utils.RespondError(w, 404, "cliente o producto no encontrado")
+			return
+		}
+		if errors.Is(err, services.ErrInvalidInput) {
+			utils.RespondError(w, 400, "insumo desconocido o stock insuficiente")
+			return
+		}
+		utils.RespondError(w, 500, "error procesando venta")
+		return
+	}
+
+	utils.RespondJSON(w, 200, map[string]any{
+		"message": "venta procesada correctamente",
+		"sale_id": saleID,
+	})
+}
+
+func (h *MoveHandler) AllCreditSales(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Service.GetAllCreditSales()
+	if err != nil {
+		utils.RespondJSON(w, 200, make([]models.CreditSale, 0))
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+func (h *MoveHandler) PayCredit(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req struct {
+		CreditSaleID int64        `json:"credit_sale_id"`
+		Amount       models.Money `json:"amount"`
+		AccountID    int64        `json:"account_id"`
+	}
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		if strings.Contains(err.Error(), "unknown field") {
+			utils.RespondError(w, 400, "campo desconocido en el cuerpo de la solicitud")
+			return
+		}
+		utils.RespondError(w, 400, "error al parsear el cuerpo de la solicitud")
+		return
+	}
+
+	if req.CreditSaleID <= 0 {
+		utils.RespondError(w, 400, "credit_sale_id inválido")
+		return
+	}
+	if req.Amount <= 0 {
+		utils.RespondError(w, 400, "amount inválido")
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	err := h.Service.PayCredit(req.CreditSaleID, req.Amount, req.AccountID, claims.Username)
+	if err != nil {
+		if errors.Is(err, services.ErrAccountNotFound) {
+			utils.RespondError(w, 404, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrNotFound) {
+			utils.RespondError(w, 404, "venta a crédito no encontrada")
+			return
+		}
+		if errors.Is(err, services.ErrInvalidInput) {
+			utils.RespondError(w, 400, "monto inválido o supera el saldo pendiente")
+			return
+		}
+		utils.RespondError(w, 500, "error procesando abono")
+		return
+	}
+
+	utils.RespondJSON(w, 200, map[string]string{"message": "abono procesado correctamente"})
+}
+
+func (h *MoveHandler) GetClientCreditSales(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	list, err := h.Service.GetCreditSalesClients(id)
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "cliente no encontrado")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo ventas a crédito")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+func (h *MoveHandler) GetCreditPayments(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["sale_id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	list, err := h.Service.GetCreditPayments(id)
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "venta a crédito no encontrada")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo pagos")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+func (h *MoveHandler) AdjustBalance(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req models.BalanceAdjustment
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		if strings.Contains(err.Error(), "unknown field") {
+			utils.RespondError(w, 400, "campo desconocido en el cuerpo de la solicitud")
+			return
+		}
+		utils.RespondError(w, 400, "error al parsear el cuerpo de la solicitud")
+		return
+	}
+
+	if req.Amount < 0 {
+		utils.RespondError(w, 400, "amount no puede ser menor a 0")
+		return
+	}
+	claims, _ := auth.FromContext(r.Context())
+	err := h.Service.AdjustBalance(req, claims.Username)
+	if err != nil {
+		if errors.Is(err, services.ErrAccountNotFound) {
+			utils.RespondError(w, 404, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrInvalidInput) {
+			utils.RespondError(w, 400, "entrada inválida")
+			return
+		}
+		utils.RespondError(w, 500, "error interno del servidor")
+		return
+	}
+
+	utils.RespondJSON(w, 200, map[string]string{"message": "saldo ajustado correctamente"})
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+	"github.com/mgdavidd/server-Eme-Mar/internal/services"
+	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
+)
+
+type PayableHandler struct {
+	Service *services.PayableService
+}
+
+func NewPayableHandler(s *services.PayableService) *PayableHandler {
+	return &PayableHandler{Service: s}
+}
+
+// GET /payables?status=pendiente|vencida|pagada|todas&supplier_id=3
+func (h *PayableHandler) GetPayables(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := models.PayableFilter{Status: q.Get("status")}
+
+	if v := q.Get("supplier_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			utils.RespondError(w, 400, "supplier_id inválido")
+			return
+		}
+		f.SupplierID = id
+	}
+
+	list, err := h.Service.GetAll(f)
+	if errors.Is(err, services.ErrInvalidInput) {
+		utils.RespondError(w, 400, "status inválido (pendiente, vencida, pagada, todas)")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo cuentas por pagar")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+func (h *PayableHandler) GetBySupplier(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Service.BySupplier()
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo cuentas por pagar")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+func (h *PayableHandler) PayPayable(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	var req struct {
+		Amount    models.Money `json:"amount"`
+		AccountID int64        `json:"account_id"`
+	}
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		if strings.Contains(err.Error(), "unknown field") {
+			utils.RespondError(w, 400, "campo desconocido en el cuerpo de la solicitud")
+			return
+		}
+		utils.RespondError(w, 400, "error al parsear el cuerpo de la solicitud")
+		return
+	}
+
+	if req.Amount <= 0 {
+		utils.RespondError(w, 400, "amount inválido")
+		return
+	}
+
+	err = h.Service.Pay(int64(id), req.Amount, req.AccountID)
+	if err != nil {
+		if errors.Is(err, services.ErrAccountNotFound) {
+			utils.RespondError(w, 404, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrNotFound) {
+			utils.RespondError(w, 404, "compra a crédito no encontrada")
+			return
+		}
+		if errors.Is(err, services.ErrInvalidInput) {
+			utils.RespondError(w, 400, "monto inválido o supera el saldo pendiente")
+			return
+		}
+		utils.RespondError(w, 500, "error procesando pago")
+		return
+	}
+
+	utils.RespondJSON(w, 200, map[string]string{"message": "pago a proveedor procesado correctamente"})
+}
+
+func (h *PayableHandler) GetPayments(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	list, err := h.Service.GetPayments(int64(id))
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "compra a crédito no encontrada")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo pagos")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
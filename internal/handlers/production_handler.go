@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/auth"
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+	"github.com/mgdavidd/server-Eme-Mar/internal/services"
+	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
+)
+
+// POST /products/{id}/production {"quantity": 48, "yield": 46, "notes": "tanda de la mañana"}
+func (h *ProductHandler) Produce(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	pid, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || pid <= 0 {
+		utils.RespondError(w, 400, "product id inválido")
+		return
+	}
+
+	var req struct {
+		Quantity int64  `json:"quantity"`
+		Yield    int64  `json:"yield"`
+		Notes    string `json:"notes"`
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		utils.RespondError(w, 400, "json inválido")
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	b := models.ProductionBatch{
+		ProductID: int64(pid),
+		Quantity:  req.Quantity,
+		Yield:     req.Yield,
+		Notes:     req.Notes,
+		CreatedBy: claims.Username,
+	}
+
+	err = h.Service.Produce(&b)
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "producto no encontrado")
+		return
+	}
+	if errors.Is(err, services.ErrInvalidInput) {
+		utils.RespondError(w, 400, "cantidad inválida, producto sin receta o stock de insumos insuficiente")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error registrando producción")
+		return
+	}
+
+	utils.RespondJSON(w, 201, b)
+}
+
+func (h *ProductHandler) GetBatches(w http.ResponseWriter, r *http.Request) {
+	pid, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || pid <= 0 {
+		utils.RespondError(w, 400, "product id inválido")
+		return
+	}
+
+	list, err := h.Service.GetBatches(int64(pid))
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "producto no encontrado")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo producción")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
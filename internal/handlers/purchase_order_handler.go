@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/auth"
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+	"github.com/mgdavidd/server-Eme-Mar/internal/services"
+	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
+)
+
+type PurchaseOrderHandler struct {
+	Service *services.PurchaseOrderService
+}
+
+func NewPurchaseOrderHandler(s *services.PurchaseOrderService) *PurchaseOrderHandler {
+	return &PurchaseOrderHandler{Service: s}
+}
+
+// GET /purchase-orders?status=pendiente
+func (h *PurchaseOrderHandler) GetOrders(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", "pendiente", models.OrderOpen, models.OrderPartial, models.OrderReceived, models.OrderCancelled:
+	default:
+		utils.RespondError(w, 400, "status inválido (pendiente, abierta, parcial, recibida, cancelada)")
+		return
+	}
+
+	list, err := h.Service.GetAll(status)
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo órdenes de compra")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+func (h *PurchaseOrderHandler) GetOrderById(w http.ResponseWriter, r *http.Request) {
+	id, ok := orderID(w, r)
+	if !ok {
+		return
+	}
+
+	o, err := h.Service.GetById(id)
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "orden de compra no encontrada")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error interno")
+		return
+	}
+
+	utils.RespondJSON(w, 200, o)
+}
+
+// POST /purchase-orders {"supplier_id": 1, "notes": "", "items": [{"id_insumo": 3, "quantity": 50, "unit_price": 2800}]}
+func (h *PurchaseOrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req struct {
+		SupplierID int64  `json:"supplier_id"`
+		Notes      string `json:"notes"`
+		Items      []struct {
+			InsumoID  int64        `json:"id_insumo"`
+			Quantity  float64      `json:"quantity"`
+			UnitPrice models.Money `json:"unit_price"`
+		} `json:"items"`
+	}
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		if strings.Contains(err.Error(), "unknown field") {
+			utils.RespondError(w, 400, "campo desconocido en el cuerpo de la solicitud")
+			return
+		}
+		utils.RespondError(w, 400, "error al parsear el cuerpo de la solicitud")
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	o := models.PurchaseOrder{Notes: req.Notes, CreatedBy: claims.Username}
+	if req.SupplierID > 0 {
+		o.SupplierID = &req.SupplierID
+	}
+	for _, it := range req.Items {
+		o.Items = append(o.Items, models.PurchaseOrderItem{
+			InsumoID:  it.InsumoID,
+			Quantity:  it.Quantity,
+			UnitPrice: it.UnitPrice,
+		})
+	}
+
+	err := h.Service.Create(&o)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInput):
+			utils.RespondError(w, 400, "la orden necesita líneas con cantidad mayor a 0 y sin insumos repetidos")
+		case errors.Is(err, services.ErrNotFound):
+			utils.RespondError(w, 404, "insumo no encontrado")
+		case errors.Is(err, services.ErrSupplierNotFound):
+			utils.RespondError(w, 404, err.Error())
+		default:
+			utils.RespondError(w, 500, "error creando orden de compra")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, 201, o)
+}
+
+// POST /purchase-orders/{id}/receive {"account_id": 1, "items": [{"item_id": 7, "quantity": 20, "total_amount": 56000}]}
+func (h *PurchaseOrderHandler) ReceiveOrder(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	id, ok := orderID(w, r)
+	if !ok {
+		return
+	}
+
+	var rc models.PurchaseReceipt
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rc); err != nil {
+		if strings.Contains(err.Error(), "unknown field") {
+			utils.RespondError(w, 400, "campo desconocido en el cuerpo de la solicitud")
+			return
+		}
+		utils.RespondError(w, 400, "error al parsear el cuerpo de la solicitud")
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	err := h.Service.Receive(id, rc, claims.Username)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			utils.RespondError(w, 404, "orden de compra no encontrada")
+		case errors.Is(err, services.ErrInvalidInput):
+			utils.RespondError(w, 400, "líneas inválidas o cantidad mayor a lo pendiente")
+		case errors.Is(err, services.ErrAccountNotFound):
+			utils.RespondError(w, 404, err.Error())
+		case errors.Is(err, services.ErrOrderClosed):
+			utils.RespondError(w, 409, err.Error())
+		default:
+			utils.RespondError(w, 500, "error recibiendo orden de compra")
+		}
+		return
+	}
+
+	o, err := h.Service.GetById(id)
+	if err != nil {
+		utils.RespondError(w, 500, "error interno")
+		return
+	}
+	utils.RespondJSON(w, 200, o)
+}
+
+func (h *PurchaseOrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	id, ok := orderID(w, r)
+	if !ok {
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	err := h.Service.Cancel(id, claims.Username)
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "orden de compra no encontrada")
+		return
+	}
+	if errors.Is(err, services.ErrOrderClosed) {
+		utils.RespondError(w, 409, err.Error())
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error cancelando orden de compra")
+		return
+	}
+
+	utils.RespondJSON(w, 200, map[string]string{"message": "orden cancelada"})
+}
+
+func (h *PurchaseOrderHandler) GetPending(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Service.Pending()
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo pendientes")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+// GET /purchase-orders/{id}/export?format=txt|pdf
+func (h *PurchaseOrderHandler) ExportOrder(w http.ResponseWriter, r *http.Request) {
+	id, ok := orderID(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "txt"
+	}
+	if format != "txt" && format != "pdf" {
+		utils.RespondError(w, 400, "format inválido (txt, pdf)")
+		return
+	}
+
+	o, err := h.Service.GetById(id)
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "orden de compra no encontrada")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error interno")
+		return
+	}
+
+	lines := orderText(o)
+	name := fmt.Sprintf("orden-compra-%d.%s", o.ID, format)
+	if format == "pdf" {
+		utils.RespondFile(w, "application/pdf", name, utils.TextPDF(lines))
+		return
+	}
+	utils.RespondFile(w, "text/plain; charset=utf-8", name, []byte(strings.Join(lines, "\n")+"\n"))
+}
+
+func orderID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return 0, false
+	}
+	return int64(id), true
+}
+
+// orderText arma la orden en columnas de ancho fijo; el PDF usa las mismas líneas.
+func orderText(o models.PurchaseOrder) []string {
+	supplier := o.SupplierName
+	if supplier == "" {
+		supplier = "-"
+	}
+
+	lines := []string{
+		fmt.Sprintf("ORDEN DE COMPRA #%d", o.ID),
+		"Fecha:     " + o.CreatedAt,
+		"Proveedor: " + supplier,
+		"Estado:    " + o.Status,
+		"",
+		pad("Insumo", 28) + padLeft("Cantidad", 10) + padLeft("Pendiente", 11) + "  " + pad("Unidad", 10) +
+			padLeft("Precio", 11) + padLeft("Subtotal", 12),
+		strings.Repeat("-", 84),
+	}
+
+	for _, it := range o.Items {
+		lines = append(lines,
+			pad(it.InsumoName, 28)+
+				padLeft(strconv.FormatFloat(it.Quantity, 'f', -1, 64), 10)+
+				padLeft(strconv.FormatFloat(it.Pending, 'f', -1, 64), 11)+"  "+
+				pad(it.Um, 10)+
+				padLeft(it.UnitPrice.String(), 11)+
+				padLeft(it.UnitPrice.MulQty(it.Quantity).String(), 12),
+		)
+	}
+
+	lines = append(lines,
+		strings.Repeat("-", 84),
+		padLeft("Total: "+o.Total.String(), 84),
+	)
+	if o.Notes != "" {
+		lines = append(lines, "", "Notas: "+o.Notes)
+	}
+
+	return lines
+}
+
+// pad y padLeft cuentan runas, no bytes, para que las tildes no corran las columnas.
+func pad(s string, n int) string {
+	if c := utf8.RuneCountInString(s); c < n {
+		return s + strings.Repeat(" ", n-c)
+	}
+	return string([]rune(s)[:n-1]) + " "
+}
+
+func padLeft(s string, n int) string {
+	if c := utf8.RuneCountInString(s); c < n {
+		return strings.Repeat(" ", n-c) + s
+	}
+	return s
+}
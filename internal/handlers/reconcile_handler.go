@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/auth"
+	"github.com/mgdavidd/server-Eme-Mar/internal/services"
+	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
+)
+
+type ReconcileHandler struct {
+	Service *services.ReconcileService
+}
+
+func NewReconcileHandler(s *services.ReconcileService) *ReconcileHandler {
+	return &ReconcileHandler{Service: s}
+}
+
+// GET /admin/reconcile: solo reporta, no cambia nada
+func (h *ReconcileHandler) Check(w http.ResponseWriter, r *http.Request) {
+	report, err := h.Service.Check()
+	if err != nil {
+		utils.RespondError(w, 500, "error revisando saldos")
+		return
+	}
+
+	utils.RespondJSON(w, 200, report)
+}
+
+// POST /admin/reconcile: corrige deuda de clientes y saldo de cuentas
+func (h *ReconcileHandler) Repair(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.FromContext(r.Context())
+
+	report, err := h.Service.Repair(claims.Username)
+	if err != nil {
+		utils.RespondError(w, 500, "error corrigiendo saldos")
+		return
+	}
+
+	utils.RespondJSON(w, 200, report)
+}
+
+func (h *ReconcileHandler) GetLog(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Service.GetLog()
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo conciliaciones")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/auth"
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+	"github.com/mgdavidd/server-Eme-Mar/internal/services"
+	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
+)
+
+type RecurringExpenseHandler struct {
+	Service *services.RecurringExpenseService
+}
+
+func NewRecurringExpenseHandler(s *services.RecurringExpenseService) *RecurringExpenseHandler {
+	return &RecurringExpenseHandler{Service: s}
+}
+
+func (h *RecurringExpenseHandler) GetRecurring(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Service.GetAll()
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo gastos recurrentes")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+func (h *RecurringExpenseHandler) GetRecurringById(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	rec, err := h.Service.GetById(int64(id))
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "gasto recurrente no encontrado")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error interno")
+		return
+	}
+
+	utils.RespondJSON(w, 200, rec)
+}
+
+func decodeRecurring(w http.ResponseWriter, r *http.Request, rec *models.RecurringExpense) bool {
+	defer r.Body.Close()
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(rec); err != nil {
+		if strings.Contains(err.Error(), "unknown field") {
+			utils.RespondError(w, 400, "campo desconocido en el cuerpo de la solicitud")
+			return false
+		}
+		utils.RespondError(w, 400, "error al parsear el cuerpo de la solicitud")
+		return false
+	}
+	return true
+}
+
+// respondRecurringError traduce los errores de crear o editar una plantilla.
+func respondRecurringError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidInput):
+		utils.RespondError(w, 400, "amount, category_id, frequency (semanal, mensual) y next_date (hoy o después) son obligatorios")
+	case errors.Is(err, services.ErrAccountNotFound):
+		utils.RespondError(w, 404, err.Error())
+	case errors.Is(err, services.ErrNotFound):
+		utils.RespondError(w, 404, "gasto recurrente o categoría no encontrada")
+	default:
+		utils.RespondError(w, 500, "error guardando gasto recurrente")
+	}
+}
+
+func (h *RecurringExpenseHandler) CreateRecurring(w http.ResponseWriter, r *http.Request) {
+	var rec models.RecurringExpense
+	if !decodeRecurring(w, r, &rec) {
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	if err := h.Service.Create(&rec, claims.Username); err != nil {
+		respondRecurringError(w, err)
+		return
+	}
+
+	utils.RespondJSON(w, 201, rec)
+}
+
+func (h *RecurringExpenseHandler) UpdateRecurring(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	var rec models.RecurringExpense
+	if !decodeRecurring(w, r, &rec) {
+		return
+	}
+	rec.ID = int64(id)
+
+	claims, _ := auth.FromContext(r.Context())
+	if err := h.Service.Update(&rec, claims.Username); err != nil {
+		respondRecurringError(w, err)
+		return
+	}
+
+	utils.RespondJSON(w, 200, rec)
+}
+
+// GET /expenses/scheduled?status=pendiente|registrado|descartado|todos
+func (h *RecurringExpenseHandler) GetScheduled(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Service.GetScheduled(r.URL.Query().Get("status"))
+	if errors.Is(err, services.ErrInvalidInput) {
+		utils.RespondError(w, 400, "status inválido (pendiente, registrado, descartado, todos)")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo gastos programados")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+// POST /expenses/scheduled/{id}/confirm: registra el gasto; amount es opcional
+func (h *RecurringExpenseHandler) ConfirmScheduled(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	var req struct {
+		Amount models.Money `json:"amount"`
+	}
+	if r.ContentLength != 0 {
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			if strings.Contains(err.Error(), "unknown field") {
+				utils.RespondError(w, 400, "campo desconocido en el cuerpo de la solicitud")
+				return
+			}
+			utils.RespondError(w, 400, "error al parsear el cuerpo de la solicitud")
+			return
+		}
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	expenseID, err := h.Service.Confirm(int64(id), req.Amount, claims.Username)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInput):
+			utils.RespondError(w, 400, "amount inválido")
+		case errors.Is(err, services.ErrAccountNotFound):
+			utils.RespondError(w, 404, err.Error())
+		case errors.Is(err, services.ErrNotFound):
+			utils.RespondError(w, 404, "gasto programado no encontrado")
+		case errors.Is(err, services.ErrAlreadyResolved):
+			utils.RespondError(w, 409, err.Error())
+		default:
+			utils.RespondError(w, 500, "error registrando gasto")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, 200, map[string]int64{"expense_id": expenseID})
+}
+
+func (h *RecurringExpenseHandler) DiscardScheduled(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	claims, _ := auth.FromContext(r.Context())
+	err = h.Service.Discard(int64(id), claims.Username)
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "gasto programado no encontrado")
+		return
+	}
+	if errors.Is(err, services.ErrAlreadyResolved) {
+		utils.RespondError(w, 409, err.Error())
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error descartando gasto programado")
+		return
+	}
+
+	w.WriteHeader(204)
+}
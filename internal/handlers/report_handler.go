@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+	"github.com/mgdavidd/server-Eme-Mar/internal/services"
+	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
+)
+
+type ReportHandler struct {
+	Service *services.ReportService
+}
+
+func NewReportHandler(s *services.ReportService) *ReportHandler {
+	return &ReportHandler{Service: s}
+}
+
+// reportFilter lee from, to y group de la query.
+func reportFilter(r *http.Request) (models.ReportFilter, bool) {
+	q := r.URL.Query()
+	f := models.ReportFilter{From: q.Get("from"), To: q.Get("to"), GroupBy: q.Get("group")}
+	return f, validDates(f.From, f.To)
+}
+
+// GET /reports/pnl?from=2025-01-01&to=2025-03-31&group=dia|semana|mes
+func (h *ReportHandler) GetPnL(w http.ResponseWriter, r *http.Request) {
+	f, ok := reportFilter(r)
+	if !ok {
+		utils.RespondError(w, 400, "fechas inválidas, usa el formato 2006-01-02")
+		return
+	}
+
+	report, err := h.Service.PnL(f)
+	if errors.Is(err, services.ErrInvalidInput) {
+		utils.RespondError(w, 400, "rango inválido o group no es dia, semana o mes")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error generando estado de resultados")
+		return
+	}
+
+	utils.RespondJSON(w, 200, report)
+}
+
+// GET /reports/products?from=2025-01-01&to=2025-03-31&rank_by=ingresos|unidades|margen&limit=5
+func (h *ReportHandler) GetProductSales(w http.ResponseWriter, r *http.Request) {
+	f, ok := reportFilter(r)
+	if !ok {
+		utils.RespondError(w, 400, "fechas inválidas, usa el formato 2006-01-02")
+		return
+	}
+	f.GroupBy = ""
+	f.RankBy = r.URL.Query().Get("rank_by")
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			utils.RespondError(w, 400, "limit inválido")
+			return
+		}
+		f.Limit = n
+	}
+
+	report, err := h.Service.ProductSales(f)
+	if errors.Is(err, services.ErrInvalidInput) {
+		utils.RespondError(w, 400, "rango inválido o rank_by no es ingresos, unidades o margen")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error generando reporte de productos")
+		return
+	}
+
+	utils.RespondJSON(w, 200, report)
+}
+
+// GET /dashboard: todos los indicadores del inicio en una sola llamada
+func (h *ReportHandler) GetDashboard(w http.ResponseWriter, r *http.Request) {
+	d, err := h.Service.Dashboard()
+	if err != nil {
+		utils.RespondError(w, 500, "error generando el tablero")
+		return
+	}
+
+	utils.RespondJSON(w, 200, d)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+	"github.com/mgdavidd/server-Eme-Mar/internal/services"
+	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
+)
+
+type SaleHandler struct {
+	Service *services.SaleService
+}
+
+func NewSaleHandler(s *services.SaleService) *SaleHandler {
+	return &SaleHandler{Service: s}
+}
+
+// GET /sales?from=2025-01-01&to=2025-01-31&client_id=1&product_id=2
+func (h *SaleHandler) GetSales(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var f models.SaleFilter
+	f.From = q.Get("from")
+	f.To = q.Get("to")
+	for _, d := range []string{f.From, f.To} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			utils.RespondError(w, 400, "fecha inválida, usa AAAA-MM-DD")
+			return
+		}
+	}
+
+	var err error
+	if v := q.Get("client_id"); v != "" {
+		f.ClientID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || f.ClientID <= 0 {
+			utils.RespondError(w, 400, "client_id inválido")
+			return
+		}
+	}
+	if v := q.Get("product_id"); v != "" {
+		f.ProductID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || f.ProductID <= 0 {
+			utils.RespondError(w, 400, "product_id inválido")
+			return
+		}
+	}
+
+	list, err := h.Service.GetAll(f)
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo ventas")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+func (h *SaleHandler) GetSaleById(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	sale, err := h.Service.GetById(int64(id))
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "venta no encontrada")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error interno")
+		return
+	}
+
+	utils.RespondJSON(w, 200, sale)
+}
+
+// POST /sales/{id}/void  {"refund": true, "reason": "..."} (cuerpo opcional)
+func (h *SaleHandler) VoidSale(w http.ResponseWriter, r *http.Request) {
+	h.handleReturn(w, r, true)
+}
+
+// POST /sales/{id}/returns  {"items": [{"sale_item_id": 1, "quantity": 1}], "refund": false}
+func (h *SaleHandler) ReturnSale(w http.ResponseWriter, r *http.Request) {
+	h.handleReturn(w, r, false)
+}
+
+func (h *SaleHandler) handleReturn(w http.ResponseWriter, r *http.Request, void bool) {
+	defer r.Body.Close()
+
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	var req models.SaleReturn
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		if strings.Contains(err.Error(), "unknown field") {
+			utils.RespondError(w, 400, "campo desconocido en el cuerpo de la solicitud")
+			return
+		}
+		utils.RespondError(w, 400, "error al parsear el cuerpo de la solicitud")
+		return
+	}
+
+	if void {
+		err = h.Service.Void(int64(id), req)
+	} else {
+		err = h.Service.Return(int64(id), req)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAccountNotFound):
+			utils.RespondError(w, 404, err.Error())
+		case errors.Is(err, services.ErrNotFound):
+			utils.RespondError(w, 404, "venta no encontrada")
+		case errors.Is(err, services.ErrInvalidInput):
+			utils.RespondError(w, 400, "líneas o cantidades inválidas")
+		case errors.Is(err, services.ErrSaleVoided), errors.Is(err, services.ErrRefundRequired):
+			utils.RespondError(w, 409, err.Error())
+		default:
+			utils.RespondError(w, 500, "error procesando devolución")
+		}
+		return
+	}
+
+	sale, err := h.Service.GetById(int64(id))
+	if err != nil {
+		utils.RespondError(w, 500, "error interno")
+		return
+	}
+
+	utils.RespondJSON(w, 200, sale)
+}
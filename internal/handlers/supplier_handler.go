@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+	"github.com/mgdavidd/server-Eme-Mar/internal/services"
+	"github.com/mgdavidd/server-Eme-Mar/internal/utils"
+)
+
+type SupplierHandler struct {
+	Service *services.SupplierService
+}
+
+func NewSupplierHandler(s *services.SupplierService) *SupplierHandler {
+	return &SupplierHandler{Service: s}
+}
+
+func (h *SupplierHandler) GetSuppliers(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Service.GetAll()
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo proveedores")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+func (h *SupplierHandler) GetSupplierById(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	sp, err := h.Service.GetById(int64(id))
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "proveedor no encontrado")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error interno")
+		return
+	}
+
+	utils.RespondJSON(w, 200, sp)
+}
+
+func (h *SupplierHandler) CreateSupplier(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var sp models.Supplier
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&sp); err != nil {
+		utils.RespondError(w, 400, "json inválido")
+		return
+	}
+
+	err := h.Service.Create(&sp)
+	if errors.Is(err, services.ErrInvalidInput) {
+		utils.RespondError(w, 400, "el campo 'name' es obligatorio")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error creando proveedor")
+		return
+	}
+
+	utils.RespondJSON(w, 201, sp)
+}
+
+func (h *SupplierHandler) UpdateSupplier(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	var sp models.Supplier
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&sp); err != nil {
+		utils.RespondError(w, 400, "json inválido")
+		return
+	}
+	sp.ID = int64(id)
+
+	err = h.Service.Update(&sp)
+	if errors.Is(err, services.ErrInvalidInput) {
+		utils.RespondError(w, 400, "el campo 'name' es obligatorio")
+		return
+	}
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "proveedor no encontrado")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error actualizando proveedor")
+		return
+	}
+
+	utils.RespondJSON(w, 200, sp)
+}
+
+func (h *SupplierHandler) DeleteSupplier(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	err = h.Service.Delete(int64(id))
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "proveedor no encontrado")
+		return
+	}
+	if errors.Is(err, services.ErrInUse) {
+		utils.RespondError(w, 409, "el proveedor tiene compras, órdenes o cuentas por pagar registradas")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error eliminando proveedor")
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+// GET /suppliers/{id}/purchases?from=2025-01-01&to=2025-03-31
+func (h *SupplierHandler) GetPurchases(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		utils.RespondError(w, 400, "id inválido")
+		return
+	}
+
+	q := r.URL.Query()
+	from, to := q.Get("from"), q.Get("to")
+	if !validDates(from, to) {
+		utils.RespondError(w, 400, "fecha inválida, usa AAAA-MM-DD")
+		return
+	}
+
+	list, err := h.Service.GetPurchases(int64(id), from, to)
+	if errors.Is(err, services.ErrNotFound) {
+		utils.RespondError(w, 404, "proveedor no encontrado")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo compras")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+// GET /suppliers/prices?insumo_id=3&from=2025-01-01&to=2025-12-31
+func (h *SupplierHandler) GetPriceComparison(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	f := models.SupplierPriceFilter{From: q.Get("from"), To: q.Get("to")}
+	if !validDates(f.From, f.To) {
+		utils.RespondError(w, 400, "fecha inválida, usa AAAA-MM-DD")
+		return
+	}
+	if v := q.Get("insumo_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			utils.RespondError(w, 400, "insumo_id inválido")
+			return
+		}
+		f.InsumoID = id
+	}
+
+	list, err := h.Service.PriceComparison(f)
+	if err != nil {
+		utils.RespondError(w, 500, "error obteniendo comparativo de precios")
+		return
+	}
+
+	utils.RespondJSON(w, 200, list)
+}
+
+// validDates acepta fechas vacías o en formato AAAA-MM-DD.
+func validDates(dates ...string) bool {
+	for _, d := range dates {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return false
+		}
+	}
+	return true
+}
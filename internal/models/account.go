@@ -1,6 +1,8 @@
-package models
-
-type Account struct {
-    Balance    float64 `json:"balance"`      // saldo que tengo
-    AmountOwed float64 `json:"amount_owed"`  // saldo que me deben
-}
\ No newline at end of file
+package models
+
+type Account struct {
+	Balance       Money          `json:"balance"`        // saldo que tengo (todas las cuentas)
+	AmountOwed    Money          `json:"amount_owed"`    // saldo que me deben
+	AmountPayable Money          `json:"amount_payable"` // saldo que le debo a proveedores
+	Accounts      []MoneyAccount `json:"accounts"`       // saldo por cuenta
+}
@@ -0,0 +1,40 @@
+package models
+
+// AgingBuckets reparte saldos pendientes según los días desde la venta.
+type AgingBuckets struct {
+	Days0to15  Money `json:"0_15"`
+	Days16to30 Money `json:"16_30"`
+	Days31to60 Money `json:"31_60"`
+	Over60     Money `json:"60_plus"`
+	Total      Money `json:"total"`
+}
+
+// Add suma un saldo al tramo que le toca según su antigüedad en días.
+func (b *AgingBuckets) Add(days int, amount Money) {
+	switch {
+	case days <= 15:
+		b.Days0to15 += amount
+	case days <= 30:
+		b.Days16to30 += amount
+	case days <= 60:
+		b.Days31to60 += amount
+	default:
+		b.Over60 += amount
+	}
+	b.Total += amount
+}
+
+type ClientAging struct {
+	ClientID       int64        `json:"client_id"`
+	ClientName     string       `json:"client_name"`
+	Phone          string       `json:"phone"`
+	OpenSales      int          `json:"open_sales"`
+	OldestSaleDate string       `json:"oldest_sale_date"`
+	Buckets        AgingBuckets `json:"buckets"`
+}
+
+type AgingReport struct {
+	AsOf    string        `json:"as_of"`
+	Clients []ClientAging `json:"clients"`
+	Totals  AgingBuckets  `json:"totals"`
+}
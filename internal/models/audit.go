@@ -0,0 +1,49 @@
+package models
+
+import "encoding/json"
+
+// Acciones de la bitácora
+const (
+	AuditCreate  = "crear"
+	AuditUpdate  = "editar"
+	AuditDelete  = "eliminar"
+	AuditArchive = "archivar"
+	AuditRestore = "restaurar"
+)
+
+// Entidades de la bitácora
+const (
+	AuditClient     = "cliente"
+	AuditInsumo     = "insumo"
+	AuditProduct    = "producto"
+	AuditProduction = "produccion"
+	AuditSale       = "venta"
+	AuditSupply     = "surtido"
+	AuditOrder      = "orden_compra"
+	AuditPayment    = "abono"
+	AuditAdjustment = "ajuste_saldo"
+	AuditExpense    = "gasto"
+	AuditRecurring  = "gasto_recurrente"
+)
+
+// AuditEntry es un cambio registrado. Before y After son el JSON del registro
+// antes y después; null cuando no aplica (crear o eliminar).
+type AuditEntry struct {
+	ID       int64           `json:"id"`
+	Date     string          `json:"date"`
+	Actor    string          `json:"actor"`
+	Action   string          `json:"action"`
+	Entity   string          `json:"entity"`
+	EntityID int64           `json:"entity_id"`
+	Before   json.RawMessage `json:"before"`
+	After    json.RawMessage `json:"after"`
+}
+
+type AuditFilter struct {
+	Entity   string
+	EntityID int64
+	Actor    string
+	From     string
+	To       string
+	Limit    int
+}
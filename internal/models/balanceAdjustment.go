@@ -1,6 +1,7 @@
-package models
-
-type BalanceAdjustment struct {
-	Amount      float64 `json:"amount"`
-	Description string  `json:"description"`
-}
+package models
+
+type BalanceAdjustment struct {
+	Amount      Money  `json:"amount"`
+	Description string `json:"description"`
+	AccountID   int64  `json:"account_id"` // 0 = caja
+}
@@ -0,0 +1,35 @@
+package models
+
+type CashSession struct {
+	ID             int64   `json:"id"`
+	OpenedAt       string  `json:"opened_at"`
+	OpenedBy       string  `json:"opened_by"`
+	OpeningAmount  Money   `json:"opening_amount"`
+	ClosedAt       *string `json:"closed_at,omitempty"`
+	ClosedBy       *string `json:"closed_by,omitempty"`
+	ExpectedAmount *Money  `json:"expected_amount,omitempty"`
+	CountedAmount  *Money  `json:"counted_amount,omitempty"`
+	Difference     *Money  `json:"difference,omitempty"` // contado - esperado
+	Notes          string  `json:"notes"`
+}
+
+// CashSessionReport es lo que el cajero entrega al cerrar: qué entró y salió en la sesión.
+type CashSessionReport struct {
+	Session     CashSession `json:"session"`
+	SalesCount  int64       `json:"sales_count"`
+	Sales       Money       `json:"sales"`       // ventas de contado
+	Payments    Money       `json:"payments"`    // abonos a fiados
+	Supplies    Money       `json:"supplies"`    // surtidos pagados
+	Payables    Money       `json:"payables"`    // pagos a proveedores de compras a crédito
+	Refunds     Money       `json:"refunds"`     // devoluciones y anulaciones
+	Adjustments Money       `json:"adjustments"` // ajustes manuales (neto)
+	Transfers   Money       `json:"transfers"`   // neto de transferencias con otras cuentas
+	Expected    Money       `json:"expected"`    // base + entradas - salidas de la caja física
+	Moves       []Move      `json:"moves"`
+}
+
+// CashCount es el cuerpo de abrir y cerrar caja.
+type CashCount struct {
+	Amount Money  `json:"amount"`
+	Notes  string `json:"notes"`
+}
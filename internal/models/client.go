@@ -1,8 +1,40 @@
-package models
-
-type Client struct {
-	ID    int64   `json:"id"`
-	Name  string  `json:"name"`
-	Phone string  `json:"phone"`
-	Debt  float64 `json:"debt"`
-}
+package models
+
+type Client struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Phone  string `json:"phone"`
+	Debt   Money  `json:"debt"`
+	Credit Money  `json:"credit"` // saldo a favor; se descuenta del próximo fiado
+
+	CreditLimit *Money `json:"credit_limit"` // nil = sin límite
+	TermDays    int    `json:"term_days"`    // plazo de cada fiado; 0 = 30 días
+
+	Overdue       bool  `json:"overdue"` // tiene fiados vencidos
+	OverdueAmount Money `json:"overdue_amount"`
+
+	ArchivedAt *string `json:"archived_at,omitempty"` // archivado: no sale en listados ni se le puede vender
+}
+
+// ClientPayment es un abono global de un cliente. Sin Allocations se reparte
+// entre sus fiados abiertos del más viejo al más nuevo.
+type ClientPayment struct {
+	Amount      Money               `json:"amount"`
+	AccountID   int64               `json:"account_id"`
+	Allocations []PaymentAllocation `json:"allocations"`
+	KeepCredit  bool                `json:"keep_credit"` // lo que sobre queda como saldo a favor
+}
+
+type PaymentAllocation struct {
+	CreditSaleID int64 `json:"credit_sale_id"`
+	Amount       Money `json:"amount"`
+}
+
+type ClientPaymentResult struct {
+	ClientID    int64               `json:"client_id"`
+	Amount      Money               `json:"amount"`
+	Allocations []PaymentAllocation `json:"allocations"`
+	CreditAdded Money               `json:"credit_added"`
+	Debt        Money               `json:"debt"`
+	Credit      Money               `json:"credit"`
+}
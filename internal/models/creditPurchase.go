@@ -0,0 +1,40 @@
+package models
+
+// CreditPurchase es un surtido que quedamos debiendo a un proveedor.
+type CreditPurchase struct {
+	ID           int64  `json:"id"`
+	SupplierID   int64  `json:"supplier_id"`
+	SupplierName string `json:"supplier_name"`
+	SupplyID     *int64 `json:"supply_id,omitempty"`
+	Description  string `json:"description"`
+	Total        Money  `json:"total"`
+	TotalPaid    Money  `json:"total_paid"`
+	Remaining    Money  `json:"remaining_balance"`
+	Date         string `json:"date"`
+	DueDate      string `json:"due_date"`
+	Overdue      bool   `json:"overdue"`
+}
+
+// PayableFilter son los filtros de GET /payables. Status: pendiente (por
+// defecto), vencida, pagada o todas.
+type PayableFilter struct {
+	SupplierID int64
+	Status     string
+}
+
+type PayablePayment struct {
+	ID        int64  `json:"id"`
+	Amount    Money  `json:"amount"`
+	AccountID int64  `json:"account_id"`
+	Date      string `json:"date"`
+}
+
+// SupplierPayable resume lo que se le debe a cada proveedor.
+type SupplierPayable struct {
+	SupplierID   int64  `json:"supplier_id"`
+	SupplierName string `json:"supplier_name"`
+	Purchases    int    `json:"purchases"`
+	Remaining    Money  `json:"remaining_balance"`
+	Overdue      Money  `json:"overdue"`
+	NextDueDate  string `json:"next_due_date"`
+}
@@ -0,0 +1,50 @@
+package models
+
+// SalesSummary son las ventas de un rango, sin anuladas y descontando
+// devoluciones, separadas en contado y fiado.
+type SalesSummary struct {
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Count       int    `json:"count"`
+	Total       Money  `json:"total"`
+	CashCount   int    `json:"cash_count"`
+	CashTotal   Money  `json:"cash_total"`
+	CreditCount int    `json:"credit_count"`
+	CreditTotal Money  `json:"credit_total"`
+}
+
+// TopProduct es un producto del ranking del inicio; sin costos, porque el
+// inicio también lo ven los cajeros.
+type TopProduct struct {
+	ProductID int64  `json:"product_id"`
+	Name      string `json:"name"`
+	Units     int64  `json:"units"`
+	Revenue   Money  `json:"revenue"`
+}
+
+type LowStockInsumo struct {
+	ID       int64   `json:"id"`
+	Name     string  `json:"name"`
+	Um       string  `json:"um"`
+	Stock    float64 `json:"stock"`
+	MinStock float64 `json:"min_stock"`
+}
+
+// Dashboard reúne lo que muestra la pantalla de inicio en una sola respuesta.
+// LastWeek cubre los mismos días de la semana pasada que van de esta, para
+// que la comparación sea pareja.
+type Dashboard struct {
+	Date          string           `json:"date"`
+	Today         SalesSummary     `json:"today"`
+	ThisWeek      SalesSummary     `json:"this_week"`
+	LastWeek      SalesSummary     `json:"last_week"`
+	WeekChangePct float64          `json:"week_change_pct"` // this_week contra last_week
+	PaymentsToday Money            `json:"payments_today"`  // abonos recibidos hoy
+	PaymentsWeek  Money            `json:"payments_week"`
+	TopProducts   []TopProduct     `json:"top_products"` // de esta semana, por ingresos
+	LowStock      []LowStockInsumo `json:"low_stock"`    // insumos por debajo del mínimo sugerido
+	Balance       Money            `json:"balance"`      // saldo de todas las cuentas
+	AmountOwed    Money            `json:"amount_owed"`
+	OverdueTotal  Money            `json:"overdue_total"`
+	Debtors       int              `json:"debtors"` // clientes con deuda
+}
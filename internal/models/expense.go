@@ -0,0 +1,90 @@
+package models
+
+// ExpenseCategory agrupa los gastos que no son surtidos: arriendo, gas, nómina...
+type ExpenseCategory struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// Expense es un gasto pagado desde una cuenta. Receipt es la foto o PDF del
+// soporte; solo viaja al crear, para leerlo está GET /expenses/{id}/receipt.
+type Expense struct {
+	ID           int64  `json:"id"`
+	CategoryID   int64  `json:"category_id"`
+	CategoryName string `json:"category_name"`
+	AccountID    int64  `json:"account_id"` // 0 = caja
+	Amount       Money  `json:"amount"`
+	Notes        string `json:"notes"`
+	Receipt      []byte `json:"receipt,omitempty"`
+	HasReceipt   bool   `json:"has_receipt"`
+	Date         string `json:"date"`
+	CreatedBy    string `json:"created_by"`
+}
+
+// ExpenseFilter son los filtros de GET /expenses. Fechas en formato 2006-01-02.
+type ExpenseFilter struct {
+	From       string
+	To         string
+	CategoryID int64
+	AccountID  int64
+}
+
+type ExpenseList struct {
+	Expenses []Expense `json:"expenses"`
+	Total    Money     `json:"total"`
+}
+
+// ExpenseTotal es lo gastado en una categoría durante un mes.
+type ExpenseTotal struct {
+	Period       string `json:"period"` // 2006-01
+	CategoryID   int64  `json:"category_id"`
+	CategoryName string `json:"category_name"`
+	Count        int    `json:"count"`
+	Total        Money  `json:"total"`
+}
+
+// Frecuencias de un gasto recurrente
+const (
+	FrequencyWeekly  = "semanal"
+	FrequencyMonthly = "mensual"
+)
+
+// RecurringExpense es la plantilla de un gasto que se repite. En cada
+// NextDate el programador lo registra, o lo deja pendiente si
+// RequiresConfirmation está activo.
+type RecurringExpense struct {
+	ID                   int64  `json:"id"`
+	CategoryID           int64  `json:"category_id"`
+	CategoryName         string `json:"category_name"`
+	AccountID            int64  `json:"account_id"` // 0 = caja
+	Amount               Money  `json:"amount"`
+	Notes                string `json:"notes"`
+	Frequency            string `json:"frequency"` // semanal o mensual
+	NextDate             string `json:"next_date"` // 2006-01-02; el día del mes se toma de aquí
+	RequiresConfirmation bool   `json:"requires_confirmation"`
+	Active               bool   `json:"active"`
+	CreatedBy            string `json:"created_by"`
+}
+
+// Estados de un gasto programado
+const (
+	ScheduledPending   = "pendiente"
+	ScheduledPosted    = "registrado"
+	ScheduledDiscarded = "descartado"
+)
+
+// ScheduledExpense es una fecha de un gasto recurrente que ya llegó.
+type ScheduledExpense struct {
+	ID           int64   `json:"id"`
+	RecurringID  int64   `json:"recurring_id"`
+	CategoryID   int64   `json:"category_id"`
+	CategoryName string  `json:"category_name"`
+	AccountID    int64   `json:"account_id"`
+	Notes        string  `json:"notes"`
+	DueDate      string  `json:"due_date"`
+	Amount       Money   `json:"amount"`
+	Status       string  `json:"status"`
+	ExpenseID    *int64  `json:"expense_id,omitempty"`
+	ResolvedAt   *string `json:"resolved_at,omitempty"`
+	ResolvedBy   *string `json:"resolved_by,omitempty"`
+}
@@ -1,10 +1,48 @@
-package models
-
-type Insumo struct {
-	ID        int64   `json:"id"`
-	Name      string  `json:"name"`
-	Um        string  `json:"um"`
-	Stock     float64 `json:"stock"`
-	MinStock  float64 `json:"min_stock"`
-	UnitPrice float64 `json:"unit_price"`
-}
+package models
+
+type Insumo struct {
+	ID         int64   `json:"id"`
+	Name       string  `json:"name"`
+	Um         string  `json:"um"`
+	Stock      float64 `json:"stock"`
+	MinStock   float64 `json:"min_stock"`
+	UnitPrice  Money   `json:"unit_price"`
+	CostMethod string  `json:"cost_method"` // ultimo, promedio (por defecto) o fifo
+	ArchivedAt *string `json:"archived_at,omitempty"`
+}
+
+// Métodos para recalcular unit_price cuando entra un surtido
+const (
+	CostLast    = "ultimo"
+	CostAverage = "promedio"
+	CostFIFO    = "fifo"
+)
+
+func ValidCostMethod(m string) bool {
+	return m == CostLast || m == CostAverage || m == CostFIFO
+}
+
+// SupplyLot es un surtido guardado con su costo real. Remaining es lo que
+// queda sin consumir, que es lo que usa FIFO.
+type SupplyLot struct {
+	ID         int64   `json:"id"`
+	InsumoID   int64   `json:"id_insumo"`
+	Kind       string  `json:"kind"` // compra, inicial, ajuste, devolucion
+	Quantity   float64 `json:"quantity"`
+	Remaining  float64 `json:"remaining"`
+	UnitCost   Money   `json:"unit_cost"`
+	Total      Money   `json:"total"`
+	AccountID  *int64  `json:"account_id,omitempty"`
+	SupplierID *int64  `json:"supplier_id,omitempty"`
+	Date       string  `json:"date"`
+}
+
+type InsumoPrice struct {
+	ID            int64  `json:"id"`
+	InsumoID      int64  `json:"id_insumo"`
+	PreviousPrice Money  `json:"previous_price"`
+	Price         Money  `json:"price"`
+	Method        string `json:"method"`
+	SupplyID      *int64 `json:"supply_id,omitempty"`
+	Date          string `json:"date"`
+}
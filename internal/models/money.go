@@ -0,0 +1,178 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money es una cantidad de dinero en centavos. En la base se guarda como
+// INTEGER y en JSON viaja como número decimal (2500.5), igual que antes.
+type Money int64
+
+var ErrInvalidMoney = errors.New("monto inválido")
+
+// MoneyFromFloat convierte pesos con decimales a centavos, redondeando.
+func MoneyFromFloat(f float64) Money {
+	return Money(math.Round(f * 100))
+}
+
+// ParseMoney lee un decimal como "2500", "2500.5" o "-3.25" sin pasar por float.
+// Los decimales después del segundo se redondean.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalidMoney
+	}
+
+	neg := false
+	switch s[0] {
+	case '-':
+		neg = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+	// Un solo signo: ParseInt aceptaría "--1" como 1
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		return 0, ErrInvalidMoney
+	}
+
+	// Notación científica (1e3): no la escribe el frontend, pero es JSON válido
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, ErrInvalidMoney
+		}
+		m := MoneyFromFloat(f)
+		if neg {
+			m = -m
+		}
+		return m, nil
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, ErrInvalidMoney
+	}
+	if whole == "" {
+		whole = "0"
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidMoney
+	}
+
+	var cents int64
+	for i := 0; i < len(frac); i++ {
+		d := frac[i]
+		if d < '0' || d > '9' {
+			return 0, ErrInvalidMoney
+		}
+		switch {
+		case i < 2:
+			cents = cents*10 + int64(d-'0')
+		case i == 2 && d >= '5':
+			cents++
+		}
+	}
+	if len(frac) == 1 {
+		cents *= 10
+	}
+
+	m := Money(units*100 + cents)
+	if neg {
+		m = -m
+	}
+	return m, nil
+}
+
+// MulQty multiplica un precio por una cantidad fraccionaria (gramos, litros...).
+func (m Money) MulQty(q float64) Money {
+	return Money(math.Round(float64(m) * q))
+}
+
+// Float64 devuelve el monto en pesos. Solo para mostrar o reportar.
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// String devuelve el monto en pesos sin ceros decimales de sobra: 2500, 2500.5, 2500.25.
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+
+	units, cents := v/100, v%100
+	switch {
+	case cents == 0:
+		return fmt.Sprintf("%s%d", sign, units)
+	case cents%10 == 0:
+		return fmt.Sprintf("%s%d.%d", sign, units, cents/10)
+	default:
+		return fmt.Sprintf("%s%d.%02d", sign, units, cents)
+	}
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	s = strings.Trim(s, `"`)
+
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Scan acepta INTEGER y también REAL, que es lo que devuelven ROUND o SUM en
+// SQLite; en ambos casos el valor ya está en centavos.
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case int64:
+		*m = Money(v)
+	case float64:
+		*m = Money(math.Round(v))
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	default:
+		return fmt.Errorf("no se puede leer %T como Money", src)
+	}
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		f, ferr := strconv.ParseFloat(s, 64)
+		if ferr != nil {
+			return err
+		}
+		n = int64(math.Round(f))
+	}
+	*m = Money(n)
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
@@ -0,0 +1,30 @@
+package models
+
+// CashAccountID es la caja física (antes la tabla caja). Es la cuenta por
+// defecto cuando una operación no indica account_id.
+const CashAccountID int64 = 1
+
+const (
+	AccountCash   = "efectivo"
+	AccountBank   = "banco"
+	AccountWallet = "billetera" // Nequi, Daviplata...
+)
+
+type MoneyAccount struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`
+	Balance Money  `json:"balance"`
+}
+
+func ValidAccountKind(kind string) bool {
+	return kind == AccountCash || kind == AccountBank || kind == AccountWallet
+}
+
+// Transfer mueve dinero entre cuentas sin contar como ingreso ni egreso.
+type Transfer struct {
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        Money  `json:"amount"`
+	Description   string `json:"description"`
+}
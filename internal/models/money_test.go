@@ -0,0 +1,179 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+	}{
+		{"2500", 250000},
+		{"2500.5", 250050},
+		{"2500.25", 250025},
+		{" 12.3 ", 1230},
+		{".5", 50},
+		{"+7", 700},
+		{"-3.25", -325},
+		{"-0.5", -50},
+		{"0", 0},
+		{"1e3", 100000},
+		{"-1.5e1", -1500},
+
+		// El tercer decimal redondea, sin pasar por float
+		{"1.004", 100},
+		{"1.005", 101},
+		{"1.239", 124},
+		{"0.995", 100},
+		{"0.999", 100},
+		{"-1.005", -101},
+		{"-0.004", 0},
+		{"2.34999", 235}, // solo mira el tercer decimal
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if err != nil {
+			t.Errorf("ParseMoney(%q): error %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, se esperaba %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseMoneyInvalid(t *testing.T) {
+	for _, in := range []string{"", " ", "-", ".", "abc", "1.2x", "1,5", "--1", "-+1", "+-1e3", "1e"} {
+		if _, err := ParseMoney(in); !errors.Is(err, ErrInvalidMoney) {
+			t.Errorf("ParseMoney(%q): se esperaba ErrInvalidMoney, llegó %v", in, err)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		in   Money
+		want string
+	}{
+		{0, "0"},
+		{5, "0.05"},
+		{50, "0.5"},
+		{250000, "2500"},
+		{250050, "2500.5"},
+		{250025, "2500.25"},
+		{-325, "-3.25"},
+		{-50, "-0.5"},
+		{-5, "-0.05"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, se esperaba %q", tt.in, got, tt.want)
+		}
+		b, err := json.Marshal(tt.in)
+		if err != nil {
+			t.Fatalf("json.Marshal(%d): %v", tt.in, err)
+		}
+		if string(b) != tt.want {
+			t.Errorf("json.Marshal(%d) = %s, se esperaba %s", tt.in, b, tt.want)
+		}
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+	}{
+		{`2500.5`, 250050},
+		{`"2500.5"`, 250050},
+		{`-3.25`, -325},
+		{`1.005`, 101},
+		{`0`, 0},
+	}
+
+	for _, tt := range tests {
+		var got struct {
+			Amount Money `json:"amount"`
+		}
+		if err := json.Unmarshal([]byte(`{"amount":`+tt.in+`}`), &got); err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.in, err)
+			continue
+		}
+		if got.Amount != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, se esperaba %d", tt.in, got.Amount, tt.want)
+		}
+	}
+
+	// null no toca el valor que ya había
+	m := Money(700)
+	if err := json.Unmarshal([]byte(`null`), &m); err != nil || m != 700 {
+		t.Errorf("Unmarshal(null) = %d, %v; se esperaba 700 sin error", m, err)
+	}
+
+	if err := json.Unmarshal([]byte(`"abc"`), &m); !errors.Is(err, ErrInvalidMoney) {
+		t.Errorf(`Unmarshal("abc"): se esperaba ErrInvalidMoney, llegó %v`, err)
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		name string
+		src  any
+		want Money
+	}{
+		{"nil", nil, 0},
+		{"int64", int64(78200), 78200},
+		{"int64 negativo", int64(-325), -325},
+		{"float64", float64(78200), 78200},
+		{"float64 redondea", 782.5, 783},
+		{"float64 negativo", -782.5, -783},
+		{"string", "1250", 1250},
+		{"string negativo", "-1250", -1250},
+		{"string decimal", "1250.4", 1250},
+		{"bytes", []byte("300"), 300},
+		{"bytes decimal", []byte("299.6"), 300},
+	}
+
+	for _, tt := range tests {
+		m := Money(99) // Scan debe pisar lo que hubiera
+		if err := m.Scan(tt.src); err != nil {
+			t.Errorf("%s: Scan(%v): %v", tt.name, tt.src, err)
+			continue
+		}
+		if m != tt.want {
+			t.Errorf("%s: Scan(%v) = %d, se esperaba %d", tt.name, tt.src, m, tt.want)
+		}
+	}
+
+	var m Money
+	for _, src := range []any{true, "abc", []byte("1,5")} {
+		if err := m.Scan(src); err == nil {
+			t.Errorf("Scan(%v): se esperaba error", src)
+		}
+	}
+}
+
+func TestMoneyFromFloatAndMulQty(t *testing.T) {
+	if got := MoneyFromFloat(782.0); got != 78200 {
+		t.Errorf("MoneyFromFloat(782.0) = %d, se esperaba 78200", got)
+	}
+	if got := MoneyFromFloat(0.125); got != 13 {
+		t.Errorf("MoneyFromFloat(0.125) = %d, se esperaba 13", got)
+	}
+	if got := MoneyFromFloat(-0.125); got != -13 {
+		t.Errorf("MoneyFromFloat(-0.125) = %d, se esperaba -13", got)
+	}
+
+	// 3.5 gramos a 12.34 el gramo
+	if got := Money(1234).MulQty(3.5); got != 4319 {
+		t.Errorf("MulQty = %d, se esperaba 4319", got)
+	}
+	if got := Money(1000).MulQty(1.0 / 3); got != 333 {
+		t.Errorf("MulQty(1/3) = %d, se esperaba 333", got)
+	}
+}
@@ -1,10 +1,61 @@
-package models
-
-type Move struct {
-	ID          int64   `json:"id"`
-	Amount      float64 `json:"amount"` //cantidad
-	Type        string  `json:"type"`
-	Description string  `json:"descripcion"` //precio total por el surtido
-	Date        string  `json:"date"`
-	ClientID    *int64  `json:"client_id,omitempty"`
-}
+package models
+
+const (
+	MoveIngreso  = "ingreso"
+	MoveEgreso   = "egreso"
+	MoveTransfer = "transferencia" // el monto lleva signo: negativo sale de la cuenta
+)
+
+// Origen de cada movimiento, para poder separar ventas de abonos, surtidos, etc.
+const (
+	OriginSale       = "venta"
+	OriginPayment    = "abono"
+	OriginSupply     = "surtido"
+	OriginAdjustment = "ajuste"
+	OriginReturn     = "devolucion"
+	OriginCashClose  = "cierre_caja"
+	OriginTransfer   = "transferencia"
+	OriginPayable    = "pago_proveedor"
+	OriginExpense    = "gasto"
+	OriginReconcile  = "conciliacion" // corrección de Repair; no cuenta al conciliar
+)
+
+type Move struct {
+	ID          int64  `json:"id"`
+	Amount      Money  `json:"amount"` //cantidad
+	Type        string `json:"type"`
+	Origin      string `json:"origin"`
+	Description string `json:"descripcion"` //precio total por el surtido
+	Date        string `json:"date"`
+	ClientID    *int64 `json:"client_id,omitempty"`
+	AccountID   int64  `json:"account_id"`
+	CategoryID  *int64 `json:"category_id,omitempty"` // categoría del gasto
+}
+
+// MoveFilter son los filtros de GET /moves. Fechas en formato 2006-01-02.
+type MoveFilter struct {
+	From     string
+	To       string
+	Type     string
+	ClientID int64
+	Search   string
+	Sort     string // fecha o monto
+	Asc      bool
+	Cursor   string
+	Limit    int
+}
+
+// MoveTotals resume todo el rango filtrado, no solo la página. Las
+// transferencias no suman como ingreso ni egreso.
+type MoveTotals struct {
+	Count    int   `json:"count"`
+	Ingresos Money `json:"ingresos"`
+	Egresos  Money `json:"egresos"`
+	Net      Money `json:"net"`
+}
+
+type MovePage struct {
+	Moves      []Move     `json:"moves"`
+	Totals     MoveTotals `json:"totals"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
@@ -1,15 +1,18 @@
-package models
-
-type Product struct {
-	ID        int64           `json:"id"`
-	Name      string          `json:"name"`
-	Price     float64         `json:"price"` // precio al que se vende
-	Foto      []byte          `json:"foto,omitempty"`
-	Insumos   []ProductInsumo `json:"insumos"` // solo id + cantidad
-	TotalCost float64         `json:"costo_total"`
-}
-
-type ProductInsumo struct {
-	InsumoID int64   `json:"id_insumo"`
-	Quantity float64 `json:"quantity"` // cantidad requerida para fabricar el producto (misma unidad de medida del insumo de la tabla)
-}
+package models
+
+type Product struct {
+	ID            int64           `json:"id"`
+	Name          string          `json:"name"`
+	Price         Money           `json:"price"` // precio al que se vende
+	Foto          []byte          `json:"foto,omitempty"`
+	Insumos       []ProductInsumo `json:"insumos"` // solo id + cantidad
+	TotalCost     Money           `json:"costo_total"`
+	Stock         int64           `json:"stock_actual"`    // unidades ya fabricadas; solo cambia con producción y ventas
+	SellFromStock bool            `json:"sell_from_stock"` // true = la venta descuenta stock_actual y no insumos
+	ArchivedAt    *string         `json:"archived_at,omitempty"`
+}
+
+type ProductInsumo struct {
+	InsumoID int64   `json:"id_insumo"`
+	Quantity float64 `json:"quantity"` // cantidad requerida para fabricar el producto (misma unidad de medida del insumo de la tabla)
+}
@@ -1,8 +1,9 @@
-package models
-
-type ProductSimple struct {
-	ID    int64   `json:"id"`
-	Name  string  `json:"name"`
-	Price float64 `json:"price"` // precio al que se vende
-	Foto  []byte  `json:"foto,omitempty"`
-}
+package models
+
+type ProductSimple struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	Price         Money  `json:"price"` // precio al que se vende
+	Foto          []byte `json:"foto,omitempty"`
+	SellFromStock bool   `json:"sell_from_stock"`
+}
@@ -0,0 +1,29 @@
+package models
+
+// Origen de las unidades de una línea de venta
+const (
+	SourceStock   = "stock"
+	SourceInsumos = "insumos"
+)
+
+// ProductionBatch es una tanda: consume insumos según la receta de Quantity
+// unidades y suma Yield unidades al stock del producto.
+type ProductionBatch struct {
+	ID        int64                   `json:"id"`
+	ProductID int64                   `json:"product_id"`
+	Quantity  int64                   `json:"quantity"`
+	Yield     int64                   `json:"yield"` // 0 = igual a quantity
+	Cost      Money                   `json:"cost"`
+	UnitCost  Money                   `json:"unit_cost"` // cost / yield
+	Notes     string                  `json:"notes"`
+	CreatedBy string                  `json:"created_by"`
+	Date      string                  `json:"date"`
+	Insumos   []ProductionBatchInsumo `json:"insumos"`
+}
+
+type ProductionBatchInsumo struct {
+	InsumoID int64   `json:"id_insumo"`
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+	UnitCost Money   `json:"unit_cost"`
+}
@@ -0,0 +1,58 @@
+package models
+
+// Estados de una orden de compra
+const (
+	OrderOpen      = "abierta"
+	OrderPartial   = "parcial" // se recibió algo pero falta
+	OrderReceived  = "recibida"
+	OrderCancelled = "cancelada"
+)
+
+type PurchaseOrder struct {
+	ID           int64               `json:"id"`
+	SupplierID   *int64              `json:"supplier_id"`
+	SupplierName string              `json:"supplier_name"`
+	Status       string              `json:"status"`
+	Notes        string              `json:"notes"`
+	CreatedBy    string              `json:"created_by"`
+	CreatedAt    string              `json:"created_at"`
+	ClosedAt     *string             `json:"closed_at,omitempty"`
+	Total        Money               `json:"total"` // cantidad * precio esperado
+	Items        []PurchaseOrderItem `json:"items"`
+}
+
+type PurchaseOrderItem struct {
+	ID         int64   `json:"id"`
+	InsumoID   int64   `json:"id_insumo"`
+	InsumoName string  `json:"insumo_name"`
+	Um         string  `json:"um"`
+	Quantity   float64 `json:"quantity"`
+	UnitPrice  Money   `json:"unit_price"`
+	Received   float64 `json:"received"`
+	Pending    float64 `json:"pending"`
+}
+
+// PurchaseReceipt es lo que llegó de una orden. TotalAmount por línea es lo
+// que se pagó; 0 = precio esperado * cantidad.
+type PurchaseReceipt struct {
+	AccountID int64                 `json:"account_id"`
+	IsCredit  bool                  `json:"is_credit"`
+	DueDate   string                `json:"due_date"`
+	Items     []PurchaseReceiptItem `json:"items"`
+}
+
+type PurchaseReceiptItem struct {
+	ItemID      int64   `json:"item_id"`
+	Quantity    float64 `json:"quantity"`
+	TotalAmount Money   `json:"total_amount"`
+}
+
+// PendingInsumo es lo que falta por llegar de un insumo sumando todas las
+// órdenes abiertas.
+type PendingInsumo struct {
+	InsumoID   int64   `json:"id_insumo"`
+	InsumoName string  `json:"insumo_name"`
+	Um         string  `json:"um"`
+	Pending    float64 `json:"pending"`
+	OrderIDs   []int64 `json:"order_ids"`
+}
@@ -0,0 +1,37 @@
+package models
+
+// Entidades que revisa la conciliación
+const (
+	DriftClient     = "cliente"
+	DriftCreditSale = "fiado"
+	DriftAccount    = "cuenta"
+)
+
+// Drift es un saldo guardado que no coincide con lo que dicen sus fuentes.
+// Fixable = false se reporta pero hay que revisarlo a mano.
+type Drift struct {
+	Entity   string `json:"entity"`
+	EntityID int64  `json:"entity_id"`
+	Name     string `json:"name"`
+	Stored   Money  `json:"stored"`
+	Computed Money  `json:"computed"`
+	Diff     Money  `json:"diff"` // guardado - calculado
+	Fixable  bool   `json:"fixable"`
+}
+
+type ReconcileReport struct {
+	RunAt    string  `json:"run_at"`
+	Repaired bool    `json:"repaired"`
+	Drifts   []Drift `json:"drifts"`
+}
+
+// ReconcileEntry es una corrección ya aplicada.
+type ReconcileEntry struct {
+	ID       int64  `json:"id"`
+	Date     string `json:"date"`
+	Actor    string `json:"actor"`
+	Entity   string `json:"entity"`
+	EntityID int64  `json:"entity_id"`
+	Before   Money  `json:"before"`
+	After    Money  `json:"after"`
+}
@@ -0,0 +1,91 @@
+package models
+
+// Agrupaciones de los reportes por período
+const (
+	GroupDay   = "dia"
+	GroupWeek  = "semana" // el período es el lunes de la semana
+	GroupMonth = "mes"
+)
+
+// Criterios para ordenar el ranking de productos
+const (
+	RankRevenue = "ingresos"
+	RankUnits   = "unidades"
+	RankMargin  = "margen"
+)
+
+// ReportFilter son los filtros comunes de /reports. Fechas en formato 2006-01-02.
+type ReportFilter struct {
+	From    string
+	To      string
+	GroupBy string
+	RankBy  string // solo /reports/products
+	Limit   int    // tamaño de los rankings de /reports/products
+}
+
+// PnLExpense es lo gastado en una categoría dentro de un período.
+type PnLExpense struct {
+	CategoryID   int64  `json:"category_id"`
+	CategoryName string `json:"category_name"`
+	Amount       Money  `json:"amount"`
+}
+
+// PnLLine es el estado de resultados de un período. Las devoluciones y
+// anulaciones restan en el período en que se hicieron, no en el de la venta.
+type PnLLine struct {
+	Period      string       `json:"period"`
+	GrossSales  Money        `json:"gross_sales"`
+	Returns     Money        `json:"returns"`
+	Revenue     Money        `json:"revenue"` // ventas - devoluciones
+	COGS        Money        `json:"cogs"`    // costo congelado en cada línea de venta
+	GrossMargin Money        `json:"gross_margin"`
+	MarginPct   float64      `json:"margin_pct"` // margen bruto / ingresos * 100
+	Expenses    Money        `json:"expenses"`
+	ByCategory  []PnLExpense `json:"expenses_by_category"`
+	Net         Money        `json:"net"`
+}
+
+type PnLReport struct {
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	GroupBy string    `json:"group_by"`
+	Periods []PnLLine `json:"periods"`
+	Totals  PnLLine   `json:"totals"`
+}
+
+// ProductSales es lo vendido de un producto en el rango, de contado y fiado,
+// ya descontadas las devoluciones. Costo es el congelado en cada venta.
+type ProductSales struct {
+	Rank        int     `json:"rank"`
+	ProductID   int64   `json:"product_id"`
+	Name        string  `json:"name"`
+	Archived    bool    `json:"archived"`
+	Units       int64   `json:"units"`
+	CashUnits   int64   `json:"cash_units"`
+	CreditUnits int64   `json:"credit_units"`
+	Revenue     Money   `json:"revenue"`
+	Cost        Money   `json:"cost"`
+	Margin      Money   `json:"margin"`
+	MarginPct   float64 `json:"margin_pct"`
+}
+
+type ProductSalesTotals struct {
+	Units     int64   `json:"units"`
+	Revenue   Money   `json:"revenue"`
+	Cost      Money   `json:"cost"`
+	Margin    Money   `json:"margin"`
+	MarginPct float64 `json:"margin_pct"`
+}
+
+// ProductSalesReport trae todos los productos ordenados por RankBy; Best y
+// Worst son los primeros y últimos de esa lista. Los productos activos sin
+// ventas en el rango aparecen con cero.
+type ProductSalesReport struct {
+	From     string             `json:"from"`
+	To       string             `json:"to"`
+	RankBy   string             `json:"rank_by"`
+	Products []ProductSales     `json:"products"`
+	Best     []ProductSales     `json:"best"`
+	Worst    []ProductSales     `json:"worst"`
+	Totals   ProductSalesTotals `json:"totals"`
+}
@@ -0,0 +1,49 @@
+package models
+
+// SaleDetail es una venta registrada (de contado o fiada) con sus líneas
+type SaleDetail struct {
+	ID           int64      `json:"id"`
+	ClientID     int64      `json:"client_id"`
+	ClientName   string     `json:"client_name"`
+	Total        Money      `json:"total"`
+	Cost         Money      `json:"cost"` // costo de los productos al momento de vender
+	IsCredit     bool       `json:"is_credit"`
+	CreditSaleID *int64     `json:"credit_sale_id,omitempty"`
+	Date         string     `json:"date"`
+	VoidedAt     *string    `json:"voided_at,omitempty"`
+	Items        []SaleLine `json:"items"`
+}
+
+type SaleLine struct {
+	ID          int64  `json:"id"`
+	ProductID   int64  `json:"product_id"`
+	ProductName string `json:"product_name"`
+	Quantity    int64  `json:"quantity"`
+	UnitPrice   Money  `json:"unit_price"` // precio de venta ese día
+	UnitCost    Money  `json:"unit_cost"`  // costo_total del producto ese día
+	Subtotal    Money  `json:"subtotal"`
+	Returned    int64  `json:"returned_quantity"`
+	Source      string `json:"source"` // stock o insumos
+}
+
+// SaleFilter son los filtros de GET /sales. Fechas en formato 2006-01-02.
+type SaleFilter struct {
+	From      string
+	To        string
+	ClientID  int64
+	ProductID int64
+}
+
+// SaleReturn es el cuerpo de POST /sales/{id}/returns y /sales/{id}/void.
+// Refund autoriza devolver de caja lo que el cliente ya había abonado.
+type SaleReturn struct {
+	Items     []SaleReturnItem `json:"items"`
+	Refund    bool             `json:"refund"`
+	Reason    string           `json:"reason"`
+	AccountID int64            `json:"account_id"` // de dónde sale el reembolso; 0 = caja
+}
+
+type SaleReturnItem struct {
+	SaleItemID int64 `json:"sale_item_id"`
+	Quantity   int64 `json:"quantity"`
+}
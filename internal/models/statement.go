@@ -0,0 +1,40 @@
+package models
+
+// Tipos de línea de un estado de cuenta
+const (
+	StatementSale       = "fiado"
+	StatementPayment    = "abono"
+	StatementReturn     = "devolucion"
+	StatementAdjustment = "ajuste" // diferencia con remaining_balance sin abonos que la expliquen
+)
+
+// ClientStatement es el estado de cuenta de un cliente en un rango de fechas.
+type ClientStatement struct {
+	Client         Client          `json:"client"`
+	From           string          `json:"from"`
+	To             string          `json:"to"`
+	OpeningBalance Money           `json:"opening_balance"`
+	Lines          []StatementLine `json:"lines"`
+	TotalCharges   Money           `json:"total_charges"`
+	TotalCredits   Money           `json:"total_credits"`
+	ClosingBalance Money           `json:"closing_balance"`
+}
+
+// StatementLine es un fiado (Charge) o un abono/devolución (Credit) con el
+// saldo que quedó después de aplicarlo.
+type StatementLine struct {
+	Date         string          `json:"date"`
+	Kind         string          `json:"kind"`
+	CreditSaleID int64           `json:"credit_sale_id"`
+	Description  string          `json:"description"`
+	Items        []StatementItem `json:"items,omitempty"`
+	Charge       Money           `json:"charge"`
+	Credit       Money           `json:"credit"`
+	Balance      Money           `json:"balance"`
+}
+
+type StatementItem struct {
+	ProductID   int64  `json:"product_id"`
+	ProductName string `json:"product_name"`
+	Quantity    int64  `json:"quantity"`
+}
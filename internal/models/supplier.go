@@ -0,0 +1,40 @@
+package models
+
+type Supplier struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Phone string `json:"phone"`
+	Notes string `json:"notes"`
+}
+
+// SupplierPurchase es un surtido comprado a un proveedor.
+type SupplierPurchase struct {
+	ID         int64   `json:"id"`
+	InsumoID   int64   `json:"id_insumo"`
+	InsumoName string  `json:"insumo_name"`
+	Quantity   float64 `json:"quantity"`
+	UnitCost   Money   `json:"unit_cost"`
+	Total      Money   `json:"total"`
+	Date       string  `json:"date"`
+}
+
+// SupplierPriceFilter son los filtros del comparativo de precios. Fechas en formato 2006-01-02.
+type SupplierPriceFilter struct {
+	InsumoID int64
+	From     string
+	To       string
+}
+
+// SupplierPrice es lo que cobró un proveedor por un insumo en un mes.
+type SupplierPrice struct {
+	InsumoID     int64   `json:"id_insumo"`
+	InsumoName   string  `json:"insumo_name"`
+	SupplierID   int64   `json:"supplier_id"`
+	SupplierName string  `json:"supplier_name"`
+	Period       string  `json:"period"` // 2006-01
+	Purchases    int     `json:"purchases"`
+	Quantity     float64 `json:"quantity"`
+	AvgUnitCost  Money   `json:"avg_unit_cost"` // total pagado / cantidad
+	MinUnitCost  Money   `json:"min_unit_cost"`
+	MaxUnitCost  Money   `json:"max_unit_cost"`
+}
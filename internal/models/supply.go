@@ -1,8 +1,13 @@
-package models
-
-type Supply struct {
-	IdInsumo    int64   `json:"id_insumo"`
-	Amount      float64 `json:"amount"`       //cantidad
-	TotalAmount float64 `json:"total_amount"` //precio total por el surtido
-	Date        string  `json:"date"`
-}
+package models
+
+type Supply struct {
+	IdInsumo    int64   `json:"id_insumo"`
+	Amount      float64 `json:"amount"`       //cantidad
+	TotalAmount Money   `json:"total_amount"` //lo que se pagó de verdad; 0 = unit_price * amount
+	Date        string  `json:"date"`
+	AccountID   int64   `json:"account_id"`  // de dónde sale el pago; 0 = caja
+	SupplierID  int64   `json:"supplier_id"` // 0 = sin proveedor
+	OrderItemID int64   `json:"-"`           // lo llena la recepción de una orden de compra
+	IsCredit    bool    `json:"is_credit"`   // true = a crédito con el proveedor, no sale plata
+	DueDate     string  `json:"due_date"`    // 2006-01-02; vacío = a 15 días
+}
@@ -0,0 +1,19 @@
+package models
+
+const (
+	RoleOwner    = "owner"    // dueño: todo, incluido ajustar caja y precios
+	RoleCashier  = "cashier"  // cajero: vender, surtir, abonos, clientes
+	RoleReadOnly = "readonly" // solo consulta
+)
+
+type User struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	Password  string `json:"password,omitempty"` // solo al crear, nunca se devuelve
+	Role      string `json:"role"`
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+func ValidRole(role string) bool {
+	return role == RoleOwner || role == RoleCashier || role == RoleReadOnly
+}
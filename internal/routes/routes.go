@@ -1,58 +1,182 @@
-package routes
-
-import (
-	"github.com/gorilla/mux"
-	"github.com/mgdavidd/server-Eme-Mar/internal/handlers"
-)
-
-func RegisterRoutes(
-	r *mux.Router,
-	clientHandler *handlers.ClientHandler,
-	insumoHandler *handlers.InsumoHandler,
-	movesHandler *handlers.MoveHandler,
-	productHandler *handlers.ProductHandler,
-) {
-
-	// --- CLIENTES ---
-	clientRoutes := r.PathPrefix("/clients").Subrouter()
-	clientRoutes.HandleFunc("", clientHandler.GetClients).Methods("GET")
-	clientRoutes.HandleFunc("", clientHandler.CreateClient).Methods("POST")
-	clientRoutes.HandleFunc("/debt", clientHandler.GetIndebtedClient).Methods("GET")
-	clientRoutes.HandleFunc("/{id}", clientHandler.UpdateClient).Methods("PUT")
-	clientRoutes.HandleFunc("/{id}", clientHandler.DeleteClient).Methods("DELETE")
-	clientRoutes.HandleFunc("/{id}", clientHandler.GetClientById).Methods("GET")
-
-	// --- INSUMOS ---
-	insumoRoutes := r.PathPrefix("/insumos").Subrouter()
-	insumoRoutes.HandleFunc("", insumoHandler.GetAllInsumos).Methods("GET")
-	insumoRoutes.HandleFunc("", insumoHandler.CreateInsumo).Methods("POST")
-	insumoRoutes.HandleFunc("/{id}", insumoHandler.GetByIdInsumos).Methods("GET")
-	insumoRoutes.HandleFunc("/{id}", insumoHandler.UpdateInsumo).Methods("PUT")
-	insumoRoutes.HandleFunc("/{id}", insumoHandler.DeleteInsumo).Methods("DELETE")
-
-	// --- PRODUCTOS ---
-	productRoutes := r.PathPrefix("/products").Subrouter()
-	productRoutes.HandleFunc("", productHandler.CreateProduct).Methods("POST")
-	productRoutes.HandleFunc("", productHandler.GetAllProducts).Methods("GET")
-	productRoutes.HandleFunc("/{id}", productHandler.GetByIdProducts).Methods("GET")
-	productRoutes.HandleFunc("/{id}", productHandler.UpdateProduct).Methods("PUT")
-	productRoutes.HandleFunc("/{id}", productHandler.DeleteProduct).Methods("DELETE")
-	productRoutes.HandleFunc("/{id}/insumos/{insumo_id}", productHandler.AddProductInsumo).Methods("POST")
-	productRoutes.HandleFunc("/{id}/insumos/{insumo_id}", productHandler.UpdateProductInsumo).Methods("PUT")
-	productRoutes.HandleFunc("/{id}/insumos/{insumo_id}", productHandler.DeleteProductInsumo).Methods("DELETE")
-
-	// --- MOVIMIENTOS ---
-	movesRoutes := r.PathPrefix("/moves").Subrouter()
-	movesRoutes.HandleFunc("", movesHandler.Supply).Methods("POST")
-	movesRoutes.HandleFunc("/sell", movesHandler.Sell).Methods("POST")
-	movesRoutes.HandleFunc("/pay/credit", movesHandler.PayCredit).Methods("POST")
-	movesRoutes.HandleFunc("", movesHandler.GetAllMoves).Methods("GET")
-	movesRoutes.HandleFunc("/client/{id}", movesHandler.GetMovesByClient).Methods("GET")
-	movesRoutes.HandleFunc("/recent", movesHandler.GetRecentMoves).Methods("GET")
-	movesRoutes.HandleFunc("/account", movesHandler.GetAccount).Methods("GET")
-	movesRoutes.HandleFunc("/credit/sales", movesHandler.AllCreditSales).Methods("GET")
-	movesRoutes.HandleFunc("/credit/client/{id}", movesHandler.GetClientCreditSales).Methods("GET")
-	movesRoutes.HandleFunc("/credit/payments/{sale_id}", movesHandler.GetCreditPayments).Methods("GET")
-	movesRoutes.HandleFunc("/adjust/balance", movesHandler.AdjustBalance).Methods("POST")
-
-}
+package routes
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/mgdavidd/server-Eme-Mar/internal/auth"
+	"github.com/mgdavidd/server-Eme-Mar/internal/handlers"
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+func RegisterRoutes(
+	r *mux.Router,
+	authenticator *auth.Authenticator,
+	authHandler *handlers.AuthHandler,
+	clientHandler *handlers.ClientHandler,
+	insumoHandler *handlers.InsumoHandler,
+	movesHandler *handlers.MoveHandler,
+	productHandler *handlers.ProductHandler,
+	saleHandler *handlers.SaleHandler,
+	cashHandler *handlers.CashSessionHandler,
+	accountHandler *handlers.AccountHandler,
+	supplierHandler *handlers.SupplierHandler,
+	orderHandler *handlers.PurchaseOrderHandler,
+	payableHandler *handlers.PayableHandler,
+	reconcileHandler *handlers.ReconcileHandler,
+	auditHandler *handlers.AuditHandler,
+	expenseHandler *handlers.ExpenseHandler,
+	recurringHandler *handlers.RecurringExpenseHandler,
+	reportHandler *handlers.ReportHandler,
+) {
+	ownerOnly := auth.Require(models.RoleOwner)
+
+	// --- AUTH (pública) ---
+	r.HandleFunc("/auth/login", authHandler.Login).Methods("POST")
+
+	// Todo lo demás exige token; readonly solo puede hacer GET
+	api := r.NewRoute().Subrouter()
+	api.Use(authenticator.Middleware)
+
+	api.HandleFunc("/auth/me", authHandler.Me).Methods("GET")
+
+	// --- USUARIOS ---
+	userRoutes := api.PathPrefix("/users").Subrouter()
+	userRoutes.HandleFunc("", ownerOnly(authHandler.GetUsers)).Methods("GET")
+	userRoutes.HandleFunc("", ownerOnly(authHandler.CreateUser)).Methods("POST")
+	userRoutes.HandleFunc("/{id}", ownerOnly(authHandler.DeleteUser)).Methods("DELETE")
+
+	// --- CLIENTES ---
+	clientRoutes := api.PathPrefix("/clients").Subrouter()
+	clientRoutes.HandleFunc("", clientHandler.GetClients).Methods("GET")
+	clientRoutes.HandleFunc("", clientHandler.CreateClient).Methods("POST")
+	clientRoutes.HandleFunc("/debt", clientHandler.GetIndebtedClient).Methods("GET")
+	clientRoutes.HandleFunc("/debt/aging", clientHandler.GetDebtAging).Methods("GET")
+	clientRoutes.HandleFunc("/{id}", ownerOnly(clientHandler.UpdateClient)).Methods("PUT")
+	clientRoutes.HandleFunc("/{id}", ownerOnly(clientHandler.DeleteClient)).Methods("DELETE")
+	clientRoutes.HandleFunc("/{id}", clientHandler.GetClientById).Methods("GET")
+	clientRoutes.HandleFunc("/{id}/restore", ownerOnly(clientHandler.RestoreClient)).Methods("POST")
+	clientRoutes.HandleFunc("/{id}/payments", clientHandler.ReceivePayment).Methods("POST")
+	clientRoutes.HandleFunc("/{id}/statement", clientHandler.GetStatement).Methods("GET")
+
+	// --- INSUMOS ---
+	insumoRoutes := api.PathPrefix("/insumos").Subrouter()
+	insumoRoutes.HandleFunc("", insumoHandler.GetAllInsumos).Methods("GET")
+	insumoRoutes.HandleFunc("", insumoHandler.CreateInsumo).Methods("POST")
+	insumoRoutes.HandleFunc("/{id}", insumoHandler.GetByIdInsumos).Methods("GET")
+	insumoRoutes.HandleFunc("/{id}/lots", insumoHandler.GetLots).Methods("GET")
+	insumoRoutes.HandleFunc("/{id}/prices", insumoHandler.GetPriceHistory).Methods("GET")
+	insumoRoutes.HandleFunc("/{id}", ownerOnly(insumoHandler.UpdateInsumo)).Methods("PUT")
+	insumoRoutes.HandleFunc("/{id}", ownerOnly(insumoHandler.DeleteInsumo)).Methods("DELETE")
+	insumoRoutes.HandleFunc("/{id}/restore", ownerOnly(insumoHandler.RestoreInsumo)).Methods("POST")
+
+	// --- PROVEEDORES ---
+	supplierRoutes := api.PathPrefix("/suppliers").Subrouter()
+	supplierRoutes.HandleFunc("", supplierHandler.GetSuppliers).Methods("GET")
+	supplierRoutes.HandleFunc("", supplierHandler.CreateSupplier).Methods("POST")
+	supplierRoutes.HandleFunc("/prices", supplierHandler.GetPriceComparison).Methods("GET")
+	supplierRoutes.HandleFunc("/{id}", supplierHandler.GetSupplierById).Methods("GET")
+	supplierRoutes.HandleFunc("/{id}", ownerOnly(supplierHandler.UpdateSupplier)).Methods("PUT")
+	supplierRoutes.HandleFunc("/{id}", ownerOnly(supplierHandler.DeleteSupplier)).Methods("DELETE")
+	supplierRoutes.HandleFunc("/{id}/purchases", supplierHandler.GetPurchases).Methods("GET")
+
+	// --- ÓRDENES DE COMPRA ---
+	orderRoutes := api.PathPrefix("/purchase-orders").Subrouter()
+	orderRoutes.HandleFunc("", orderHandler.GetOrders).Methods("GET")
+	orderRoutes.HandleFunc("", orderHandler.CreateOrder).Methods("POST")
+	orderRoutes.HandleFunc("/pending", orderHandler.GetPending).Methods("GET")
+	orderRoutes.HandleFunc("/{id}", orderHandler.GetOrderById).Methods("GET")
+	orderRoutes.HandleFunc("/{id}/export", orderHandler.ExportOrder).Methods("GET")
+	orderRoutes.HandleFunc("/{id}/receive", orderHandler.ReceiveOrder).Methods("POST")
+	orderRoutes.HandleFunc("/{id}/cancel", ownerOnly(orderHandler.CancelOrder)).Methods("POST")
+
+	// --- CUENTAS POR PAGAR ---
+	payableRoutes := api.PathPrefix("/payables").Subrouter()
+	payableRoutes.HandleFunc("", payableHandler.GetPayables).Methods("GET")
+	payableRoutes.HandleFunc("/suppliers", payableHandler.GetBySupplier).Methods("GET")
+	payableRoutes.HandleFunc("/{id}/payments", payableHandler.GetPayments).Methods("GET")
+	payableRoutes.HandleFunc("/{id}/payments", payableHandler.PayPayable).Methods("POST")
+
+	// --- PRODUCTOS ---
+	productRoutes := api.PathPrefix("/products").Subrouter()
+	productRoutes.HandleFunc("", ownerOnly(productHandler.CreateProduct)).Methods("POST")
+	productRoutes.HandleFunc("", productHandler.GetAllProducts).Methods("GET")
+	productRoutes.HandleFunc("/{id}", productHandler.GetByIdProducts).Methods("GET")
+	productRoutes.HandleFunc("/{id}", ownerOnly(productHandler.UpdateProduct)).Methods("PUT")
+	productRoutes.HandleFunc("/{id}", ownerOnly(productHandler.DeleteProduct)).Methods("DELETE")
+	productRoutes.HandleFunc("/{id}/restore", ownerOnly(productHandler.RestoreProduct)).Methods("POST")
+	productRoutes.HandleFunc("/{id}/insumos/{insumo_id}", ownerOnly(productHandler.AddProductInsumo)).Methods("POST")
+	productRoutes.HandleFunc("/{id}/insumos/{insumo_id}", ownerOnly(productHandler.UpdateProductInsumo)).Methods("PUT")
+	productRoutes.HandleFunc("/{id}/insumos/{insumo_id}", ownerOnly(productHandler.DeleteProductInsumo)).Methods("DELETE")
+	productRoutes.HandleFunc("/{id}/production", productHandler.GetBatches).Methods("GET")
+	productRoutes.HandleFunc("/{id}/production", productHandler.Produce).Methods("POST")
+
+	// --- MOVIMIENTOS ---
+	movesRoutes := api.PathPrefix("/moves").Subrouter()
+	movesRoutes.HandleFunc("", movesHandler.Supply).Methods("POST")
+	movesRoutes.HandleFunc("/sell", movesHandler.Sell).Methods("POST")
+	movesRoutes.HandleFunc("/pay/credit", movesHandler.PayCredit).Methods("POST")
+	movesRoutes.HandleFunc("", movesHandler.GetAllMoves).Methods("GET")
+	movesRoutes.HandleFunc("/client/{id}", movesHandler.GetMovesByClient).Methods("GET")
+	movesRoutes.HandleFunc("/recent", movesHandler.GetRecentMoves).Methods("GET")
+	movesRoutes.HandleFunc("/account", movesHandler.GetAccount).Methods("GET")
+	movesRoutes.HandleFunc("/credit/sales", movesHandler.AllCreditSales).Methods("GET")
+	movesRoutes.HandleFunc("/credit/client/{id}", movesHandler.GetClientCreditSales).Methods("GET")
+	movesRoutes.HandleFunc("/credit/payments/{sale_id}", movesHandler.GetCreditPayments).Methods("GET")
+	movesRoutes.HandleFunc("/adjust/balance", ownerOnly(movesHandler.AdjustBalance)).Methods("POST")
+
+	// --- VENTAS ---
+	saleRoutes := api.PathPrefix("/sales").Subrouter()
+	saleRoutes.HandleFunc("", saleHandler.GetSales).Methods("GET")
+	saleRoutes.HandleFunc("/{id}", saleHandler.GetSaleById).Methods("GET")
+	saleRoutes.HandleFunc("/{id}/void", ownerOnly(saleHandler.VoidSale)).Methods("POST")
+	saleRoutes.HandleFunc("/{id}/returns", ownerOnly(saleHandler.ReturnSale)).Methods("POST")
+
+	// --- SESIONES DE CAJA ---
+	cashRoutes := api.PathPrefix("/cash/sessions").Subrouter()
+	cashRoutes.HandleFunc("", cashHandler.GetSessions).Methods("GET")
+	cashRoutes.HandleFunc("/current", cashHandler.GetCurrent).Methods("GET")
+	cashRoutes.HandleFunc("/open", cashHandler.Open).Methods("POST")
+	cashRoutes.HandleFunc("/close", cashHandler.Close).Methods("POST")
+	cashRoutes.HandleFunc("/{id}/report", cashHandler.GetReport).Methods("GET")
+
+	// --- CUENTAS ---
+	accountRoutes := api.PathPrefix("/accounts").Subrouter()
+	accountRoutes.HandleFunc("", accountHandler.GetAccounts).Methods("GET")
+	accountRoutes.HandleFunc("", ownerOnly(accountHandler.CreateAccount)).Methods("POST")
+	accountRoutes.HandleFunc("/transfers", ownerOnly(accountHandler.Transfer)).Methods("POST")
+	accountRoutes.HandleFunc("/{id}", ownerOnly(accountHandler.UpdateAccount)).Methods("PUT")
+
+	// --- GASTOS ---
+	expenseRoutes := api.PathPrefix("/expenses").Subrouter()
+	expenseRoutes.HandleFunc("", expenseHandler.GetExpenses).Methods("GET")
+	expenseRoutes.HandleFunc("", expenseHandler.CreateExpense).Methods("POST")
+	expenseRoutes.HandleFunc("/totals", expenseHandler.GetMonthlyTotals).Methods("GET")
+	expenseRoutes.HandleFunc("/categories", expenseHandler.GetCategories).Methods("GET")
+	expenseRoutes.HandleFunc("/categories", ownerOnly(expenseHandler.CreateCategory)).Methods("POST")
+	expenseRoutes.HandleFunc("/recurring", recurringHandler.GetRecurring).Methods("GET")
+	expenseRoutes.HandleFunc("/recurring", ownerOnly(recurringHandler.CreateRecurring)).Methods("POST")
+	expenseRoutes.HandleFunc("/recurring/{id}", recurringHandler.GetRecurringById).Methods("GET")
+	expenseRoutes.HandleFunc("/recurring/{id}", ownerOnly(recurringHandler.UpdateRecurring)).Methods("PUT")
+	expenseRoutes.HandleFunc("/scheduled", recurringHandler.GetScheduled).Methods("GET")
+	expenseRoutes.HandleFunc("/scheduled/{id}/confirm", ownerOnly(recurringHandler.ConfirmScheduled)).Methods("POST")
+	expenseRoutes.HandleFunc("/scheduled/{id}/discard", ownerOnly(recurringHandler.DiscardScheduled)).Methods("POST")
+	expenseRoutes.HandleFunc("/{id}", expenseHandler.GetExpenseById).Methods("GET")
+	expenseRoutes.HandleFunc("/{id}/receipt", expenseHandler.GetReceipt).Methods("GET")
+
+	// --- TABLERO ---
+	api.HandleFunc("/dashboard", reportHandler.GetDashboard).Methods("GET")
+
+	// --- REPORTES ---
+	reportRoutes := api.PathPrefix("/reports").Subrouter()
+	reportRoutes.HandleFunc("/pnl", ownerOnly(reportHandler.GetPnL)).Methods("GET")
+	reportRoutes.HandleFunc("/products", ownerOnly(reportHandler.GetProductSales)).Methods("GET")
+
+	// --- ADMINISTRACIÓN ---
+	adminRoutes := api.PathPrefix("/admin").Subrouter()
+	adminRoutes.HandleFunc("/reconcile", ownerOnly(reconcileHandler.Check)).Methods("GET")
+	adminRoutes.HandleFunc("/reconcile", ownerOnly(reconcileHandler.Repair)).Methods("POST")
+	adminRoutes.HandleFunc("/reconcile/log", ownerOnly(reconcileHandler.GetLog)).Methods("GET")
+
+	// --- AUDITORÍA ---
+	api.HandleFunc("/audit", ownerOnly(auditHandler.GetAudit)).Methods("GET")
+
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+type AccountService struct {
+	DB *sql.DB
+}
+
+func NewAccountService(db *sql.DB) *AccountService {
+	return &AccountService{DB: db}
+}
+
+func (s *AccountService) GetAll() ([]models.MoneyAccount, error) {
+	return listAccounts(s.DB)
+}
+
+func listAccounts(db *sql.DB) ([]models.MoneyAccount, error) {
+	rows, err := db.Query(`SELECT id, name, kind, saldo FROM accounts ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.MoneyAccount{}
+	for rows.Next() {
+		var a models.MoneyAccount
+		if err := rows.Scan(&a.ID, &a.Name, &a.Kind, &a.Balance); err != nil {
+			return nil, err
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+// Create abre una cuenta en cero; el saldo inicial se carga con un ajuste.
+func (s *AccountService) Create(a *models.MoneyAccount) error {
+	a.Name = strings.TrimSpace(a.Name)
+	if a.Name == "" || !models.ValidAccountKind(a.Kind) {
+		return ErrInvalidInput
+	}
+
+	res, err := s.DB.Exec(`INSERT INTO accounts (name, kind, saldo) VALUES (?, ?, 0)`, a.Name, a.Kind)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return ErrInvalidInput
+		}
+		return err
+	}
+
+	a.ID, _ = res.LastInsertId()
+	a.Balance = 0
+	return nil
+}
+
+// Update cambia nombre y tipo. El saldo solo cambia con movimientos.
+func (s *AccountService) Update(a *models.MoneyAccount) error {
+	a.Name = strings.TrimSpace(a.Name)
+	if a.Name == "" || !models.ValidAccountKind(a.Kind) {
+		return ErrInvalidInput
+	}
+
+	res, err := s.DB.Exec(`UPDATE accounts SET name = ?, kind = ? WHERE id = ?`, a.Name, a.Kind, a.ID)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return ErrInvalidInput
+		}
+		return err
+	}
+
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return s.DB.QueryRow(`SELECT saldo FROM accounts WHERE id = ?`, a.ID).Scan(&a.Balance)
+}
+
+// Transfer deja un movimiento de salida y otro de entrada con tipo
+// transferencia, que no cuentan como ingreso ni egreso.
+func (s *AccountService) Transfer(t models.Transfer) (err error) {
+	if t.Amount <= 0 || t.FromAccountID == t.ToAccountID {
+		return ErrInvalidInput
+	}
+	now := time.Now().Format("2006-01-02 15:04")
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	var fromName, toName string
+	err = tx.QueryRow(`SELECT name FROM accounts WHERE id = ?`, t.FromAccountID).Scan(&fromName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAccountNotFound
+	}
+	if err != nil {
+		return err
+	}
+	err = tx.QueryRow(`SELECT name FROM accounts WHERE id = ?`, t.ToAccountID).Scan(&toName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAccountNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	note := ""
+	if d := strings.TrimSpace(t.Description); d != "" {
+		note = ": " + d
+	}
+
+	err = recordMove(tx, moveRecord{
+		Description: "Transferencia a " + toName + note,
+		Type:        models.MoveTransfer,
+		Origin:      models.OriginTransfer,
+		Amount:      -t.Amount,
+		Date:        now,
+		AccountID:   t.FromAccountID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return recordMove(tx, moveRecord{
+		Description: "Transferencia desde " + fromName + note,
+		Type:        models.MoveTransfer,
+		Origin:      models.OriginTransfer,
+		Amount:      t.Amount,
+		Date:        now,
+		AccountID:   t.ToAccountID,
+	})
+}
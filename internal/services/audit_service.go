@@ -0,0 +1,125 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 500
+)
+
+type AuditService struct {
+	DB *sql.DB
+}
+
+func NewAuditService(db *sql.DB) *AuditService {
+	return &AuditService{DB: db}
+}
+
+// execer lo cumplen *sql.DB y *sql.Tx, para auditar dentro o fuera de una transacción.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// writeAudit guarda un cambio en audit_log. before/after se serializan a
+// JSON; nil queda como NULL.
+func writeAudit(ex execer, user, action, entity string, entityID int64, before, after any) error {
+	b, err := auditJSON(before)
+	if err != nil {
+		return err
+	}
+	a, err := auditJSON(after)
+	if err != nil {
+		return err
+	}
+
+	_, err = ex.Exec(`
+		INSERT INTO audit_log (date, actor, action, entity, entity_id, before, after)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, time.Now().Format("2006-01-02 15:04:05"), user, action, entity, entityID, b, a)
+	return err
+}
+
+func auditJSON(v any) (sql.NullString, error) {
+	if v == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// GetAll devuelve la bitácora, lo más reciente primero.
+func (s *AuditService) GetAll(f models.AuditFilter) ([]models.AuditEntry, error) {
+	where := []string{"1 = 1"}
+	args := []any{}
+
+	if f.Entity != "" {
+		where = append(where, "entity = ?")
+		args = append(args, f.Entity)
+	}
+	if f.EntityID > 0 {
+		where = append(where, "entity_id = ?")
+		args = append(args, f.EntityID)
+	}
+	if f.Actor != "" {
+		where = append(where, "actor = ?")
+		args = append(args, f.Actor)
+	}
+	if f.From != "" {
+		where = append(where, "date(date) >= ?")
+		args = append(args, f.From)
+	}
+	if f.To != "" {
+		where = append(where, "date(date) <= ?")
+		args = append(args, f.To)
+	}
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	limit = min(limit, maxAuditLimit)
+	args = append(args, limit)
+
+	rows, err := s.DB.Query(`
+		SELECT id, date, actor, action, entity, entity_id, before, after
+		FROM audit_log
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		var before, after sql.NullString
+		if err := rows.Scan(&e.ID, &e.Date, &e.Actor, &e.Action, &e.Entity, &e.EntityID, &before, &after); err != nil {
+			return nil, err
+		}
+		e.Before = rawJSON(before)
+		e.After = rawJSON(after)
+		list = append(list, e)
+	}
+
+	return list, rows.Err()
+}
+
+func rawJSON(s sql.NullString) json.RawMessage {
+	if !s.Valid {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(s.String)
+}
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+var (
+	ErrSessionOpen   = errors.New("ya hay una caja abierta")
+	ErrNoOpenSession = errors.New("no hay una caja abierta")
+)
+
+type CashSessionService struct {
+	DB *sql.DB
+}
+
+func NewCashSessionService(db *sql.DB) *CashSessionService {
+	return &CashSessionService{DB: db}
+}
+
+const cashSessionColumns = `
+	id, opened_at, opened_by, opening_amount, closed_at, closed_by,
+	expected_amount, counted_amount, difference, notes`
+
+func scanCashSession(r rowScanner) (models.CashSession, error) {
+	var cs models.CashSession
+	var closedAt, closedBy sql.NullString
+	var expected, counted, diff sql.NullInt64
+
+	err := r.Scan(&cs.ID, &cs.OpenedAt, &cs.OpenedBy, &cs.OpeningAmount, &closedAt, &closedBy,
+		&expected, &counted, &diff, &cs.Notes)
+	if err != nil {
+		return models.CashSession{}, err
+	}
+
+	if closedAt.Valid {
+		cs.ClosedAt = &closedAt.String
+	}
+	if closedBy.Valid {
+		cs.ClosedBy = &closedBy.String
+	}
+	if expected.Valid {
+		m := models.Money(expected.Int64)
+		cs.ExpectedAmount = &m
+	}
+	if counted.Valid {
+		m := models.Money(counted.Int64)
+		cs.CountedAmount = &m
+	}
+	if diff.Valid {
+		m := models.Money(diff.Int64)
+		cs.Difference = &m
+	}
+	return cs, nil
+}
+
+func (s *CashSessionService) GetAll() ([]models.CashSession, error) {
+	rows, err := s.DB.Query(`SELECT ` + cashSessionColumns + ` FROM cash_sessions ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.CashSession{}
+	for rows.Next() {
+		cs, err := scanCashSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, cs)
+	}
+	return list, rows.Err()
+}
+
+func (s *CashSessionService) GetCurrent() (models.CashSession, error) {
+	cs, err := scanCashSession(s.DB.QueryRow(`
+		SELECT ` + cashSessionColumns + ` FROM cash_sessions WHERE closed_at IS NULL
+	`))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.CashSession{}, ErrNoOpenSession
+	}
+	return cs, err
+}
+
+// Open abre la caja con la base contada. Si no coincide con el saldo que
+// tenía la caja, la diferencia queda como un ajuste fuera de la sesión.
+func (s *CashSessionService) Open(count models.CashCount, user string) (cs models.CashSession, err error) {
+	if count.Amount < 0 {
+		return cs, ErrInvalidInput
+	}
+	now := time.Now().Format("2006-01-02 15:04")
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return cs, err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	var open int
+	if err = tx.QueryRow(`SELECT COUNT(*) FROM cash_sessions WHERE closed_at IS NULL`).Scan(&open); err != nil {
+		return cs, err
+	}
+	if open > 0 {
+		return cs, ErrSessionOpen
+	}
+
+	// El ajuste va antes de crear la sesión para que no cuente dentro de ella
+	var balance models.Money
+	err = tx.QueryRow(`SELECT saldo FROM accounts WHERE id = ?`, models.CashAccountID).Scan(&balance)
+	if err != nil {
+		return cs, err
+	}
+
+	if diff := count.Amount - balance; diff != 0 {
+		tipo := models.MoveIngreso
+		if diff < 0 {
+			tipo = models.MoveEgreso
+			diff = -diff
+		}
+		err = recordMove(tx, moveRecord{
+			Description: "Diferencia en apertura de caja",
+			Type:        tipo,
+			Origin:      models.OriginAdjustment,
+			Amount:      diff,
+			Date:        now,
+		})
+		if err != nil {
+			return cs, err
+		}
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO cash_sessions (opened_at, opened_by, opening_amount, notes)
+		VALUES (?, ?, ?, ?)
+	`, now, user, count.Amount, count.Notes)
+	if err != nil {
+		return cs, err
+	}
+	id, _ := res.LastInsertId()
+
+	cs, err = scanCashSession(tx.QueryRow(`SELECT `+cashSessionColumns+` FROM cash_sessions WHERE id = ?`, id))
+	return cs, err
+}
+
+// Close cierra la sesión abierta con lo contado. La diferencia contra lo
+// esperado se registra como su propio movimiento, así la caja queda en lo contado.
+func (s *CashSessionService) Close(count models.CashCount, user string) (report models.CashSessionReport, err error) {
+	if count.Amount < 0 {
+		return report, ErrInvalidInput
+	}
+	now := time.Now().Format("2006-01-02 15:04")
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return report, err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	var id int64
+	var opening models.Money
+	err = tx.QueryRow(`
+		SELECT id, opening_amount FROM cash_sessions WHERE closed_at IS NULL
+	`).Scan(&id, &opening)
+	if errors.Is(err, sql.ErrNoRows) {
+		return report, ErrNoOpenSession
+	}
+	if err != nil {
+		return report, err
+	}
+
+	// Solo la caja física: lo que entra por Nequi o banco no está en el cajón
+	var net models.Money
+	err = tx.QueryRow(`
+		SELECT COALESCE(SUM(CASE tipo
+			WHEN 'ingreso' THEN monto
+			WHEN 'egreso' THEN -monto
+			WHEN 'transferencia' THEN monto
+			ELSE 0 END), 0)
+		FROM movimientos WHERE sesion_id = ? AND cuenta_id = ?
+	`, id, models.CashAccountID).Scan(&net)
+	if err != nil {
+		return report, err
+	}
+
+	expected := opening + net
+	diff := count.Amount - expected
+
+	if diff != 0 {
+		tipo := models.MoveIngreso
+		amount := diff
+		if diff < 0 {
+			tipo = models.MoveEgreso
+			amount = -diff
+		}
+		err = recordMove(tx, moveRecord{
+			Description: "Diferencia en cierre de caja #" + strconv.FormatInt(id, 10),
+			Type:        tipo,
+			Origin:      models.OriginCashClose,
+			Amount:      amount,
+			Date:        now,
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+
+	notes := count.Notes
+	_, err = tx.Exec(`
+		UPDATE cash_sessions
+		SET closed_at = ?, closed_by = ?, expected_amount = ?, counted_amount = ?, difference = ?,
+			notes = CASE WHEN ? = '' THEN notes ELSE ? END
+		WHERE id = ?
+	`, now, user, expected, count.Amount, diff, notes, notes, id)
+	if err != nil {
+		return report, err
+	}
+
+	report, err = sessionReport(tx, id)
+	return report, err
+}
+
+func (s *CashSessionService) Report(id int64) (models.CashSessionReport, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return models.CashSessionReport{}, err
+	}
+	defer tx.Rollback()
+
+	return sessionReport(tx, id)
+}
+
+func sessionReport(tx *sql.Tx, id int64) (models.CashSessionReport, error) {
+	var r models.CashSessionReport
+
+	cs, err := scanCashSession(tx.QueryRow(`SELECT `+cashSessionColumns+` FROM cash_sessions WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return r, ErrNotFound
+	}
+	if err != nil {
+		return r, err
+	}
+	r.Session = cs
+
+	rows, err := tx.Query(`
+		SELECT id, descripcion, tipo, origen, monto, fecha, cliente_id, cuenta_id
+		FROM movimientos WHERE sesion_id = ?
+		ORDER BY id
+	`, id)
+	if err != nil {
+		return r, err
+	}
+	defer rows.Close()
+
+	r.Moves = []models.Move{}
+	r.Expected = cs.OpeningAmount
+	for rows.Next() {
+		var m models.Move
+		var clientID sql.NullInt64
+		if err := rows.Scan(&m.ID, &m.Description, &m.Type, &m.Origin, &m.Amount, &m.Date, &clientID, &m.AccountID); err != nil {
+			return r, err
+		}
+		if clientID.Valid {
+			m.ClientID = &clientID.Int64
+		}
+		r.Moves = append(r.Moves, m)
+
+		signed := m.Amount
+		if m.Type == models.MoveEgreso {
+			signed = -signed
+		}
+
+		switch m.Origin {
+		case models.OriginTransfer:
+			if m.AccountID == models.CashAccountID {
+				r.Transfers += signed
+			}
+		case models.OriginSale:
+			r.SalesCount++
+			r.Sales += m.Amount
+		case models.OriginPayment:
+			r.Payments += m.Amount
+		case models.OriginSupply:
+			r.Supplies += m.Amount
+		case models.OriginPayable:
+			r.Payables += m.Amount
+		case models.OriginReturn:
+			r.Refunds += m.Amount
+		case models.OriginCashClose:
+			// la diferencia del cierre no forma parte de lo esperado
+			continue
+		default:
+			r.Adjustments += signed
+		}
+		if m.AccountID == models.CashAccountID {
+			r.Expected += signed
+		}
+	}
+
+	return r, rows.Err()
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+type openCreditSale struct {
+	id        int64
+	remaining models.Money
+}
+
+// ReceivePayment reparte un abono entre los fiados abiertos del cliente: una
+// fila de credit_payments por fiado y un solo movimiento por el total.
+func (s *ClientService) ReceivePayment(clientID int64, p models.ClientPayment, user string) (res models.ClientPaymentResult, err error) {
+	if p.Amount <= 0 {
+		return res, ErrInvalidInput
+	}
+	now := time.Now().Format("2006-01-02 15:04")
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return res, err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	var name string
+	err = tx.QueryRow(`SELECT nombre FROM clientes WHERE id = ?`, clientID).Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return res, ErrNotFound
+	}
+	if err != nil {
+		return res, err
+	}
+
+	open, err := openCreditSales(tx, clientID)
+	if err != nil {
+		return res, err
+	}
+
+	var allocs []models.PaymentAllocation
+	if len(p.Allocations) > 0 {
+		allocs, err = explicitAllocations(p.Allocations, open, p.Amount)
+	} else {
+		allocs = oldestFirst(open, p.Amount)
+	}
+	if err != nil {
+		return res, err
+	}
+
+	var allocated models.Money
+	for _, a := range allocs {
+		allocated += a.Amount
+
+		_, err = tx.Exec(`
+			UPDATE credit_sales SET remaining_balance = remaining_balance - ? WHERE id = ?
+		`, a.Amount, a.CreditSaleID)
+		if err != nil {
+			return res, err
+		}
+
+		_, err = tx.Exec(`INSERT INTO credit_payments (credit_sale_id, amount, date) VALUES (?, ?, ?)`,
+			a.CreditSaleID, a.Amount, now)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	leftover := p.Amount - allocated
+	if leftover > 0 && !p.KeepCredit {
+		return res, ErrOverpayment
+	}
+
+	if allocated > 0 {
+		r, err := tx.Exec(`UPDATE clientes SET deuda = deuda - ? WHERE id = ? AND deuda >= ?`, allocated, clientID, allocated)
+		if err != nil {
+			return res, err
+		}
+		ra, _ := r.RowsAffected()
+		if ra == 0 {
+			return res, ErrInvalidInput
+		}
+	}
+
+	if leftover > 0 {
+		_, err = tx.Exec(`UPDATE clientes SET saldo_favor = saldo_favor + ? WHERE id = ?`, leftover, clientID)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	err = recordMove(tx, moveRecord{
+		Description: "Abono de " + name,
+		Type:        models.MoveIngreso,
+		Origin:      models.OriginPayment,
+		Amount:      p.Amount,
+		Date:        now,
+		ClientID:    clientID,
+		AccountID:   p.AccountID,
+	})
+	if err != nil {
+		return res, err
+	}
+
+	res = models.ClientPaymentResult{
+		ClientID:    clientID,
+		Amount:      p.Amount,
+		Allocations: allocs,
+		CreditAdded: leftover,
+	}
+	err = tx.QueryRow(`SELECT deuda, saldo_favor FROM clientes WHERE id = ?`, clientID).Scan(&res.Debt, &res.Credit)
+	if err != nil {
+		return res, err
+	}
+
+	return res, writeAudit(tx, user, models.AuditCreate, models.AuditPayment, clientID, nil, res)
+}
+
+// openCreditSales devuelve los fiados con saldo del cliente, el más viejo primero.
+func openCreditSales(tx *sql.Tx, clientID int64) ([]openCreditSale, error) {
+	rows, err := tx.Query(`
+		SELECT id, remaining_balance
+		FROM credit_sales
+		WHERE client_id = ? AND remaining_balance > 0
+		ORDER BY date ASC, id ASC
+	`, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []openCreditSale
+	for rows.Next() {
+		var cs openCreditSale
+		if err := rows.Scan(&cs.id, &cs.remaining); err != nil {
+			return nil, err
+		}
+		list = append(list, cs)
+	}
+	return list, rows.Err()
+}
+
+func oldestFirst(open []openCreditSale, amount models.Money) []models.PaymentAllocation {
+	allocs := []models.PaymentAllocation{}
+	for _, cs := range open {
+		if amount <= 0 {
+			break
+		}
+		a := min(amount, cs.remaining)
+		allocs = append(allocs, models.PaymentAllocation{CreditSaleID: cs.id, Amount: a})
+		amount -= a
+	}
+	return allocs
+}
+
+// explicitAllocations valida que cada fiado sea del cliente, no se repita ni
+// reciba más de lo que debe, y que la suma no pase del abono.
+func explicitAllocations(req []models.PaymentAllocation, open []openCreditSale, amount models.Money) ([]models.PaymentAllocation, error) {
+	remaining := map[int64]models.Money{}
+	for _, cs := range open {
+		remaining[cs.id] = cs.remaining
+	}
+
+	var total models.Money
+	seen := map[int64]bool{}
+	for _, a := range req {
+		rem, ok := remaining[a.CreditSaleID]
+		if !ok || seen[a.CreditSaleID] || a.Amount <= 0 || a.Amount > rem {
+			return nil, ErrInvalidInput
+		}
+		seen[a.CreditSaleID] = true
+		total += a.Amount
+	}
+	if total > amount {
+		return nil, ErrInvalidInput
+	}
+
+	return req, nil
+}
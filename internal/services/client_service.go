@@ -1,136 +1,367 @@
-package services
-
-import (
-	"database/sql"
-
-	"github.com/mgdavidd/server-Eme-Mar/internal/models"
-)
-
-type ClientService struct {
-	DB *sql.DB
-}
-
-func NewClientService(db *sql.DB) *ClientService {
-	return &ClientService{DB: db}
-}
-
-func (s *ClientService) GetAll() ([]models.Client, error) {
-	rows, err := s.DB.Query(`
-        SELECT id, nombre, telefono, deuda 
-        FROM clientes ORDER BY nombre ASC
-    `)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	list := []models.Client{}
-	for rows.Next() {
-		var c models.Client
-		rows.Scan(&c.ID, &c.Name, &c.Phone, &c.Debt)
-		list = append(list, c)
-	}
-
-	return list, nil
-}
-
-func (s *ClientService) GetById(id int) (models.Client, error) {
-	var c models.Client
-
-	err := s.DB.QueryRow(`
-        SELECT id, nombre, telefono, deuda 
-        FROM clientes WHERE id = ?
-    `, id).Scan(&c.ID, &c.Name, &c.Phone, &c.Debt)
-
-	if err == sql.ErrNoRows {
-		return models.Client{}, ErrNotFound
-	}
-	if err != nil {
-		return models.Client{}, err
-	}
-	return c, nil
-}
-
-func (s *ClientService) Create(c *models.Client) error {
-	stmt, err := s.DB.Prepare(`
-        INSERT INTO clientes (nombre, telefono, deuda)
-        VALUES (?, ?, ?)
-    `)
-	if err != nil {
-		return err
-	}
-
-	res, err := stmt.Exec(c.Name, c.Phone, c.Debt)
-	if err != nil {
-		return err
-	}
-
-	id, _ := res.LastInsertId()
-	c.ID = id
-	return nil
-}
-
-func (s *ClientService) UpdateClient(c *models.Client) (*models.Client, error) {
-	res, err := s.DB.Exec(`
-        UPDATE clientes
-        SET nombre = ?, telefono = ?, deuda = ?
-        WHERE id = ?
-    `, c.Name, c.Phone, c.Debt, c.ID)
-
-	if err != nil {
-		return nil, err
-	}
-
-	rows, _ := res.RowsAffected()
-	if rows == 0 {
-		return nil, ErrNotFound
-	}
-
-	var updated models.Client
-	err = s.DB.QueryRow(`
-		SELECT id, nombre, telefono, deuda
-		FROM clientes
-		WHERE id = ?
-	`, c.ID).Scan(&updated.ID, &updated.Name, &updated.Phone, &updated.Debt)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return &updated, nil
-}
-
-func (s *ClientService) DeleteClient(id int) error {
-	res, err := s.DB.Exec(`DELETE FROM clientes WHERE id = ?`, id)
-	if err != nil {
-		return err
-	}
-
-	rows, _ := res.RowsAffected()
-	if rows == 0 {
-		return ErrNotFound
-	}
-
-	return nil
-}
-
-// clientes que deben
-func (s *ClientService) GetIndebtedClient() ([]models.Client, error) {
-	rows, err := s.DB.Query(`
-        SELECT id, nombre, telefono, deuda 
-        FROM clientes WHERE deuda > 0
-    `)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	list := []models.Client{}
-	for rows.Next() {
-		var c models.Client
-		rows.Scan(&c.ID, &c.Name, &c.Phone, &c.Debt)
-		list = append(list, c)
-	}
-
-	return list, nil
-}
+package services
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+type ClientService struct {
+	DB *sql.DB
+}
+
+func NewClientService(db *sql.DB) *ClientService {
+	return &ClientService{DB: db}
+}
+
+// clientColumns son las columnas que lee scanClient, con lo vencido calculado
+const clientColumns = `
+	id, nombre, telefono, deuda, saldo_favor, limite_credito, COALESCE(plazo_dias, 0),
+	(SELECT COALESCE(SUM(remaining_balance), 0) FROM credit_sales
+	 WHERE client_id = clientes.id AND remaining_balance > 0 AND due_date < date('now', 'localtime')) AS vencido,
+	archived_at`
+
+func scanClient(row rowScanner) (models.Client, error) {
+	var c models.Client
+	var limit sql.NullInt64
+	err := row.Scan(&c.ID, &c.Name, &c.Phone, &c.Debt, &c.Credit, &limit, &c.TermDays, &c.OverdueAmount, &c.ArchivedAt)
+	if err != nil {
+		return c, err
+	}
+	if limit.Valid {
+		l := models.Money(limit.Int64)
+		c.CreditLimit = &l
+	}
+	c.Overdue = c.OverdueAmount > 0
+	return c, nil
+}
+
+// GetAll lista los clientes activos, o solo los archivados si archived es true.
+func (s *ClientService) GetAll(archived bool) ([]models.Client, error) {
+	rows, err := s.DB.Query(`
+        SELECT `+clientColumns+`
+        FROM clientes WHERE (archived_at IS NOT NULL) = ?
+        ORDER BY nombre ASC
+    `, archived)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.Client{}
+	for rows.Next() {
+		c, err := scanClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+
+	return list, nil
+}
+
+func (s *ClientService) GetById(id int) (models.Client, error) {
+	return clientByID(s.DB, int64(id))
+}
+
+// clientByID lee el cliente con q, que puede ser la transacción en curso.
+func clientByID(q Queryer, id int64) (models.Client, error) {
+	c, err := scanClient(q.QueryRow(`
+        SELECT `+clientColumns+`
+        FROM clientes WHERE id = ?
+    `, id))
+
+	if err == sql.ErrNoRows {
+		return models.Client{}, ErrNotFound
+	}
+	if err != nil {
+		return models.Client{}, err
+	}
+	return c, nil
+}
+
+func (s *ClientService) Create(c *models.Client, user string) (err error) {
+	if c.TermDays < 0 || (c.CreditLimit != nil && *c.CreditLimit < 0) {
+		return ErrInvalidInput
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	res, err := tx.Exec(`
+        INSERT INTO clientes (nombre, telefono, deuda, limite_credito, plazo_dias)
+        VALUES (?, ?, ?, ?, ?)
+    `, c.Name, c.Phone, c.Debt, c.CreditLimit, termDays(c.TermDays))
+	if err != nil {
+		return err
+	}
+
+	id, _ := res.LastInsertId()
+	c.ID = id
+
+	return writeAudit(tx, user, models.AuditCreate, models.AuditClient, c.ID, nil, c)
+}
+
+func (s *ClientService) UpdateClient(c *models.Client, user string) (updated *models.Client, err error) {
+	if c.TermDays < 0 || (c.CreditLimit != nil && *c.CreditLimit < 0) {
+		return nil, ErrInvalidInput
+	}
+
+	before, err := s.GetById(int(c.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	res, err := tx.Exec(`
+        UPDATE clientes
+        SET nombre = ?, telefono = ?, deuda = ?, limite_credito = ?, plazo_dias = ?
+        WHERE id = ?
+    `, c.Name, c.Phone, c.Debt, c.CreditLimit, termDays(c.TermDays), c.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return nil, ErrNotFound
+	}
+
+	after, err := clientByID(tx, c.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = writeAudit(tx, user, models.AuditUpdate, models.AuditClient, c.ID, before, after); err != nil {
+		return nil, err
+	}
+
+	return &after, nil
+}
+
+// termDays guarda 0 como NULL, que es "usar el plazo por defecto".
+func termDays(days int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(days), Valid: days > 0}
+}
+
+// DeleteClient archiva el cliente: sus fiados y abonos siguen ahí para los
+// reportes. No se puede archivar a alguien que todavía debe.
+func (s *ClientService) DeleteClient(id int, user string) (err error) {
+	before, err := s.GetById(id)
+	if err != nil {
+		return err
+	}
+	if before.ArchivedAt != nil {
+		return nil
+	}
+	if before.Debt > 0 {
+		return ErrHasDebt
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	res, err := tx.Exec(`
+		UPDATE clientes SET archived_at = ?
+		WHERE id = ? AND archived_at IS NULL AND deuda <= 0
+	`, time.Now().Format("2006-01-02 15:04"), id)
+	if err != nil {
+		return err
+	}
+
+	// Entre la lectura y el UPDATE pudo entrar un fiado
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return ErrHasDebt
+	}
+
+	after, err := clientByID(tx, int64(id))
+	if err != nil {
+		return err
+	}
+	return writeAudit(tx, user, models.AuditArchive, models.AuditClient, int64(id), before, after)
+}
+
+// Restore vuelve a activar un cliente archivado.
+func (s *ClientService) Restore(id int, user string) (after models.Client, err error) {
+	before, err := s.GetById(id)
+	if err != nil {
+		return models.Client{}, err
+	}
+	if before.ArchivedAt == nil {
+		return before, nil
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return models.Client{}, err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	if _, err = tx.Exec(`UPDATE clientes SET archived_at = NULL WHERE id = ?`, id); err != nil {
+		return models.Client{}, err
+	}
+
+	after, err = clientByID(tx, int64(id))
+	if err != nil {
+		return models.Client{}, err
+	}
+	return after, writeAudit(tx, user, models.AuditRestore, models.AuditClient, int64(id), before, after)
+}
+
+// clientes que deben, los que tienen fiados vencidos primero
+func (s *ClientService) GetIndebtedClient() ([]models.Client, error) {
+	rows, err := s.DB.Query(`
+        SELECT ` + clientColumns + `
+        FROM clientes WHERE deuda > 0
+        ORDER BY vencido DESC, deuda DESC
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.Client{}
+	for rows.Next() {
+		c, err := scanClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+
+	return list, nil
+}
+
+// Aging reparte el saldo de los fiados abiertos por antigüedad a la fecha
+// asOf (2006-01-02). El saldo se reconstruye a esa fecha: al pendiente de hoy
+// se le suman los abonos posteriores y lo que se perdonó en devoluciones
+// posteriores. El que tiene la venta sin pagar más vieja va primero.
+func (s *ClientService) Aging(asOf string) (models.AgingReport, error) {
+	if asOf == "" {
+		asOf = time.Now().Format("2006-01-02")
+	}
+	report := models.AgingReport{AsOf: asOf, Clients: []models.ClientAging{}}
+
+	rows, err := s.DB.Query(`
+		SELECT id, nombre, telefono, date, days, balance FROM (
+			SELECT c.id, c.nombre, COALESCE(c.telefono, '') AS telefono, cs.date,
+				CAST(julianday(?) - julianday(date(cs.date)) AS INTEGER) AS days,
+				cs.remaining_balance
+				+ COALESCE((
+					SELECT SUM(amount) FROM credit_payments
+					WHERE credit_sale_id = cs.id AND date(date) > ?
+				), 0)
+				+ COALESCE((
+					SELECT SUM(sr.amount - sr.refunded - sr.credited) FROM sale_returns sr
+					JOIN sales sa ON sa.id = sr.sale_id
+					WHERE sa.credit_sale_id = cs.id AND date(sr.date) > ?
+				), 0) AS balance
+			FROM credit_sales cs
+			JOIN clientes c ON c.id = cs.client_id
+			WHERE date(cs.date) <= ?
+		) WHERE balance > 0
+		ORDER BY id, date
+	`, asOf, asOf, asOf, asOf)
+	if err != nil {
+		return report, err
+	}
+	defer rows.Close()
+
+	byClient := map[int64]int{}
+	for rows.Next() {
+		var id int64
+		var name, phone, date string
+		var days int
+		var remaining models.Money
+		if err := rows.Scan(&id, &name, &phone, &date, &days, &remaining); err != nil {
+			return report, err
+		}
+
+		i, ok := byClient[id]
+		if !ok {
+			report.Clients = append(report.Clients, models.ClientAging{
+				ClientID:       id,
+				ClientName:     name,
+				Phone:          phone,
+				OldestSaleDate: date,
+			})
+			i = len(report.Clients) - 1
+			byClient[id] = i
+		}
+
+		report.Clients[i].OpenSales++
+		report.Clients[i].Buckets.Add(days, remaining)
+		report.Totals.Add(days, remaining)
+	}
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+
+	sort.SliceStable(report.Clients, func(i, j int) bool {
+		return report.Clients[i].OldestSaleDate < report.Clients[j].OldestSaleDate
+	})
+
+	return report, nil
+}
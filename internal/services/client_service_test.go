@@ -0,0 +1,86 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+func TestGetIndebtedClientOverdueFirst(t *testing.T) {
+	database := testDB(t)
+
+	// Beto debe más, pero lo de Ana ya se venció
+	mustExec(t, database, `INSERT INTO clientes (id, nombre, telefono, deuda) VALUES (1, 'Ana', '', 10000), (2, 'Beto', '', 50000), (3, 'Caro', '', 0)`)
+	mustExec(t, database, `
+		INSERT INTO credit_sales (client_id, total, remaining_balance, date, due_date) VALUES
+			(1, 10000, 10000, '2025-01-10 10:00', '2025-01-25'),
+			(2, 50000, 50000, date('now', 'localtime') || ' 10:00', date('now', 'localtime', '+15 days'))
+	`)
+
+	list, err := NewClientService(database).GetIndebtedClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("%d clientes con deuda, se esperaban 2", len(list))
+	}
+	if list[0].Name != "Ana" || list[1].Name != "Beto" {
+		t.Errorf("orden %s, %s; se esperaba Ana, Beto", list[0].Name, list[1].Name)
+	}
+	expectMoney(t, "vencido de Ana", list[0].OverdueAmount, 10000)
+}
+
+func TestAgingAsOfPastDate(t *testing.T) {
+	database := testDB(t)
+
+	mustExec(t, database, `INSERT INTO clientes (id, nombre, telefono, deuda) VALUES (1, 'Ana', '', 10000)`)
+	// Fiado 1: 100 del 10 de enero, pagado en febrero y marzo
+	// Fiado 2: 50 del 1 de marzo, sin abonos
+	// Fiado 3: 80 del 20 de enero; el 20 de febrero se devolvieron 30 sin reembolso
+	mustExec(t, database, `
+		INSERT INTO credit_sales (id, client_id, total, remaining_balance, date, due_date) VALUES
+			(1, 1, 10000, 0, '2025-01-10 10:00', '2025-02-10'),
+			(2, 1, 5000, 5000, '2025-03-01 10:00', '2025-04-01'),
+			(3, 1, 5000, 5000, '2025-01-20 10:00', '2025-02-20')
+	`)
+	mustExec(t, database, `
+		INSERT INTO credit_payments (credit_sale_id, amount, date) VALUES
+			(1, 4000, '2025-02-05 10:00'),
+			(1, 6000, '2025-03-05 10:00')
+	`)
+	mustExec(t, database, `INSERT INTO sales (id, client_id, total, cost, is_credit, credit_sale_id, date) VALUES (3, 1, 5000, 0, 1, 3, '2025-01-20 10:00')`)
+	mustExec(t, database, `
+		INSERT INTO sale_returns (sale_id, kind, amount, refunded, date) VALUES (3, 'devolucion', 3000, 0, '2025-02-20 10:00')
+	`)
+
+	clients := NewClientService(database)
+
+	tests := []struct {
+		asOf      string
+		openSales int
+		want      models.AgingBuckets
+	}{
+		// Antes del segundo abono y de la devolución; el fiado 2 todavía no existe
+		{"2025-02-15", 2, models.AgingBuckets{Days16to30: 8000, Days31to60: 6000, Total: 14000}},
+		// El fiado 1 aún debe el abono del 5 de marzo
+		{"2025-03-02", 3, models.AgingBuckets{Days0to15: 5000, Days31to60: 11000, Total: 16000}},
+		// Hoy solo quedan los saldos actuales
+		{"2025-04-30", 2, models.AgingBuckets{Days31to60: 5000, Over60: 5000, Total: 10000}},
+	}
+
+	for _, tt := range tests {
+		report, err := clients.Aging(tt.asOf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Clients) != 1 {
+			t.Fatalf("%s: %d clientes, se esperaba 1", tt.asOf, len(report.Clients))
+		}
+		if got := report.Clients[0].OpenSales; got != tt.openSales {
+			t.Errorf("%s: %d fiados abiertos, se esperaban %d", tt.asOf, got, tt.openSales)
+		}
+		if report.Totals != tt.want {
+			t.Errorf("%s: tramos %+v, se esperaban %+v", tt.asOf, report.Totals, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"strconv"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+// Statement arma el estado de cuenta del cliente con sus fiados, abonos y
+// devoluciones. Lo anterior a from entra en el saldo inicial; sin fechas
+// abarca toda la historia.
+func (s *ClientService) Statement(clientID int64, from, to string) (models.ClientStatement, error) {
+	st := models.ClientStatement{From: from, To: to, Lines: []models.StatementLine{}}
+
+	c, err := s.GetById(int(clientID))
+	if err != nil {
+		return st, err
+	}
+	st.Client = c
+
+	// Un fiado se muestra por lo que se vendió; lo devuelto va en su propia línea
+	rows, err := s.DB.Query(`
+		SELECT cs.id, cs.date, 'fiado', cs.total + COALESCE((
+				SELECT SUM(sr.amount) FROM sale_returns sr
+				JOIN sales sa ON sa.id = sr.sale_id
+				WHERE sa.credit_sale_id = cs.id
+			), 0), 0, '', 0
+		FROM credit_sales cs
+		WHERE cs.client_id = ?
+
+		UNION ALL
+
+		SELECT cp.credit_sale_id, cp.date, 'abono', 0, cp.amount, '', 1
+		FROM credit_payments cp
+		JOIN credit_sales cs ON cs.id = cp.credit_sale_id
+		WHERE cs.client_id = ?
+
+		UNION ALL
+
+		SELECT sa.credit_sale_id, sr.date, 'devolucion', 0, sr.amount - sr.refunded - sr.credited, sr.kind, 1
+		FROM sale_returns sr
+		JOIN sales sa ON sa.id = sr.sale_id
+		WHERE sa.client_id = ? AND sa.credit_sale_id IS NOT NULL
+
+		UNION ALL
+
+		-- Abonos viejos que no quedaron en credit_payments: la diferencia
+		-- contra remaining_balance va como ajuste para cuadrar con la deuda
+		SELECT id, date, 'ajuste', 0, diff, '', 1
+		FROM (
+			SELECT cs.id, cs.date,
+				cs.total - cs.remaining_balance
+				- COALESCE((SELECT SUM(amount) FROM credit_payments WHERE credit_sale_id = cs.id), 0)
+				+ COALESCE((
+					SELECT SUM(sr.refunded + sr.credited) FROM sale_returns sr
+					JOIN sales sa ON sa.id = sr.sale_id
+					WHERE sa.credit_sale_id = cs.id
+				), 0) AS diff
+			FROM credit_sales cs
+			WHERE cs.client_id = ?
+		)
+		WHERE diff <> 0
+
+		-- a igual fecha el fiado va antes que sus abonos
+		ORDER BY 2, 7, 1
+	`, clientID, clientID, clientID, clientID)
+	if err != nil {
+		return st, err
+	}
+	defer rows.Close()
+
+	var lines []models.StatementLine
+	for rows.Next() {
+		var l models.StatementLine
+		var returnKind string
+		var order int
+		if err := rows.Scan(&l.CreditSaleID, &l.Date, &l.Kind, &l.Charge, &l.Credit, &returnKind, &order); err != nil {
+			return st, err
+		}
+
+		id := strconv.FormatInt(l.CreditSaleID, 10)
+		switch l.Kind {
+		case models.StatementSale:
+			l.Description = "Fiado #" + id
+		case models.StatementPayment:
+			l.Description = "Abono a fiado #" + id
+		case models.StatementAdjustment:
+			l.Description = "Abonos sin detalle fiado #" + id
+		default:
+			l.Description = "Devolución fiado #" + id
+			if returnKind == "anulacion" {
+				l.Description = "Anulación fiado #" + id
+			}
+		}
+		lines = append(lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		return st, err
+	}
+
+	var balance models.Money
+	for _, l := range lines {
+		day := l.Date[:min(len(l.Date), 10)]
+		if to != "" && day > to {
+			break
+		}
+
+		balance += l.Charge - l.Credit
+		if from != "" && day < from {
+			st.OpeningBalance = balance
+			continue
+		}
+
+		if l.Kind == models.StatementSale {
+			l.Items, err = s.creditSaleItems(l.CreditSaleID)
+			if err != nil {
+				return st, err
+			}
+		}
+		l.Balance = balance
+		st.TotalCharges += l.Charge
+		st.TotalCredits += l.Credit
+		st.Lines = append(st.Lines, l)
+	}
+	st.ClosingBalance = balance
+
+	return st, nil
+}
+
+func (s *ClientService) creditSaleItems(creditSaleID int64) ([]models.StatementItem, error) {
+	rows, err := s.DB.Query(`
+		SELECT csi.product_id, COALESCE(p.nombre, ''), csi.quantity
+		FROM credit_sale_items csi
+		LEFT JOIN productos p ON p.id = csi.product_id
+		WHERE csi.credit_sale_id = ?
+		ORDER BY csi.id
+	`, creditSaleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.StatementItem{}
+	for rows.Next() {
+		var it models.StatementItem
+		if err := rows.Scan(&it.ProductID, &it.ProductName, &it.Quantity); err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+// Cuántos productos muestra el inicio
+const dashboardTopProducts = 5
+
+// Dashboard arma los indicadores del inicio. La semana empieza el lunes.
+func (s *ReportService) Dashboard() (models.Dashboard, error) {
+	now := time.Now()
+	today := now.Format("2006-01-02")
+	weekday := (int(now.Weekday()) + 6) % 7 // lunes = 0
+	weekStart := now.AddDate(0, 0, -weekday).Format("2006-01-02")
+	lastWeekStart := now.AddDate(0, 0, -weekday-7).Format("2006-01-02")
+	lastWeekToday := now.AddDate(0, 0, -7).Format("2006-01-02")
+
+	d := models.Dashboard{Date: today, TopProducts: []models.TopProduct{}}
+
+	var err error
+	if d.Today, err = s.salesSummary(today, today); err != nil {
+		return d, err
+	}
+	if d.ThisWeek, err = s.salesSummary(weekStart, today); err != nil {
+		return d, err
+	}
+	if d.LastWeek, err = s.salesSummary(lastWeekStart, lastWeekToday); err != nil {
+		return d, err
+	}
+	d.WeekChangePct = pct(d.ThisWeek.Total-d.LastWeek.Total, d.LastWeek.Total)
+
+	err = s.DB.QueryRow(`
+		SELECT
+			COALESCE(SUM(CASE WHEN date(fecha) = date(?) THEN monto END), 0),
+			COALESCE(SUM(monto), 0)
+		FROM movimientos
+		WHERE origen = ? AND tipo = ? AND date(fecha) BETWEEN date(?) AND date(?)
+	`, today, models.OriginPayment, models.MoveIngreso, weekStart, today).Scan(&d.PaymentsToday, &d.PaymentsWeek)
+	if err != nil {
+		return d, err
+	}
+
+	products, err := s.ProductSales(models.ReportFilter{From: weekStart, To: today, Limit: dashboardTopProducts})
+	if err != nil {
+		return d, err
+	}
+	for _, p := range products.Best {
+		if p.Units > 0 {
+			d.TopProducts = append(d.TopProducts, models.TopProduct{
+				ProductID: p.ProductID,
+				Name:      p.Name,
+				Units:     p.Units,
+				Revenue:   p.Revenue,
+			})
+		}
+	}
+
+	if d.LowStock, err = s.lowStock(); err != nil {
+		return d, err
+	}
+
+	err = s.DB.QueryRow(`
+		SELECT
+			(SELECT COALESCE(SUM(saldo), 0) FROM accounts),
+			(SELECT COALESCE(SUM(deuda), 0) FROM clientes),
+			(SELECT COALESCE(SUM(remaining_balance), 0) FROM credit_sales
+			 WHERE remaining_balance > 0 AND due_date < date(?)),
+			(SELECT COUNT(*) FROM clientes WHERE deuda > 0)
+	`, today).Scan(&d.Balance, &d.AmountOwed, &d.OverdueTotal, &d.Debtors)
+	return d, err
+}
+
+func (s *ReportService) salesSummary(from, to string) (models.SalesSummary, error) {
+	sum := models.SalesSummary{From: from, To: to}
+	err := s.DB.QueryRow(`
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(net), 0),
+			COALESCE(SUM(CASE WHEN is_credit = 0 THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN is_credit = 0 THEN net END), 0),
+			COALESCE(SUM(CASE WHEN is_credit = 1 THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN is_credit = 1 THEN net END), 0)
+		FROM (
+			SELECT s.is_credit, SUM((si.quantity - si.returned_quantity) * si.unit_price) AS net
+			FROM sales s
+			JOIN sale_items si ON si.sale_id = s.id
+			WHERE s.voided_at IS NULL AND date(s.date) BETWEEN date(?) AND date(?)
+			GROUP BY s.id
+		)
+	`, from, to).Scan(&sum.Count, &sum.Total, &sum.CashCount, &sum.CashTotal, &sum.CreditCount, &sum.CreditTotal)
+	return sum, err
+}
+
+func (s *ReportService) lowStock() ([]models.LowStockInsumo, error) {
+	rows, err := s.DB.Query(`
+		SELECT id, nombre, unidad_medida, stock_actual, minimo_sugerido
+		FROM insumos
+		WHERE archived_at IS NULL AND stock_actual < minimo_sugerido
+		ORDER BY stock_actual / NULLIF(minimo_sugerido, 0) ASC, nombre
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.LowStockInsumo{}
+	for rows.Next() {
+		var i models.LowStockInsumo
+		if err := rows.Scan(&i.ID, &i.Name, &i.Um, &i.Stock, &i.MinStock); err != nil {
+			return nil, err
+		}
+		list = append(list, i)
+	}
+	return list, rows.Err()
+}
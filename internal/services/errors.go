@@ -1,8 +1,22 @@
-package services
-
-import "errors"
-
-var (
-	ErrNotFound     = errors.New("no encontrado")
-	ErrInvalidInput = errors.New("datos inválidos")
-)
+package services
+
+import "errors"
+
+var (
+	ErrNotFound     = errors.New("no encontrado")
+	ErrInvalidInput = errors.New("datos inválidos")
+
+	ErrAccountNotFound  = errors.New("cuenta no encontrada")
+	ErrSupplierNotFound = errors.New("proveedor no encontrado")
+	ErrInUse            = errors.New("el registro tiene movimientos asociados")
+	ErrHasDebt          = errors.New("el cliente tiene deuda pendiente")
+
+	ErrSaleVoided     = errors.New("la venta ya fue anulada")
+	ErrRefundRequired = errors.New("la venta fiada ya tiene abonos, hay que indicar el reembolso")
+
+	ErrOverpayment   = errors.New("el abono supera lo que se debe; usa keep_credit para dejar el sobrante como saldo a favor")
+	ErrCreditLimit   = errors.New("el fiado supera el límite de crédito del cliente")
+	ErrCreditOverdue = errors.New("el cliente tiene fiados vencidos")
+
+	ErrAlreadyResolved = errors.New("el gasto programado ya fue registrado o descartado")
+)
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+// Tamaño máximo del soporte de un gasto (foto o PDF)
+const maxReceiptSize = 5 << 20
+
+type ExpenseService struct {
+	DB *sql.DB
+}
+
+func NewExpenseService(db *sql.DB) *ExpenseService {
+	return &ExpenseService{DB: db}
+}
+
+func (s *ExpenseService) GetCategories() ([]models.ExpenseCategory, error) {
+	rows, err := s.DB.Query(`SELECT id, name FROM expense_categories ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.ExpenseCategory{}
+	for rows.Next() {
+		var c models.ExpenseCategory
+		if err := rows.Scan(&c.ID, &c.Name); err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+// CreateCategory devuelve ErrInUse si ya hay una categoría con ese nombre.
+func (s *ExpenseService) CreateCategory(c *models.ExpenseCategory) error {
+	c.Name = strings.ToLower(strings.TrimSpace(c.Name))
+	if c.Name == "" {
+		return ErrInvalidInput
+	}
+
+	var n int
+	err := s.DB.QueryRow(`SELECT COUNT(*) FROM expense_categories WHERE name = ?`, c.Name).Scan(&n)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrInUse
+	}
+
+	res, err := s.DB.Exec(`INSERT INTO expense_categories (name) VALUES (?)`, c.Name)
+	if err != nil {
+		return err
+	}
+	c.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// Create registra el gasto y su egreso en la cuenta. El movimiento queda con
+// la categoría para poder separarlo de surtidos y ajustes.
+func (s *ExpenseService) Create(e *models.Expense, user string) (err error) {
+	if e.Amount <= 0 || e.CategoryID <= 0 || len(e.Receipt) > maxReceiptSize {
+		return ErrInvalidInput
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	return insertExpense(tx, e, user)
+}
+
+// insertExpense guarda el gasto dentro de tx; también lo usan los gastos recurrentes.
+func insertExpense(tx *sql.Tx, e *models.Expense, user string) error {
+	if e.AccountID == 0 {
+		e.AccountID = models.CashAccountID
+	}
+	e.Notes = strings.TrimSpace(e.Notes)
+	e.Date = time.Now().Format("2006-01-02 15:04")
+	e.CreatedBy = user
+
+	err := tx.QueryRow(`SELECT name FROM expense_categories WHERE id = ?`, e.CategoryID).Scan(&e.CategoryName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	desc := "Gasto: " + e.CategoryName
+	if e.Notes != "" {
+		desc += " - " + e.Notes
+	}
+	err = recordMove(tx, moveRecord{
+		Description: desc,
+		Type:        models.MoveEgreso,
+		Origin:      models.OriginExpense,
+		Amount:      e.Amount,
+		Date:        e.Date,
+		AccountID:   e.AccountID,
+		CategoryID:  e.CategoryID,
+	})
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO expenses (category_id, account_id, amount, notes, receipt, date, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, e.CategoryID, e.AccountID, e.Amount, e.Notes, e.Receipt, e.Date, e.CreatedBy)
+	if err != nil {
+		return err
+	}
+	e.ID, _ = res.LastInsertId()
+	e.HasReceipt = len(e.Receipt) > 0
+
+	receipt := e.Receipt
+	e.Receipt = nil
+	err = writeAudit(tx, user, models.AuditCreate, models.AuditExpense, e.ID, nil, e)
+	e.Receipt = receipt
+	return err
+}
+
+const expenseColumns = `
+	e.id, e.category_id, c.name, e.account_id, e.amount, e.notes,
+	e.receipt IS NOT NULL AND length(e.receipt) > 0, e.date, e.created_by`
+
+func scanExpense(row rowScanner) (models.Expense, error) {
+	var e models.Expense
+	err := row.Scan(&e.ID, &e.CategoryID, &e.CategoryName, &e.AccountID, &e.Amount, &e.Notes,
+		&e.HasReceipt, &e.Date, &e.CreatedBy)
+	return e, err
+}
+
+func (s *ExpenseService) GetAll(f models.ExpenseFilter) (models.ExpenseList, error) {
+	list := models.ExpenseList{Expenses: []models.Expense{}}
+
+	where, args := expenseWhere(f)
+	rows, err := s.DB.Query(`
+		SELECT `+expenseColumns+`
+		FROM expenses e
+		JOIN expense_categories c ON c.id = e.category_id
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY e.date DESC, e.id DESC
+	`, args...)
+	if err != nil {
+		return list, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanExpense(rows)
+		if err != nil {
+			return list, err
+		}
+		list.Total += e.Amount
+		list.Expenses = append(list.Expenses, e)
+	}
+	return list, rows.Err()
+}
+
+func (s *ExpenseService) GetById(id int64) (models.Expense, error) {
+	e, err := scanExpense(s.DB.QueryRow(`
+		SELECT `+expenseColumns+`
+		FROM expenses e
+		JOIN expense_categories c ON c.id = e.category_id
+		WHERE e.id = ?
+	`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return e, ErrNotFound
+	}
+	return e, err
+}
+
+// GetReceipt devuelve el soporte del gasto, o ErrNotFound si no tiene.
+func (s *ExpenseService) GetReceipt(id int64) ([]byte, error) {
+	var data []byte
+	err := s.DB.QueryRow(`SELECT receipt FROM expenses WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && len(data) == 0) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// MonthlyTotals suma los gastos por mes y categoría.
+func (s *ExpenseService) MonthlyTotals(f models.ExpenseFilter) ([]models.ExpenseTotal, error) {
+	where, args := expenseWhere(f)
+	rows, err := s.DB.Query(`
+		SELECT substr(e.date, 1, 7), e.category_id, c.name, COUNT(*), SUM(e.amount)
+		FROM expenses e
+		JOIN expense_categories c ON c.id = e.category_id
+		WHERE `+strings.Join(where, " AND ")+`
+		GROUP BY 1, e.category_id
+		ORDER BY 1 DESC, SUM(e.amount) DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.ExpenseTotal{}
+	for rows.Next() {
+		var t models.ExpenseTotal
+		if err := rows.Scan(&t.Period, &t.CategoryID, &t.CategoryName, &t.Count, &t.Total); err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+func expenseWhere(f models.ExpenseFilter) ([]string, []any) {
+	where := []string{"1 = 1"}
+	args := []any{}
+
+	if f.From != "" {
+		where = append(where, "date(e.date) >= date(?)")
+		args = append(args, f.From)
+	}
+	if f.To != "" {
+		where = append(where, "date(e.date) <= date(?)")
+		args = append(args, f.To)
+	}
+	if f.CategoryID > 0 {
+		where = append(where, "e.category_id = ?")
+		args = append(args, f.CategoryID)
+	}
+	if f.AccountID > 0 {
+		where = append(where, "e.account_id = ?")
+		args = append(args, f.AccountID)
+	}
+	return where, args
+}
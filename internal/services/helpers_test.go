@@ -0,0 +1,65 @@
+package services
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/db"
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+const testUser = "prueba"
+
+// testDB abre una base vacía en un directorio temporal con todas las
+// migraciones aplicadas.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+	database, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	if _, err := db.MigrateUp(database, 0); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+// mustExec ejecuta sentencias de preparación que no pueden fallar.
+func mustExec(t *testing.T, database *sql.DB, query string, args ...any) {
+	t.Helper()
+	if _, err := database.Exec(query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+func queryMoney(t *testing.T, database *sql.DB, query string, args ...any) models.Money {
+	t.Helper()
+	var m models.Money
+	if err := database.QueryRow(query, args...).Scan(&m); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return m
+}
+
+func queryFloat(t *testing.T, database *sql.DB, query string, args ...any) float64 {
+	t.Helper()
+	var f float64
+	if err := database.QueryRow(query, args...).Scan(&f); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return f
+}
+
+func expectMoney(t *testing.T, what string, got, want models.Money) {
+	t.Helper()
+	if got != want {
+		t.Errorf("%s = %s, se esperaba %s", what, got, want)
+	}
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+// Los lotes guardan cantidades REAL; por debajo de esto se consideran agotados.
+const lotEpsilon = 0.000001
+
+// Tipos de lote en supplies
+const (
+	lotPurchase = "compra"
+	lotOpening  = "inicial"
+	lotAdjust   = "ajuste"
+	lotReturn   = "devolucion"
+)
+
+type recipeItem struct {
+	insumoID int64
+	quantity float64 // por unidad de producto
+}
+
+// productRecipe lee la receta completa antes de tocar stock, para no tener
+// filas abiertas mientras se actualiza dentro de la misma transacción.
+func productRecipe(tx *sql.Tx, productID int64) ([]recipeItem, error) {
+	rows, err := tx.Query(`
+		SELECT insumo_id, cantidad_insumo
+		FROM producto_insumos
+		WHERE producto_id = ?
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []recipeItem{}
+	for rows.Next() {
+		var ri recipeItem
+		if err := rows.Scan(&ri.insumoID, &ri.quantity); err != nil {
+			return nil, err
+		}
+		list = append(list, ri)
+	}
+	return list, rows.Err()
+}
+
+// lotRecord es un lote a guardar en supplies. La cantidad entera queda
+// disponible (remaining) al crearlo.
+type lotRecord struct {
+	InsumoID    int64
+	Kind        string
+	Quantity    float64
+	UnitCost    models.Money
+	Total       models.Money
+	AccountID   int64
+	SupplierID  int64
+	OrderItemID int64
+	Date        string
+}
+
+func addLot(tx *sql.Tx, l lotRecord) (int64, error) {
+	res, err := tx.Exec(`
+		INSERT INTO supplies (insumo_id, kind, quantity, remaining, unit_cost, total, account_id, supplier_id, order_item_id, date)
+		VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, 0), NULLIF(?, 0), NULLIF(?, 0), ?)
+	`, l.InsumoID, l.Kind, l.Quantity, l.Quantity, l.UnitCost, l.Total, l.AccountID, l.SupplierID, l.OrderItemID, l.Date)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// consumeInsumo descuenta qty del stock y de los lotes, el más viejo primero,
+// y devuelve lo que costaron los lotes usados. Sin stock suficiente devuelve
+// ErrInvalidInput.
+func consumeInsumo(tx *sql.Tx, insumoID int64, qty float64) (models.Money, error) {
+	res, err := tx.Exec(`
+		UPDATE insumos
+		SET stock_actual = stock_actual - ?
+		WHERE id = ? AND stock_actual >= ?
+	`, qty, insumoID, qty)
+	if err != nil {
+		return 0, err
+	}
+	ra, _ := res.RowsAffected()
+	if ra == 0 {
+		return 0, ErrInvalidInput
+	}
+
+	cost, err := consumeLots(tx, insumoID, qty)
+	if err != nil {
+		return 0, err
+	}
+	return cost, refreshFIFOCost(tx, insumoID, "")
+}
+
+func consumeLots(tx *sql.Tx, insumoID int64, qty float64) (models.Money, error) {
+	type lot struct {
+		id        int64
+		remaining float64
+		unitCost  models.Money
+	}
+
+	rows, err := tx.Query(`
+		SELECT id, remaining, unit_cost FROM supplies
+		WHERE insumo_id = ? AND remaining > ?
+		ORDER BY date, id
+	`, insumoID, lotEpsilon)
+	if err != nil {
+		return 0, err
+	}
+	lots := []lot{}
+	for rows.Next() {
+		var l lot
+		if err := rows.Scan(&l.id, &l.remaining, &l.unitCost); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		lots = append(lots, l)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, err
+	}
+	rows.Close()
+
+	var cost models.Money
+	for _, l := range lots {
+		if qty <= lotEpsilon {
+			break
+		}
+		take := min(l.remaining, qty)
+		if _, err := tx.Exec(`UPDATE supplies SET remaining = remaining - ? WHERE id = ?`, take, l.id); err != nil {
+			return 0, err
+		}
+		cost += l.unitCost.MulQty(take)
+		qty -= take
+	}
+
+	// Si los lotes no alcanzan (stock editado a mano antes de existir lotes)
+	// se agotan y lo que falta se cuesta al precio actual
+	if qty > lotEpsilon {
+		var price models.Money
+		err := tx.QueryRow(`SELECT precio_unitario FROM insumos WHERE id = ?`, insumoID).Scan(&price)
+		if err != nil {
+			return 0, err
+		}
+		cost += price.MulQty(qty)
+	}
+	return cost, nil
+}
+
+// restoreInsumo vuelve a sumar qty al stock (devoluciones) como un lote
+// nuevo al precio actual.
+func restoreInsumo(tx *sql.Tx, insumoID int64, qty float64, date string) error {
+	if qty <= 0 {
+		return nil
+	}
+
+	var price models.Money
+	err := tx.QueryRow(`SELECT precio_unitario FROM insumos WHERE id = ?`, insumoID).Scan(&price)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE insumos SET stock_actual = stock_actual + ? WHERE id = ?`, qty, insumoID); err != nil {
+		return err
+	}
+	_, err = addLot(tx, lotRecord{
+		InsumoID: insumoID,
+		Kind:     lotReturn,
+		Quantity: qty,
+		UnitCost: price,
+		Total:    price.MulQty(qty),
+		Date:     date,
+	})
+	if err != nil {
+		return err
+	}
+	return refreshFIFOCost(tx, insumoID, date)
+}
+
+// refreshFIFOCost pone como precio del insumo el costo del lote más viejo con
+// saldo, solo si el insumo usa FIFO.
+func refreshFIFOCost(tx *sql.Tx, insumoID int64, date string) error {
+	var method string
+	var price models.Money
+	err := tx.QueryRow(`
+		SELECT metodo_costo, precio_unitario FROM insumos WHERE id = ?
+	`, insumoID).Scan(&method, &price)
+	if err != nil {
+		return err
+	}
+	if method != models.CostFIFO {
+		return nil
+	}
+
+	oldest, ok, err := oldestLotCost(tx, insumoID)
+	if err != nil || !ok {
+		return err
+	}
+	return setInsumoPrice(tx, insumoID, price, oldest, models.CostFIFO, 0, date)
+}
+
+func oldestLotCost(tx *sql.Tx, insumoID int64) (models.Money, bool, error) {
+	var cost models.Money
+	err := tx.QueryRow(`
+		SELECT unit_cost FROM supplies
+		WHERE insumo_id = ? AND remaining > ?
+		ORDER BY date, id
+		LIMIT 1
+	`, insumoID, lotEpsilon).Scan(&cost)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return cost, err == nil, err
+}
+
+// setInsumoPrice cambia precio_unitario y deja el cambio en el historial. Los
+// triggers de costo se encargan de productos.costo_total.
+func setInsumoPrice(tx *sql.Tx, insumoID int64, prev, price models.Money, method string, supplyID int64, date string) error {
+	if price == prev {
+		return nil
+	}
+	if date == "" {
+		date = time.Now().Format("2006-01-02 15:04")
+	}
+
+	_, err := tx.Exec(`UPDATE insumos SET precio_unitario = ? WHERE id = ?`, price, insumoID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO insumo_price_history (insumo_id, previous_price, price, method, supply_id, date)
+		VALUES (?, ?, ?, ?, NULLIF(?, 0), ?)
+	`, insumoID, prev, price, method, supplyID, date)
+	return err
+}
@@ -1,104 +1,316 @@
-package services
-
-import (
-	"database/sql"
-	"errors"
-
-	"github.com/mgdavidd/server-Eme-Mar/internal/models"
-)
-
-type InsumoService struct {
-	DB *sql.DB
-}
-
-func NewInsumoService(db *sql.DB) *InsumoService {
-	return &InsumoService{DB: db}
-}
-
-func (s *InsumoService) GetAll() ([]models.Insumo, error) {
-	rows, err := s.DB.Query(`
-        SELECT id, nombre, unidad_medida, stock_actual, minimo_sugerido, precio_unitario 
-        FROM insumos
-    `)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	insumos := []models.Insumo{}
-	for rows.Next() {
-		var i models.Insumo
-		rows.Scan(&i.ID, &i.Name, &i.Um, &i.Stock, &i.MinStock, &i.UnitPrice)
-		insumos = append(insumos, i)
-	}
-
-	return insumos, nil
-}
-
-func (s *InsumoService) GetById(id int) (models.Insumo, error) {
-	var i models.Insumo
-
-	err := s.DB.QueryRow(`
-        SELECT id, nombre, unidad_medida, stock_actual, minimo_sugerido, precio_unitario
-        FROM insumos WHERE id = ?
-    `, id).Scan(&i.ID, &i.Name, &i.Um, &i.Stock, &i.MinStock, &i.UnitPrice)
-
-	if errors.Is(err, sql.ErrNoRows) {
-		return models.Insumo{}, ErrNotFound
-	}
-	if err != nil {
-		return models.Insumo{}, err
-	}
-	return i, nil
-}
-
-func (s *InsumoService) Create(i *models.Insumo) error {
-	stmt, err := s.DB.Prepare(`
-        INSERT INTO insumos (nombre, unidad_medida, stock_actual, minimo_sugerido, precio_unitario)
-        VALUES (?, ?, ?, ?, ?)
-    `)
-	if err != nil {
-		return err
-	}
-
-	res, err := stmt.Exec(i.Name, i.Um, i.Stock, i.MinStock, i.UnitPrice)
-	if err != nil {
-		return err
-	}
-
-	id, _ := res.LastInsertId()
-	i.ID = id
-	return nil
-}
-
-func (s *InsumoService) Update(i *models.Insumo) error {
-	res, err := s.DB.Exec(`
-		UPDATE insumos
-		SET nombre = ?, unidad_medida = ?, stock_actual = ?, minimo_sugerido = ?, precio_unitario = ?
-		WHERE id = ?
-	`, i.Name, i.Um, i.Stock, i.MinStock, i.UnitPrice, i.ID)
-
-	if err != nil {
-		return err
-	}
-
-	rows, _ := res.RowsAffected()
-	if rows == 0 {
-		return ErrNotFound
-	}
-	return nil
-}
-
-func (s *InsumoService) Delete(id int) error {
-	res, err := s.DB.Exec(`DELETE FROM insumos WHERE id = ?`, id)
-	if err != nil {
-		return err
-	}
-
-	rows, _ := res.RowsAffected()
-	if rows == 0 {
-		return ErrNotFound
-	}
-
-	return nil
-}
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+type InsumoService struct {
+	DB *sql.DB
+}
+
+func NewInsumoService(db *sql.DB) *InsumoService {
+	return &InsumoService{DB: db}
+}
+
+// GetAll lista los insumos activos, o solo los archivados si archived es true.
+func (s *InsumoService) GetAll(archived bool) ([]models.Insumo, error) {
+	rows, err := s.DB.Query(`
+        SELECT id, nombre, unidad_medida, stock_actual, minimo_sugerido, precio_unitario, metodo_costo, archived_at
+        FROM insumos WHERE (archived_at IS NOT NULL) = ?
+    `, archived)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	insumos := []models.Insumo{}
+	for rows.Next() {
+		var i models.Insumo
+		rows.Scan(&i.ID, &i.Name, &i.Um, &i.Stock, &i.MinStock, &i.UnitPrice, &i.CostMethod, &i.ArchivedAt)
+		insumos = append(insumos, i)
+	}
+
+	return insumos, nil
+}
+
+func (s *InsumoService) GetById(id int) (models.Insumo, error) {
+	return insumoByID(s.DB, id)
+}
+
+// insumoByID lee el insumo con q, que puede ser la transacción en curso.
+func insumoByID(q Queryer, id int) (models.Insumo, error) {
+	var i models.Insumo
+
+	err := q.QueryRow(`
+        SELECT id, nombre, unidad_medida, stock_actual, minimo_sugerido, precio_unitario, metodo_costo, archived_at
+        FROM insumos WHERE id = ?
+    `, id).Scan(&i.ID, &i.Name, &i.Um, &i.Stock, &i.MinStock, &i.UnitPrice, &i.CostMethod, &i.ArchivedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Insumo{}, ErrNotFound
+	}
+	if err != nil {
+		return models.Insumo{}, err
+	}
+	return i, nil
+}
+
+func (s *InsumoService) Create(i *models.Insumo, user string) (err error) {
+	if i.CostMethod == "" {
+		i.CostMethod = models.CostAverage
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	res, err := tx.Exec(`
+        INSERT INTO insumos (nombre, unidad_medida, stock_actual, minimo_sugerido, precio_unitario, metodo_costo)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `, i.Name, i.Um, i.Stock, i.MinStock, i.UnitPrice, i.CostMethod)
+	if err != nil {
+		return err
+	}
+
+	id, _ := res.LastInsertId()
+	i.ID = id
+
+	if i.Stock > 0 {
+		_, err = addLot(tx, lotRecord{
+			InsumoID: i.ID,
+			Kind:     lotOpening,
+			Quantity: i.Stock,
+			UnitCost: i.UnitPrice,
+			Total:    i.UnitPrice.MulQty(i.Stock),
+			Date:     time.Now().Format("2006-01-02 15:04"),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeAudit(tx, user, models.AuditCreate, models.AuditInsumo, i.ID, nil, i)
+}
+
+// Update también acepta stock y precio a mano: el precio queda en el historial
+// como "manual" y la diferencia de stock entra o sale de los lotes.
+func (s *InsumoService) Update(i *models.Insumo, user string) (err error) {
+	now := time.Now().Format("2006-01-02 15:04")
+
+	before, err := s.GetById(int(i.ID))
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	var prevStock float64
+	var prevPrice models.Money
+	var prevMethod string
+	err = tx.QueryRow(`
+		SELECT stock_actual, precio_unitario, metodo_costo FROM insumos WHERE id = ?
+	`, i.ID).Scan(&prevStock, &prevPrice, &prevMethod)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	// Sin cost_method se conserva el que tenía
+	if i.CostMethod == "" {
+		i.CostMethod = prevMethod
+	}
+
+	_, err = tx.Exec(`
+		UPDATE insumos
+		SET nombre = ?, unidad_medida = ?, stock_actual = ?, minimo_sugerido = ?, metodo_costo = ?
+		WHERE id = ?
+	`, i.Name, i.Um, i.Stock, i.MinStock, i.CostMethod, i.ID)
+	if err != nil {
+		return err
+	}
+
+	if err = setInsumoPrice(tx, i.ID, prevPrice, i.UnitPrice, "manual", 0, now); err != nil {
+		return err
+	}
+
+	switch diff := i.Stock - prevStock; {
+	case diff > lotEpsilon:
+		_, err = addLot(tx, lotRecord{
+			InsumoID: i.ID,
+			Kind:     lotAdjust,
+			Quantity: diff,
+			UnitCost: i.UnitPrice,
+			Total:    i.UnitPrice.MulQty(diff),
+			Date:     now,
+		})
+	case diff < -lotEpsilon:
+		_, err = consumeLots(tx, i.ID, -diff)
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeAudit(tx, user, models.AuditUpdate, models.AuditInsumo, i.ID, before, i)
+}
+
+// GetLots devuelve los surtidos del insumo, el más reciente primero.
+func (s *InsumoService) GetLots(id int) ([]models.SupplyLot, error) {
+	if _, err := s.GetById(id); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT id, insumo_id, kind, quantity, remaining, unit_cost, total, account_id, supplier_id, date
+		FROM supplies
+		WHERE insumo_id = ?
+		ORDER BY date DESC, id DESC
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.SupplyLot{}
+	for rows.Next() {
+		var l models.SupplyLot
+		var accountID, supplierID sql.NullInt64
+		err := rows.Scan(&l.ID, &l.InsumoID, &l.Kind, &l.Quantity, &l.Remaining, &l.UnitCost, &l.Total, &accountID, &supplierID, &l.Date)
+		if err != nil {
+			return nil, err
+		}
+		if accountID.Valid {
+			l.AccountID = &accountID.Int64
+		}
+		if supplierID.Valid {
+			l.SupplierID = &supplierID.Int64
+		}
+		list = append(list, l)
+	}
+
+	return list, rows.Err()
+}
+
+func (s *InsumoService) GetPriceHistory(id int) ([]models.InsumoPrice, error) {
+	if _, err := s.GetById(id); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT id, insumo_id, previous_price, price, method, supply_id, date
+		FROM insumo_price_history
+		WHERE insumo_id = ?
+		ORDER BY date DESC, id DESC
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.InsumoPrice{}
+	for rows.Next() {
+		var p models.InsumoPrice
+		var supplyID sql.NullInt64
+		if err := rows.Scan(&p.ID, &p.InsumoID, &p.PreviousPrice, &p.Price, &p.Method, &supplyID, &p.Date); err != nil {
+			return nil, err
+		}
+		if supplyID.Valid {
+			p.SupplyID = &supplyID.Int64
+		}
+		list = append(list, p)
+	}
+
+	return list, rows.Err()
+}
+
+// Delete archiva el insumo. Las recetas que lo usan no se tocan, así que el
+// costo de esos productos no cambia; solo deja de aceptar surtidos y de
+// aparecer para recetas nuevas.
+func (s *InsumoService) Delete(id int, user string) error {
+	return s.setArchived(id, true, user)
+}
+
+// Restore vuelve a activar un insumo archivado.
+func (s *InsumoService) Restore(id int, user string) error {
+	return s.setArchived(id, false, user)
+}
+
+func (s *InsumoService) setArchived(id int, archived bool, user string) (err error) {
+	before, err := s.GetById(id)
+	if err != nil {
+		return err
+	}
+	if (before.ArchivedAt != nil) == archived {
+		return nil
+	}
+
+	var at any
+	action := models.AuditRestore
+	if archived {
+		at = time.Now().Format("2006-01-02 15:04")
+		action = models.AuditArchive
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	if _, err = tx.Exec(`UPDATE insumos SET archived_at = ? WHERE id = ?`, at, id); err != nil {
+		return err
+	}
+
+	after, err := insumoByID(tx, id)
+	if err != nil {
+		return err
+	}
+	return writeAudit(tx, user, action, models.AuditInsumo, int64(id), before, after)
+}
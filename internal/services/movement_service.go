@@ -1,734 +1,1070 @@
-package services
-
-import (
-	"database/sql"
-	"errors"
-	"strconv"
-	"strings"
-	"time"
-
-	"github.com/mgdavidd/server-Eme-Mar/internal/models"
-)
-
-type MovementService struct {
-	DB *sql.DB
-}
-
-func NewMoveService(db *sql.DB) *MovementService {
-	return &MovementService{DB: db}
-}
-
-func (s *MovementService) GetAll() ([]models.Move, error) {
-	rows, err := s.DB.Query(`
-        SELECT id, descripcion, tipo, monto, fecha 
-        FROM movimientos
-		WHERE date(fecha) >= date('now', '-30 days')
-        ORDER BY fecha DESC
-    `)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	moves := []models.Move{}
-	for rows.Next() {
-		var i models.Move
-		if err := rows.Scan(&i.ID, &i.Description, &i.Type, &i.Amount, &i.Date); err != nil {
-			return nil, err
-		}
-		moves = append(moves, i)
-	}
-
-	return moves, nil
-}
-
-func (s *MovementService) GetMovesByClient(clientID int) ([]models.Move, error) {
-	rows, err := s.DB.Query(`
-        SELECT id, descripcion, tipo, monto, fecha, cliente_id
-        FROM movimientos
-        WHERE cliente_id = ?
-		  AND date(fecha) >= date('now', '-30 days')
-        ORDER BY fecha DESC
-    `, clientID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	moves := []models.Move{}
-
-	for rows.Next() {
-		var m models.Move
-		var clientIDNullable sql.NullInt64
-
-		err := rows.Scan(
-			&m.ID,
-			&m.Description,
-			&m.Type,
-			&m.Amount,
-			&m.Date,
-			&clientIDNullable,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		if clientIDNullable.Valid {
-			clientIDValue := clientIDNullable.Int64
-			m.ClientID = &clientIDValue
-		} else {
-			m.ClientID = nil
-		}
-
-		moves = append(moves, m)
-	}
-
-	return moves, nil
-}
-
-func (s *MovementService) GetRecent() ([]models.Move, error) {
-	rows, err := s.DB.Query(`
-		SELECT id, descripcion, tipo, monto, fecha
-		FROM movimientos
-		ORDER BY fecha DESC
-		LIMIT 5
-	`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	moves := []models.Move{}
-	for rows.Next() {
-		var i models.Move
-		if err := rows.Scan(&i.ID, &i.Description, &i.Type, &i.Amount, &i.Date); err != nil {
-			return nil, err
-		}
-		moves = append(moves, i)
-	}
-
-	return moves, nil
-}
-
-func (s *MovementService) GetBalance() (models.Account, error) {
-	var b models.Account
-	err := s.DB.QueryRow(`
-        SELECT
-            (SELECT saldo FROM caja WHERE id = 1),
-            (SELECT COALESCE(SUM(deuda), 0) FROM clientes)
-    `).Scan(&b.Balance, &b.AmountOwed)
-	if err != nil {
-		return models.Account{}, err
-	}
-	return b, nil
-}
-
-type Queryer interface {
-	QueryRow(query string, args ...any) *sql.Row
-}
-
-func buildSaleDescription(items []models.SaleItem, db Queryer) (string, error) {
-
-	var sb strings.Builder
-
-	for _, item := range items {
-		var name string
-		err := db.QueryRow(`
-			SELECT nombre 
-			FROM productos 
-			WHERE id = ?
-		`, item.ProductID).Scan(&name)
-
-		if errors.Is(err, sql.ErrNoRows) {
-			return "", ErrNotFound
-		}
-		if err != nil {
-			return "", err
-		}
-
-		sb.WriteString("- ")
-		sb.WriteString(name)
-		sb.WriteString(" x ")
-		sb.WriteString(strconv.FormatInt(item.Quantity, 10))
-		sb.WriteString("\n")
-	}
-
-	return sb.String(), nil
-}
-
-func (s *MovementService) Supply(supply models.Supply) (err error) {
-	if supply.Amount <= 0 {
-		return ErrInvalidInput
-	}
-
-	supply.Date = time.Now().Format("2006-01-02 15:04")
-
-	tx, err := s.DB.Begin()
-	if err != nil {
-		return err
-	}
-
-	defer func() {
-		if p := recover(); p != nil {
-			_ = tx.Rollback()
-			panic(p)
-		} else if err != nil {
-			_ = tx.Rollback()
-		} else {
-			if cerr := tx.Commit(); cerr != nil {
-				err = cerr
-			}
-		}
-	}()
-
-	var actualStock float64
-	var nameInsumo string
-	var unitPrice float64
-
-	err = tx.QueryRow(`
-        SELECT stock_actual, nombre, precio_unitario 
-        FROM insumos 
-        WHERE id = ?
-    `, supply.IdInsumo).Scan(&actualStock, &nameInsumo, &unitPrice)
-
-	if errors.Is(err, sql.ErrNoRows) {
-		return ErrNotFound
-	}
-	if err != nil {
-		return err
-	}
-
-	newStock := actualStock + supply.Amount
-
-	_, err = tx.Exec(`
-        UPDATE insumos
-        SET stock_actual = ?
-        WHERE id = ?
-    `, newStock, supply.IdInsumo)
-	if err != nil {
-		return err
-	}
-
-	supply.TotalAmount = unitPrice * supply.Amount
-
-	description := "Surtido de insumo: " +
-		strings.ToTitle(nameInsumo) +
-		" X " +
-		strconv.FormatFloat(supply.Amount, 'f', -1, 64)
-
-	_, err = tx.Exec(`
-        INSERT INTO movimientos (descripcion, tipo, monto, fecha)
-        VALUES (?, 'egreso', ?, ?)
-    `, description, supply.TotalAmount, supply.Date)
-	if err != nil {
-		return err
-	}
-
-	_, err = tx.Exec(`
-		UPDATE caja
-		SET saldo = saldo - ?
-		WHERE id = 1
-	`, supply.TotalAmount)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (s *MovementService) Sell(sale models.Sale) (err error) {
-	sale.Date = time.Now().Format("2006-01-02 15:04")
-
-	tx, err := s.DB.Begin()
-	if err != nil {
-		return err
-	}
-
-	defer func() {
-		if p := recover(); p != nil {
-			_ = tx.Rollback()
-			panic(p)
-		} else if err != nil {
-			_ = tx.Rollback()
-		} else {
-			if cerr := tx.Commit(); cerr != nil {
-				err = cerr
-			}
-		}
-	}()
-
-	var clientName string
-	err = tx.QueryRow(`
-        SELECT nombre FROM clientes WHERE id = ?
-    `, sale.ClientId).Scan(&clientName)
-	if errors.Is(err, sql.ErrNoRows) {
-		return ErrNotFound
-	}
-	if err != nil {
-		return err
-	}
-
-	sale.Total = 0
-	for _, item := range sale.Items {
-		var price float64
-		err = tx.QueryRow(`
-			SELECT precio FROM productos WHERE id = ?
-		`, item.ProductID).Scan(&price)
-		if errors.Is(err, sql.ErrNoRows) {
-			return ErrNotFound
-		}
-		if err != nil {
-			return err
-		}
-		sale.Total += price * float64(item.Quantity)
-	}
-
-	for _, item := range sale.Items {
-		rows, err := tx.Query(`
-            SELECT insumo_id, cantidad_insumo
-            FROM producto_insumos
-            WHERE producto_id = ?
-        `, item.ProductID)
-		if err != nil {
-			return err
-		}
-
-		for rows.Next() {
-			var insumoID int64
-			var qtyPerProduct float64
-			if err := rows.Scan(&insumoID, &qtyPerProduct); err != nil {
-				rows.Close()
-				return err
-			}
-
-			totalNeeded := qtyPerProduct * float64(item.Quantity)
-
-			res, err := tx.Exec(`
-                UPDATE insumos
-                SET stock_actual = stock_actual - ?
-                WHERE id = ? AND stock_actual >= ?
-            `, totalNeeded, insumoID, totalNeeded)
-			if err != nil {
-				rows.Close()
-				return err
-			}
-			ra, _ := res.RowsAffected()
-			if ra == 0 {
-				rows.Close()
-				return ErrInvalidInput
-			}
-		}
-
-		if err := rows.Err(); err != nil {
-			rows.Close()
-			return err
-		}
-		rows.Close()
-	}
-
-	if sale.IsCredit {
-		res, err := tx.Exec(`
-			INSERT INTO credit_sales (client_id, total, remaining_balance, date)
-			VALUES (?, ?, ?, ?)
-		`, sale.ClientId, sale.Total, sale.Total, sale.Date)
-		if err != nil {
-			return err
-		}
-
-		creditID, _ := res.LastInsertId()
-
-		for _, item := range sale.Items {
-			_, err = tx.Exec(`
-				INSERT INTO credit_sale_items (credit_sale_id, product_id, quantity)
-				VALUES (?, ?, ?)
-			`, creditID, item.ProductID, item.Quantity)
-			if err != nil {
-				return err
-			}
-		}
-
-		_, err = tx.Exec(`
-        UPDATE clientes
-        SET deuda = deuda + ?
-        WHERE id = ?`, sale.Total, sale.ClientId)
-		if err != nil {
-			return err
-		}
-
-		return nil
-	}
-
-	description, err := buildSaleDescription(sale.Items, tx)
-	if err != nil {
-		return err
-	}
-
-	description = strings.ToTitle(clientName) + ":\n" + description
-
-	_, err = tx.Exec(`
-    INSERT INTO movimientos (descripcion, tipo, monto, fecha, cliente_id)
-    VALUES (?, 'ingreso', ?, ?, ?)
-	`, description, sale.Total, sale.Date, sale.ClientId)
-
-	if err != nil {
-		return err
-	}
-
-	_, err = tx.Exec(`
-		UPDATE caja
-		SET saldo = saldo + ?
-		WHERE id = 1
-	`, sale.Total)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (s *MovementService) PayCredit(creditSaleID int64, amount float64) (err error) {
-	if amount <= 0 {
-		return ErrInvalidInput
-	}
-
-	tx, err := s.DB.Begin()
-	if err != nil {
-		return err
-	}
-
-	defer func() {
-		if p := recover(); p != nil {
-			_ = tx.Rollback()
-			panic(p)
-		} else if err != nil {
-			_ = tx.Rollback()
-		} else {
-			if cerr := tx.Commit(); cerr != nil {
-				err = cerr
-			}
-		}
-	}()
-
-	var rem float64
-	var clientID int64
-	err = tx.QueryRow(`SELECT remaining_balance, client_id FROM credit_sales WHERE id = ?`, creditSaleID).Scan(&rem, &clientID)
-	if errors.Is(err, sql.ErrNoRows) {
-		return ErrNotFound
-	}
-	if err != nil {
-		return err
-	}
-
-	if amount > rem {
-		return ErrInvalidInput
-	}
-
-	res, err := tx.Exec(`UPDATE credit_sales SET remaining_balance = remaining_balance - ? WHERE id = ? AND remaining_balance >= ?`, amount, creditSaleID, amount)
-	if err != nil {
-		return err
-	}
-	ra, _ := res.RowsAffected()
-	if ra == 0 {
-		return ErrInvalidInput
-	}
-
-	res, err = tx.Exec(`UPDATE clientes SET deuda = deuda - ? WHERE id = ? AND deuda >= ?`, amount, clientID, amount)
-	if err != nil {
-		return err
-	}
-	ra, _ = res.RowsAffected()
-	if ra == 0 {
-		return ErrInvalidInput
-	}
-
-	_, err = tx.Exec(`INSERT INTO credit_payments (credit_sale_id, amount, date) VALUES (?, ?, ?)`,
-		creditSaleID, amount, time.Now().Format("2006-01-02 15:04"))
-	if err != nil {
-		return err
-	}
-
-	_, err = tx.Exec(`
-    INSERT INTO movimientos (descripcion, tipo, monto, fecha, cliente_id)
-    VALUES (?, 'ingreso', ?, ?, ?)
-	`, "Abono a crédito", amount, time.Now().Format("2006-01-02 15:04"), clientID)
-
-	if err != nil {
-		return err
-	}
-
-	_, err = tx.Exec(`UPDATE caja SET saldo = saldo + ? WHERE id = 1`, amount)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (s *MovementService) GetAllCreditSales() ([]models.CreditSale, error) {
-
-	sales := []models.CreditSale{}
-
-	rows, err := s.DB.Query(`
-		SELECT 
-			cs.id,
-			cs.total,
-			cs.remaining_balance,
-			cs.date,
-			c.nombre
-		FROM credit_sales cs
-		JOIN clientes c ON c.id = cs.client_id
-		WHERE
-			cs.remaining_balance > 0
-			OR (
-				cs.remaining_balance = 0
-				AND date(cs.date) >= date('now', '-30 days')
-			)
-		ORDER BY cs.id
-	`)
-	if err != nil {
-		return sales, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var cs models.CreditSale
-		var remain float64
-
-		err := rows.Scan(
-			&cs.SaleId,
-			&cs.Total,
-			&remain,
-			&cs.Date,
-			&cs.ClientName,
-		)
-		if err != nil {
-			return sales, err
-		}
-
-		cs.TotalPaid = cs.Total - remain
-		cs.Items = []models.SaleItem{}
-		sales = append(sales, cs)
-	}
-
-	if err := rows.Err(); err != nil {
-		return sales, err
-	}
-
-	for i := range sales {
-		itemsRows, err := s.DB.Query(`
-			SELECT product_id, quantity 
-			FROM credit_sale_items
-			WHERE credit_sale_id = ?
-		`, sales[i].SaleId)
-		if err != nil {
-			return sales, err
-		}
-
-		for itemsRows.Next() {
-			var item models.SaleItem
-			err := itemsRows.Scan(&item.ProductID, &item.Quantity)
-			if err != nil {
-				itemsRows.Close()
-				return sales, err
-			}
-			sales[i].Items = append(sales[i].Items, item)
-		}
-		itemsRows.Close()
-
-		desc, err := buildSaleDescription(sales[i].Items, s.DB)
-		if err != nil {
-			return sales, err
-		}
-		sales[i].Description = desc
-	}
-
-	return sales, nil
-}
-
-func (s *MovementService) GetCreditSalesClients(client_id int) ([]models.CreditSale, error) {
-
-	tx, err := s.DB.Begin()
-	if err != nil {
-		return nil, err
-	}
-
-	defer func() {
-		if p := recover(); p != nil {
-			_ = tx.Rollback()
-			panic(p)
-		} else if err != nil {
-			_ = tx.Rollback()
-		} else {
-			if cerr := tx.Commit(); cerr != nil {
-				err = cerr
-			}
-		}
-	}()
-
-	var tmpID int
-	err = tx.QueryRow(`
-		SELECT id FROM clientes WHERE id = ?
-	`, client_id).Scan(&tmpID)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrNotFound
-		}
-		return nil, err
-	}
-
-	rows, err := tx.Query(`
-		SELECT cs.id, cs.total, cs.remaining_balance, cs.date, csi.product_id, csi.quantity
-		FROM credit_sales cs
-		JOIN credit_sale_items csi ON csi.credit_sale_id = cs.id
-		WHERE cs.client_id = ?
-		  AND (
-			  cs.remaining_balance > 0
-			  OR (
-				  cs.remaining_balance = 0
-				  AND date(cs.date) >= date('now', '-30 days')
-			  )
-		  )
-		ORDER BY cs.id
-	`, client_id)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	salesMap := make(map[int64]int)
-	var creditSalesArr []models.CreditSale
-
-	for rows.Next() {
-		var saleID int64
-		var total float64
-		var remaining float64
-		var dateStr string
-		var productID int64
-		var qty float64
-
-		if err := rows.Scan(&saleID, &total, &remaining, &dateStr, &productID, &qty); err != nil {
-			return nil, err
-		}
-		idx, exists := salesMap[saleID]
-		if !exists {
-			cs := models.CreditSale{
-				SaleId:    saleID,
-				Items:     []models.SaleItem{},
-				Total:     total,
-				Date:      dateStr,
-				TotalPaid: total - remaining,
-			}
-			creditSalesArr = append(creditSalesArr, cs)
-			idx = len(creditSalesArr) - 1
-			salesMap[saleID] = idx
-		}
-
-		creditSalesArr[idx].Items = append(creditSalesArr[idx].Items, models.SaleItem{
-			ProductID: productID,
-			Quantity:  int64(qty),
-		})
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	for i := range creditSalesArr {
-		desc, err := buildSaleDescription(creditSalesArr[i].Items, tx)
-		if err != nil {
-			return nil, err
-		}
-		creditSalesArr[i].Description = desc
-	}
-
-	return creditSalesArr, nil
-}
-
-func (s *MovementService) GetCreditPayments(sale_id int) ([]models.Payments, error) {
-	var tmpID int64
-	err := s.DB.QueryRow(`
-		SELECT id FROM credit_sales WHERE id = ?
-	`, sale_id).Scan(&tmpID)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrNotFound
-		}
-		return nil, err
-	}
-	var paymentsArr []models.Payments
-
-	rows, err := s.DB.Query(`
-		SELECT id, date, amount FROM credit_payments WHERE credit_sale_id = ?
-	`, sale_id)
-
-	if err != nil {
-		return nil, err
-	}
-	for rows.Next() {
-		var payment models.Payments
-		if err := rows.Scan(&payment.ID, &payment.Date, &payment.Amount); err != nil {
-			return nil, err
-		}
-		paymentsArr = append(paymentsArr, payment)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return paymentsArr, nil
-}
-
-func (s *MovementService) AdjustBalance(req models.BalanceAdjustment) (err error) {
-	if req.Amount < 0 {
-		return ErrInvalidInput
-	}
-	tx, err := s.DB.Begin()
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if p := recover(); p != nil {
-			_ = tx.Rollback()
-			panic(p)
-		} else if err != nil {
-			_ = tx.Rollback()
-		}
-		if cerr := tx.Commit(); cerr != nil {
-			err = cerr
-		}
-	}()
-
-	var currentBalance float64
-	err = tx.QueryRow(`
-		SELECT saldo FROM caja WHERE id = 1
-	`).Scan(&currentBalance)
-	if err != nil {
-		return err
-	}
-	diff := req.Amount - currentBalance
-	var movementType string
-	if diff > 0 {
-		movementType = "ingreso"
-	}
-	if diff < 0 {
-		movementType = "egreso"
-		diff = -diff
-	}
-	_, err = tx.Exec(`
-		INSERT INTO movimientos (descripcion, tipo, monto, fecha)
-		VALUES (?, ?, ?, ?)
-	`, req.Description, movementType, diff, time.Now().Format("2006-01-02 15:04"))
-	if err != nil {
-		return err
-	}
-
-	_, err = tx.Exec(`
-		UPDATE caja
-		SET saldo = ?
-		WHERE id = 1
-	`, req.Amount)
-	if err != nil {
-		return err
-	}
-	return nil
-}
+package services
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+type MovementService struct {
+	DB *sql.DB
+}
+
+func NewMoveService(db *sql.DB) *MovementService {
+	return &MovementService{DB: db}
+}
+
+const (
+	defaultMovesLimit = 50
+	maxMovesLimit     = 500
+
+	// Plazo de un fiado cuando el cliente no tiene uno propio
+	defaultCreditDays = 30
+)
+
+// GetAll devuelve una página de movimientos con los totales del rango. Sin
+// fechas se limita a los últimos 30 días, como antes.
+func (s *MovementService) GetAll(f models.MoveFilter) (models.MovePage, error) {
+	page := models.MovePage{Moves: []models.Move{}}
+
+	where, args := moveWhere(f)
+
+	var ingresos, egresos models.Money
+	err := s.DB.QueryRow(`
+		SELECT COUNT(*),
+			COALESCE(SUM(CASE WHEN tipo = 'ingreso' THEN monto END), 0),
+			COALESCE(SUM(CASE WHEN tipo = 'egreso' THEN monto END), 0)
+		FROM movimientos
+		WHERE `+strings.Join(where, " AND "), args...).Scan(&page.Totals.Count, &ingresos, &egresos)
+	if err != nil {
+		return page, err
+	}
+	page.Totals.Ingresos = ingresos
+	page.Totals.Egresos = egresos
+	page.Totals.Net = ingresos - egresos
+
+	col := "fecha"
+	if f.Sort == "monto" {
+		col = "monto"
+	}
+	dir, cmp := "DESC", "<"
+	if f.Asc {
+		dir, cmp = "ASC", ">"
+	}
+
+	// Paginación por cursor: seguimos desde el último (valor, id) entregado
+	if f.Cursor != "" {
+		val, id, err := decodeMoveCursor(f.Cursor, col)
+		if err != nil {
+			return page, err
+		}
+		where = append(where, "("+col+" "+cmp+" ? OR ("+col+" = ? AND id "+cmp+" ?))")
+		args = append(args, val, val, id)
+	}
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = defaultMovesLimit
+	}
+	if limit > maxMovesLimit {
+		limit = maxMovesLimit
+	}
+
+	moves, err := queryMoves(s.DB, `
+		SELECT id, descripcion, tipo, origen, monto, fecha, cliente_id, COALESCE(cuenta_id, 1), categoria_id
+		FROM movimientos
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY `+col+` `+dir+`, id `+dir+`
+		LIMIT ?
+	`, append(args, limit+1)...)
+	if err != nil {
+		return page, err
+	}
+
+	if len(moves) > limit {
+		moves = moves[:limit]
+		last := moves[limit-1]
+		if col == "monto" {
+			page.NextCursor = encodeMoveCursor(strconv.FormatInt(int64(last.Amount), 10), last.ID)
+		} else {
+			page.NextCursor = encodeMoveCursor(last.Date, last.ID)
+		}
+	}
+	page.Moves = moves
+
+	return page, nil
+}
+
+func moveWhere(f models.MoveFilter) ([]string, []any) {
+	where := []string{"1 = 1"}
+	args := []any{}
+
+	if f.From == "" && f.To == "" {
+		where = append(where, "date(fecha) >= date('now', '-30 days')")
+	}
+	if f.From != "" {
+		where = append(where, "date(fecha) >= date(?)")
+		args = append(args, f.From)
+	}
+	if f.To != "" {
+		where = append(where, "date(fecha) <= date(?)")
+		args = append(args, f.To)
+	}
+	if f.Type != "" {
+		where = append(where, "tipo = ?")
+		args = append(args, f.Type)
+	}
+	if f.ClientID > 0 {
+		where = append(where, "cliente_id = ?")
+		args = append(args, f.ClientID)
+	}
+	if q := strings.TrimSpace(f.Search); q != "" {
+		q = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q)
+		where = append(where, `descripcion LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+q+"%")
+	}
+
+	return where, args
+}
+
+func encodeMoveCursor(val string, id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(val + "|" + strconv.FormatInt(id, 10)))
+}
+
+func decodeMoveCursor(cursor, col string) (any, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, 0, ErrInvalidInput
+	}
+
+	i := strings.LastIndex(string(raw), "|")
+	if i < 0 {
+		return nil, 0, ErrInvalidInput
+	}
+	val := string(raw[:i])
+	id, err := strconv.ParseInt(string(raw[i+1:]), 10, 64)
+	if err != nil {
+		return nil, 0, ErrInvalidInput
+	}
+
+	if col == "monto" {
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return nil, 0, ErrInvalidInput
+		}
+		return n, id, nil
+	}
+	return val, id, nil
+}
+
+func queryMoves(db *sql.DB, query string, args ...any) ([]models.Move, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	moves := []models.Move{}
+	for rows.Next() {
+		var m models.Move
+		var clientID, categoryID sql.NullInt64
+
+		err := rows.Scan(&m.ID, &m.Description, &m.Type, &m.Origin, &m.Amount, &m.Date, &clientID, &m.AccountID, &categoryID)
+		if err != nil {
+			return nil, err
+		}
+		if clientID.Valid {
+			v := clientID.Int64
+			m.ClientID = &v
+		}
+		if categoryID.Valid {
+			v := categoryID.Int64
+			m.CategoryID = &v
+		}
+
+		moves = append(moves, m)
+	}
+
+	return moves, rows.Err()
+}
+
+// GetMovesByClient devuelve todos los movimientos del cliente en el rango de f.
+func (s *MovementService) GetMovesByClient(clientID int, f models.MoveFilter) ([]models.Move, error) {
+	f.ClientID = int64(clientID)
+	where, args := moveWhere(f)
+
+	return queryMoves(s.DB, `
+		SELECT id, descripcion, tipo, origen, monto, fecha, cliente_id, COALESCE(cuenta_id, 1), categoria_id
+		FROM movimientos
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY fecha DESC, id DESC
+	`, args...)
+}
+
+func (s *MovementService) GetRecent(limit int) ([]models.Move, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+	if limit > maxMovesLimit {
+		limit = maxMovesLimit
+	}
+
+	return queryMoves(s.DB, `
+		SELECT id, descripcion, tipo, origen, monto, fecha, cliente_id, COALESCE(cuenta_id, 1), categoria_id
+		FROM movimientos
+		ORDER BY fecha DESC, id DESC
+		LIMIT ?
+	`, limit)
+}
+
+func (s *MovementService) GetBalance() (models.Account, error) {
+	var b models.Account
+	err := s.DB.QueryRow(`
+        SELECT
+            (SELECT COALESCE(SUM(saldo), 0) FROM accounts),
+            (SELECT COALESCE(SUM(deuda), 0) FROM clientes),
+            (SELECT COALESCE(SUM(remaining_balance), 0) FROM credit_purchases)
+    `).Scan(&b.Balance, &b.AmountOwed, &b.AmountPayable)
+	if err != nil {
+		return models.Account{}, err
+	}
+
+	b.Accounts, err = listAccounts(s.DB)
+	if err != nil {
+		return models.Account{}, err
+	}
+	return b, nil
+}
+
+// moveRecord es una fila nueva de movimientos. ClientID y SaleID en 0 se
+// guardan como NULL; AccountID en 0 es la caja.
+type moveRecord struct {
+	Description string
+	Type        string // models.MoveIngreso | models.MoveEgreso | models.MoveTransfer
+	Origin      string
+	Amount      models.Money
+	Date        string
+	ClientID    int64
+	SaleID      int64
+	AccountID   int64
+	CategoryID  int64
+}
+
+// recordMove registra el movimiento en la sesión de caja abierta (si hay una)
+// y lo aplica al saldo de la cuenta.
+func recordMove(tx *sql.Tx, m moveRecord) error {
+	if m.AccountID == 0 {
+		m.AccountID = models.CashAccountID
+	}
+
+	delta := m.Amount
+	switch m.Type {
+	case models.MoveIngreso, models.MoveTransfer:
+	case models.MoveEgreso:
+		delta = -delta
+	default:
+		delta = 0
+	}
+
+	res, err := tx.Exec(`UPDATE accounts SET saldo = saldo + ? WHERE id = ?`, delta, m.AccountID)
+	if err != nil {
+		return err
+	}
+	if ra, _ := res.RowsAffected(); ra == 0 {
+		return ErrAccountNotFound
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO movimientos (descripcion, tipo, origen, monto, fecha, cliente_id, venta_id, cuenta_id, categoria_id, sesion_id)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, 0), NULLIF(?, 0), ?, NULLIF(?, 0), (SELECT id FROM cash_sessions WHERE closed_at IS NULL))
+	`, m.Description, m.Type, m.Origin, m.Amount, m.Date, m.ClientID, m.SaleID, m.AccountID, m.CategoryID)
+	return err
+}
+
+// Queryer lo cumplen *sql.DB y *sql.Tx, para leer dentro o fuera de una transacción.
+type Queryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func buildSaleDescription(items []models.SaleItem, db Queryer) (string, error) {
+
+	var sb strings.Builder
+
+	for _, item := range items {
+		var name string
+		err := db.QueryRow(`
+			SELECT nombre 
+			FROM productos 
+			WHERE id = ?
+		`, item.ProductID).Scan(&name)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		if err != nil {
+			return "", err
+		}
+
+		sb.WriteString("- ")
+		sb.WriteString(name)
+		sb.WriteString(" x ")
+		sb.WriteString(strconv.FormatInt(item.Quantity, 10))
+		sb.WriteString("\n")
+	}
+
+	return sb.String(), nil
+}
+
+func (s *MovementService) Supply(supply models.Supply, user string) (err error) {
+	if supply.Amount <= 0 || supply.TotalAmount < 0 {
+		return ErrInvalidInput
+	}
+
+	supply.Date = time.Now().Format("2006-01-02 15:04")
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	lotID, err := supplyTx(tx, supply)
+	if err != nil {
+		return err
+	}
+
+	return writeAudit(tx, user, models.AuditCreate, models.AuditSupply, lotID, nil, supply)
+}
+
+// supplyTx hace el surtido dentro de tx: suma stock, registra el egreso,
+// guarda el lote y recalcula el precio del insumo. Devuelve el id del lote.
+func supplyTx(tx *sql.Tx, supply models.Supply) (lotID int64, err error) {
+	// A crédito no sale plata de ninguna cuenta, pero hay que saber a quién se le debe
+	if supply.IsCredit {
+		if supply.SupplierID <= 0 {
+			return 0, ErrInvalidInput
+		}
+		supply.AccountID = 0
+		if supply.DueDate == "" {
+			due, _ := time.Parse("2006-01-02 15:04", supply.Date)
+			supply.DueDate = due.AddDate(0, 0, defaultPayableDays).Format("2006-01-02")
+		} else if _, err := time.Parse("2006-01-02", supply.DueDate); err != nil {
+			return 0, ErrInvalidInput
+		}
+	} else if supply.AccountID == 0 {
+		supply.AccountID = models.CashAccountID
+	}
+
+	var actualStock float64
+	var nameInsumo string
+	var unitPrice models.Money
+	var method string
+
+	err = tx.QueryRow(`
+        SELECT stock_actual, nombre, precio_unitario, metodo_costo
+        FROM insumos 
+        WHERE id = ? AND archived_at IS NULL
+    `, supply.IdInsumo).Scan(&actualStock, &nameInsumo, &unitPrice, &method)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	supplierName := ""
+	if supply.SupplierID > 0 {
+		err = tx.QueryRow(`SELECT name FROM suppliers WHERE id = ?`, supply.SupplierID).Scan(&supplierName)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrSupplierNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	newStock := actualStock + supply.Amount
+
+	_, err = tx.Exec(`
+        UPDATE insumos
+        SET stock_actual = ?
+        WHERE id = ?
+    `, newStock, supply.IdInsumo)
+	if err != nil {
+		return 0, err
+	}
+
+	// Sin total se asume el precio vigente, como antes
+	if supply.TotalAmount == 0 {
+		supply.TotalAmount = unitPrice.MulQty(supply.Amount)
+	}
+	lotCost := supply.TotalAmount.MulQty(1 / supply.Amount)
+
+	description := "Surtido de insumo: " +
+		strings.ToTitle(nameInsumo) +
+		" X " +
+		strconv.FormatFloat(supply.Amount, 'f', -1, 64)
+	if supplierName != "" {
+		description += " (" + supplierName + ")"
+	}
+
+	if !supply.IsCredit {
+		err = recordMove(tx, moveRecord{
+			Description: description,
+			Type:        models.MoveEgreso,
+			Origin:      models.OriginSupply,
+			Amount:      supply.TotalAmount,
+			Date:        supply.Date,
+			AccountID:   supply.AccountID,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	lotID, err = addLot(tx, lotRecord{
+		InsumoID:    supply.IdInsumo,
+		Kind:        lotPurchase,
+		Quantity:    supply.Amount,
+		UnitCost:    lotCost,
+		Total:       supply.TotalAmount,
+		AccountID:   supply.AccountID,
+		SupplierID:  supply.SupplierID,
+		OrderItemID: supply.OrderItemID,
+		Date:        supply.Date,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if supply.IsCredit {
+		_, err = tx.Exec(`
+			INSERT INTO credit_purchases (supplier_id, supply_id, description, total, remaining_balance, date, due_date)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, supply.SupplierID, lotID, description, supply.TotalAmount, supply.TotalAmount, supply.Date, supply.DueDate)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	newPrice := unitPrice
+	switch method {
+	case models.CostLast:
+		newPrice = lotCost
+	case models.CostAverage:
+		// Stock negativo o en cero no pesa en el promedio
+		prevStock := max(actualStock, 0)
+		newPrice = models.Money(math.Round(
+			(float64(unitPrice)*prevStock + float64(supply.TotalAmount)) / (prevStock + supply.Amount),
+		))
+	case models.CostFIFO:
+		oldest, ok, err := oldestLotCost(tx, supply.IdInsumo)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			newPrice = oldest
+		}
+	}
+
+	return lotID, setInsumoPrice(tx, supply.IdInsumo, unitPrice, newPrice, method, lotID, supply.Date)
+}
+
+func (s *MovementService) Sell(sale models.Sale, user string) (saleID int64, err error) {
+	sale.Date = time.Now().Format("2006-01-02 15:04")
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	var clientName string
+	err = tx.QueryRow(`
+        SELECT nombre FROM clientes WHERE id = ? AND archived_at IS NULL
+    `, sale.ClientId).Scan(&clientName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// Precio y costo se congelan en la línea para que los reportes no cambien
+	// cuando después se edite el producto o suba un insumo.
+	sale.Total = 0
+	var saleCost models.Money
+	lines := make([]models.SaleLine, len(sale.Items))
+	for i, item := range sale.Items {
+		line := models.SaleLine{ProductID: item.ProductID, Quantity: item.Quantity}
+		var fromStock bool
+		err = tx.QueryRow(`
+			SELECT precio, costo_total, usa_stock FROM productos WHERE id = ? AND archived_at IS NULL
+		`, item.ProductID).Scan(&line.UnitPrice, &line.UnitCost, &fromStock)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+		line.Source = models.SourceInsumos
+		if fromStock {
+			line.Source = models.SourceStock
+			if line.UnitCost, err = stockUnitCost(tx, item.ProductID, line.UnitCost); err != nil {
+				return 0, err
+			}
+		}
+		line.Subtotal = line.UnitPrice * models.Money(item.Quantity)
+		lines[i] = line
+
+		sale.Total += line.Subtotal
+		saleCost += line.UnitCost * models.Money(item.Quantity)
+	}
+
+	for _, item := range lines {
+		// Lo fabricado por tandas sale del stock del producto, no de la receta
+		if item.Source == models.SourceStock {
+			res, err := tx.Exec(`
+				UPDATE productos
+				SET stock_actual = stock_actual - ?
+				WHERE id = ? AND stock_actual >= ?
+			`, item.Quantity, item.ProductID, item.Quantity)
+			if err != nil {
+				return 0, err
+			}
+			ra, _ := res.RowsAffected()
+			if ra == 0 {
+				return 0, ErrInvalidInput
+			}
+			continue
+		}
+
+		recipe, err := productRecipe(tx, item.ProductID)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, ri := range recipe {
+			if _, err := consumeInsumo(tx, ri.insumoID, ri.quantity*float64(item.Quantity)); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	var creditID sql.NullInt64
+	if sale.IsCredit {
+		var debt, credit models.Money
+		var limit sql.NullInt64
+		var termDays, overdue int
+		err := tx.QueryRow(`
+			SELECT deuda, saldo_favor, limite_credito, COALESCE(plazo_dias, 0),
+				(SELECT COUNT(*) FROM credit_sales
+				 WHERE client_id = clientes.id AND remaining_balance > 0 AND due_date < date('now', 'localtime'))
+			FROM clientes WHERE id = ?
+		`, sale.ClientId).Scan(&debt, &credit, &limit, &termDays, &overdue)
+		if err != nil {
+			return 0, err
+		}
+
+		// El saldo a favor del cliente se descuenta primero, como un abono ya recibido
+		credit = min(credit, sale.Total)
+
+		if !sale.Override {
+			if overdue > 0 {
+				return 0, ErrCreditOverdue
+			}
+			if limit.Valid && debt+sale.Total-credit > models.Money(limit.Int64) {
+				return 0, ErrCreditLimit
+			}
+		}
+
+		if termDays <= 0 {
+			termDays = defaultCreditDays
+		}
+		dueDate := time.Now().AddDate(0, 0, termDays).Format("2006-01-02")
+
+		res, err := tx.Exec(`
+			INSERT INTO credit_sales (client_id, total, remaining_balance, date, due_date)
+			VALUES (?, ?, ?, ?, ?)
+		`, sale.ClientId, sale.Total, sale.Total-credit, sale.Date, dueDate)
+		if err != nil {
+			return 0, err
+		}
+
+		creditID.Int64, _ = res.LastInsertId()
+		creditID.Valid = true
+
+		if credit > 0 {
+			_, err = tx.Exec(`INSERT INTO credit_payments (credit_sale_id, amount, date, from_credit) VALUES (?, ?, ?, 1)`,
+				creditID.Int64, credit, sale.Date)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		for _, item := range sale.Items {
+			_, err = tx.Exec(`
+				INSERT INTO credit_sale_items (credit_sale_id, product_id, quantity)
+				VALUES (?, ?, ?)
+			`, creditID.Int64, item.ProductID, item.Quantity)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		_, err = tx.Exec(`
+        UPDATE clientes
+        SET deuda = deuda + ?, saldo_favor = saldo_favor - ?
+        WHERE id = ?`, sale.Total-credit, credit, sale.ClientId)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO sales (client_id, total, cost, is_credit, credit_sale_id, date)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, sale.ClientId, sale.Total, saleCost, sale.IsCredit, creditID, sale.Date)
+	if err != nil {
+		return 0, err
+	}
+	saleID, _ = res.LastInsertId()
+
+	for _, line := range lines {
+		_, err = tx.Exec(`
+			INSERT INTO sale_items (sale_id, product_id, quantity, unit_price, unit_cost, source)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, saleID, line.ProductID, line.Quantity, line.UnitPrice, line.UnitCost, line.Source)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err = writeAudit(tx, user, models.AuditCreate, models.AuditSale, saleID, nil, sale); err != nil {
+		return 0, err
+	}
+
+	if sale.IsCredit {
+		return saleID, nil
+	}
+
+	description, err := buildSaleDescription(sale.Items, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	description = strings.ToTitle(clientName) + ":\n" + description
+
+	err = recordMove(tx, moveRecord{
+		Description: description,
+		Type:        models.MoveIngreso,
+		Origin:      models.OriginSale,
+		Amount:      sale.Total,
+		Date:        sale.Date,
+		ClientID:    sale.ClientId,
+		SaleID:      saleID,
+		AccountID:   sale.AccountID,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return saleID, nil
+}
+
+func (s *MovementService) PayCredit(creditSaleID int64, amount models.Money, accountID int64, user string) (err error) {
+	if amount <= 0 {
+		return ErrInvalidInput
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	var rem models.Money
+	var clientID int64
+	err = tx.QueryRow(`SELECT remaining_balance, client_id FROM credit_sales WHERE id = ?`, creditSaleID).Scan(&rem, &clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if amount > rem {
+		return ErrInvalidInput
+	}
+
+	res, err := tx.Exec(`UPDATE credit_sales SET remaining_balance = remaining_balance - ? WHERE id = ? AND remaining_balance >= ?`, amount, creditSaleID, amount)
+	if err != nil {
+		return err
+	}
+	ra, _ := res.RowsAffected()
+	if ra == 0 {
+		return ErrInvalidInput
+	}
+
+	res, err = tx.Exec(`UPDATE clientes SET deuda = deuda - ? WHERE id = ? AND deuda >= ?`, amount, clientID, amount)
+	if err != nil {
+		return err
+	}
+	ra, _ = res.RowsAffected()
+	if ra == 0 {
+		return ErrInvalidInput
+	}
+
+	_, err = tx.Exec(`INSERT INTO credit_payments (credit_sale_id, amount, date) VALUES (?, ?, ?)`,
+		creditSaleID, amount, time.Now().Format("2006-01-02 15:04"))
+	if err != nil {
+		return err
+	}
+
+	err = recordMove(tx, moveRecord{
+		Description: "Abono a crédito",
+		Type:        models.MoveIngreso,
+		Origin:      models.OriginPayment,
+		Amount:      amount,
+		Date:        time.Now().Format("2006-01-02 15:04"),
+		ClientID:    clientID,
+		AccountID:   accountID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeAudit(tx, user, models.AuditCreate, models.AuditPayment, creditSaleID, nil, map[string]any{
+		"credit_sale_id": creditSaleID,
+		"client_id":      clientID,
+		"amount":         amount,
+		"account_id":     accountID,
+	})
+}
+
+func (s *MovementService) GetAllCreditSales() ([]models.CreditSale, error) {
+
+	sales := []models.CreditSale{}
+
+	rows, err := s.DB.Query(`
+		SELECT 
+			cs.id,
+			cs.total,
+			cs.remaining_balance,
+			cs.date,
+			COALESCE(cs.due_date, ''),
+			c.nombre
+		FROM credit_sales cs
+		JOIN clientes c ON c.id = cs.client_id
+		WHERE
+			cs.remaining_balance > 0
+			OR (
+				cs.remaining_balance = 0
+				AND date(cs.date) >= date('now', '-30 days')
+			)
+		ORDER BY cs.id
+	`)
+	if err != nil {
+		return sales, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cs models.CreditSale
+		var remain models.Money
+
+		err := rows.Scan(
+			&cs.SaleId,
+			&cs.Total,
+			&remain,
+			&cs.Date,
+			&cs.DueDate,
+			&cs.ClientName,
+		)
+		if err != nil {
+			return sales, err
+		}
+
+		cs.TotalPaid = cs.Total - remain
+		cs.Overdue = remain > 0 && isOverdue(cs.DueDate)
+		cs.Items = []models.SaleItem{}
+		sales = append(sales, cs)
+	}
+
+	if err := rows.Err(); err != nil {
+		return sales, err
+	}
+
+	for i := range sales {
+		itemsRows, err := s.DB.Query(`
+			SELECT product_id, quantity 
+			FROM credit_sale_items
+			WHERE credit_sale_id = ?
+		`, sales[i].SaleId)
+		if err != nil {
+			return sales, err
+		}
+
+		for itemsRows.Next() {
+			var item models.SaleItem
+			err := itemsRows.Scan(&item.ProductID, &item.Quantity)
+			if err != nil {
+				itemsRows.Close()
+				return sales, err
+			}
+			sales[i].Items = append(sales[i].Items, item)
+		}
+		itemsRows.Close()
+
+		desc, err := buildSaleDescription(sales[i].Items, s.DB)
+		if err != nil {
+			return sales, err
+		}
+		sales[i].Description = desc
+	}
+
+	return sales, nil
+}
+
+func (s *MovementService) GetCreditSalesClients(client_id int) ([]models.CreditSale, error) {
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	var tmpID int
+	err = tx.QueryRow(`
+		SELECT id FROM clientes WHERE id = ?
+	`, client_id).Scan(&tmpID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT cs.id, cs.total, cs.remaining_balance, cs.date, COALESCE(cs.due_date, ''), csi.product_id, csi.quantity
+		FROM credit_sales cs
+		JOIN credit_sale_items csi ON csi.credit_sale_id = cs.id
+		WHERE cs.client_id = ?
+		  AND (
+			  cs.remaining_balance > 0
+			  OR (
+				  cs.remaining_balance = 0
+				  AND date(cs.date) >= date('now', '-30 days')
+			  )
+		  )
+		ORDER BY cs.id
+	`, client_id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	salesMap := make(map[int64]int)
+	var creditSalesArr []models.CreditSale
+
+	for rows.Next() {
+		var saleID int64
+		var total models.Money
+		var remaining models.Money
+		var dateStr, dueDate string
+		var productID int64
+		var qty float64
+
+		if err := rows.Scan(&saleID, &total, &remaining, &dateStr, &dueDate, &productID, &qty); err != nil {
+			return nil, err
+		}
+		idx, exists := salesMap[saleID]
+		if !exists {
+			cs := models.CreditSale{
+				SaleId:    saleID,
+				Items:     []models.SaleItem{},
+				Total:     total,
+				Date:      dateStr,
+				DueDate:   dueDate,
+				Overdue:   remaining > 0 && isOverdue(dueDate),
+				TotalPaid: total - remaining,
+			}
+			creditSalesArr = append(creditSalesArr, cs)
+			idx = len(creditSalesArr) - 1
+			salesMap[saleID] = idx
+		}
+
+		creditSalesArr[idx].Items = append(creditSalesArr[idx].Items, models.SaleItem{
+			ProductID: productID,
+			Quantity:  int64(qty),
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range creditSalesArr {
+		desc, err := buildSaleDescription(creditSalesArr[i].Items, tx)
+		if err != nil {
+			return nil, err
+		}
+		creditSalesArr[i].Description = desc
+	}
+
+	return creditSalesArr, nil
+}
+
+// isOverdue dice si una fecha de vencimiento (2006-01-02) ya pasó.
+func isOverdue(dueDate string) bool {
+	return dueDate != "" && dueDate < time.Now().Format("2006-01-02")
+}
+
+func (s *MovementService) GetCreditPayments(sale_id int) ([]models.Payments, error) {
+	var tmpID int64
+	err := s.DB.QueryRow(`
+		SELECT id FROM credit_sales WHERE id = ?
+	`, sale_id).Scan(&tmpID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	var paymentsArr []models.Payments
+
+	rows, err := s.DB.Query(`
+		SELECT id, date, amount, from_credit FROM credit_payments WHERE credit_sale_id = ?
+	`, sale_id)
+
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var payment models.Payments
+		if err := rows.Scan(&payment.ID, &payment.Date, &payment.Amount, &payment.FromCredit); err != nil {
+			return nil, err
+		}
+		paymentsArr = append(paymentsArr, payment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return paymentsArr, nil
+}
+
+func (s *MovementService) AdjustBalance(req models.BalanceAdjustment, user string) (err error) {
+	if req.Amount < 0 {
+		return ErrInvalidInput
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		}
+		if cerr := tx.Commit(); cerr != nil {
+			err = cerr
+		}
+	}()
+
+	if req.AccountID == 0 {
+		req.AccountID = models.CashAccountID
+	}
+
+	var currentBalance models.Money
+	err = tx.QueryRow(`
+		SELECT saldo FROM accounts WHERE id = ?
+	`, req.AccountID).Scan(&currentBalance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAccountNotFound
+	}
+	if err != nil {
+		return err
+	}
+	diff := req.Amount - currentBalance
+	var movementType string
+	if diff > 0 {
+		movementType = models.MoveIngreso
+	}
+	if diff < 0 {
+		movementType = models.MoveEgreso
+		diff = -diff
+	}
+	err = recordMove(tx, moveRecord{
+		Description: req.Description,
+		Type:        movementType,
+		Origin:      models.OriginAdjustment,
+		Amount:      diff,
+		Date:        time.Now().Format("2006-01-02 15:04"),
+		AccountID:   req.AccountID,
+	})
+	if err != nil {
+		return err
+	}
+
+	before := map[string]any{"account_id": req.AccountID, "balance": currentBalance}
+	return writeAudit(tx, user, models.AuditUpdate, models.AuditAdjustment, req.AccountID, before, req)
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+// Plazo por defecto de una compra a crédito
+const defaultPayableDays = 15
+
+type PayableService struct {
+	DB *sql.DB
+}
+
+func NewPayableService(db *sql.DB) *PayableService {
+	return &PayableService{DB: db}
+}
+
+func (s *PayableService) GetAll(f models.PayableFilter) ([]models.CreditPurchase, error) {
+	where := []string{"1 = 1"}
+	args := []any{}
+
+	switch f.Status {
+	case "", "pendiente":
+		where = append(where, "cp.remaining_balance > 0")
+	case "vencida":
+		where = append(where, "cp.remaining_balance > 0", "cp.due_date < date('now', 'localtime')")
+	case "pagada":
+		where = append(where, "cp.remaining_balance = 0")
+	case "todas":
+	default:
+		return nil, ErrInvalidInput
+	}
+	if f.SupplierID > 0 {
+		where = append(where, "cp.supplier_id = ?")
+		args = append(args, f.SupplierID)
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT cp.id, cp.supplier_id, COALESCE(sp.name, ''), cp.supply_id, cp.description,
+			cp.total, cp.remaining_balance, cp.date, cp.due_date,
+			cp.remaining_balance > 0 AND cp.due_date < date('now', 'localtime')
+		FROM credit_purchases cp
+		LEFT JOIN suppliers sp ON sp.id = cp.supplier_id
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY cp.due_date ASC, cp.id ASC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.CreditPurchase{}
+	for rows.Next() {
+		var p models.CreditPurchase
+		var supplyID sql.NullInt64
+		err := rows.Scan(&p.ID, &p.SupplierID, &p.SupplierName, &supplyID, &p.Description,
+			&p.Total, &p.Remaining, &p.Date, &p.DueDate, &p.Overdue)
+		if err != nil {
+			return nil, err
+		}
+		if supplyID.Valid {
+			p.SupplyID = &supplyID.Int64
+		}
+		p.TotalPaid = p.Total - p.Remaining
+		list = append(list, p)
+	}
+
+	return list, rows.Err()
+}
+
+// BySupplier agrupa lo pendiente por proveedor, el que más se le debe primero.
+func (s *PayableService) BySupplier() ([]models.SupplierPayable, error) {
+	rows, err := s.DB.Query(`
+		SELECT cp.supplier_id, COALESCE(sp.name, ''), COUNT(*),
+			SUM(cp.remaining_balance),
+			COALESCE(SUM(CASE WHEN cp.due_date < date('now', 'localtime') THEN cp.remaining_balance END), 0),
+			MIN(cp.due_date)
+		FROM credit_purchases cp
+		LEFT JOIN suppliers sp ON sp.id = cp.supplier_id
+		WHERE cp.remaining_balance > 0
+		GROUP BY cp.supplier_id
+		ORDER BY SUM(cp.remaining_balance) DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.SupplierPayable{}
+	for rows.Next() {
+		var p models.SupplierPayable
+		if err := rows.Scan(&p.SupplierID, &p.SupplierName, &p.Purchases, &p.Remaining, &p.Overdue, &p.NextDueDate); err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+
+	return list, rows.Err()
+}
+
+// Pay abona a una compra a crédito; la plata sale de la cuenta indicada.
+func (s *PayableService) Pay(creditPurchaseID int64, amount models.Money, accountID int64) (err error) {
+	if amount <= 0 {
+		return ErrInvalidInput
+	}
+	if accountID == 0 {
+		accountID = models.CashAccountID
+	}
+	now := time.Now().Format("2006-01-02 15:04")
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	var rem models.Money
+	var supplierName string
+	err = tx.QueryRow(`
+		SELECT cp.remaining_balance, COALESCE(sp.name, '')
+		FROM credit_purchases cp
+		LEFT JOIN suppliers sp ON sp.id = cp.supplier_id
+		WHERE cp.id = ?
+	`, creditPurchaseID).Scan(&rem, &supplierName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if amount > rem {
+		return ErrInvalidInput
+	}
+
+	_, err = tx.Exec(`
+		UPDATE credit_purchases SET remaining_balance = remaining_balance - ? WHERE id = ?
+	`, amount, creditPurchaseID)
+	if err != nil {
+		return err
+	}
+
+	err = recordMove(tx, moveRecord{
+		Description: "Pago a proveedor: " + supplierName,
+		Type:        models.MoveEgreso,
+		Origin:      models.OriginPayable,
+		Amount:      amount,
+		Date:        now,
+		AccountID:   accountID,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO credit_purchase_payments (credit_purchase_id, amount, account_id, date)
+		VALUES (?, ?, ?, ?)
+	`, creditPurchaseID, amount, accountID, now)
+	return err
+}
+
+func (s *PayableService) GetPayments(creditPurchaseID int64) ([]models.PayablePayment, error) {
+	var tmpID int64
+	err := s.DB.QueryRow(`SELECT id FROM credit_purchases WHERE id = ?`, creditPurchaseID).Scan(&tmpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT id, amount, account_id, date
+		FROM credit_purchase_payments
+		WHERE credit_purchase_id = ?
+		ORDER BY date, id
+	`, creditPurchaseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.PayablePayment{}
+	for rows.Next() {
+		var p models.PayablePayment
+		if err := rows.Scan(&p.ID, &p.Amount, &p.AccountID, &p.Date); err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+
+	return list, rows.Err()
+}
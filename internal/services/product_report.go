@@ -0,0 +1,138 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+const (
+	defaultRankingSize = 5
+	maxRankingSize     = 50
+)
+
+// ProductSales resume las ventas de cada producto en el rango, contado y
+// fiado juntos, descontando lo devuelto. Las ventas de contado anteriores a
+// la migración 4 solo existen como texto en movimientos y no se cuentan.
+func (s *ReportService) ProductSales(f models.ReportFilter) (models.ProductSalesReport, error) {
+	f, err := reportRange(f)
+	if err != nil {
+		return models.ProductSalesReport{}, err
+	}
+	switch f.RankBy {
+	case "":
+		f.RankBy = models.RankRevenue
+	case models.RankRevenue, models.RankUnits, models.RankMargin:
+	default:
+		return models.ProductSalesReport{}, ErrInvalidInput
+	}
+	if f.Limit <= 0 {
+		f.Limit = defaultRankingSize
+	}
+	if f.Limit > maxRankingSize {
+		f.Limit = maxRankingSize
+	}
+
+	// Se parte de las líneas de venta para no perder productos que ya no existen
+	rows, err := s.DB.Query(`
+		-- Un producto borrado antes de poder archivarse cuenta como archivado
+		SELECT si.product_id, COALESCE(p.nombre, ''),
+			CASE WHEN p.id IS NULL THEN 1 ELSE p.archived_at IS NOT NULL END,
+			SUM(CASE WHEN s.is_credit = 0 THEN si.quantity - si.returned_quantity ELSE 0 END),
+			SUM(CASE WHEN s.is_credit = 1 THEN si.quantity - si.returned_quantity ELSE 0 END),
+			SUM((si.quantity - si.returned_quantity) * si.unit_price),
+			SUM((si.quantity - si.returned_quantity) * si.unit_cost)
+		FROM sale_items si
+		JOIN sales s ON s.id = si.sale_id
+		LEFT JOIN productos p ON p.id = si.product_id
+		WHERE date(s.date) BETWEEN date(?) AND date(?)
+		GROUP BY si.product_id
+	`, f.From, f.To)
+	if err != nil {
+		return models.ProductSalesReport{}, err
+	}
+
+	list := []models.ProductSales{}
+	seen := map[int64]bool{}
+	for rows.Next() {
+		var p models.ProductSales
+		err := rows.Scan(&p.ProductID, &p.Name, &p.Archived, &p.CashUnits, &p.CreditUnits, &p.Revenue, &p.Cost)
+		if err != nil {
+			rows.Close()
+			return models.ProductSalesReport{}, err
+		}
+		if p.Name == "" {
+			p.Name = "(producto eliminado)"
+		}
+		list = append(list, p)
+		seen[p.ProductID] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.ProductSalesReport{}, err
+	}
+
+	// Los activos que no vendieron nada también cuentan para los menos vendidos
+	rows, err = s.DB.Query(`SELECT id, nombre FROM productos WHERE archived_at IS NULL`)
+	if err != nil {
+		return models.ProductSalesReport{}, err
+	}
+	for rows.Next() {
+		var p models.ProductSales
+		if err := rows.Scan(&p.ProductID, &p.Name); err != nil {
+			rows.Close()
+			return models.ProductSalesReport{}, err
+		}
+		if !seen[p.ProductID] {
+			list = append(list, p)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.ProductSalesReport{}, err
+	}
+
+	report := models.ProductSalesReport{From: f.From, To: f.To, RankBy: f.RankBy}
+	for i := range list {
+		p := &list[i]
+		p.Units = p.CashUnits + p.CreditUnits
+		p.Margin = p.Revenue - p.Cost
+		p.MarginPct = pct(p.Margin, p.Revenue)
+
+		report.Totals.Units += p.Units
+		report.Totals.Revenue += p.Revenue
+		report.Totals.Cost += p.Cost
+	}
+	report.Totals.Margin = report.Totals.Revenue - report.Totals.Cost
+	report.Totals.MarginPct = pct(report.Totals.Margin, report.Totals.Revenue)
+
+	key := func(p models.ProductSales) int64 {
+		switch f.RankBy {
+		case models.RankUnits:
+			return p.Units
+		case models.RankMargin:
+			return int64(p.Margin)
+		default:
+			return int64(p.Revenue)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if ki, kj := key(list[i]), key(list[j]); ki != kj {
+			return ki > kj
+		}
+		return list[i].Name < list[j].Name
+	})
+	for i := range list {
+		list[i].Rank = i + 1
+	}
+	report.Products = list
+
+	n := min(f.Limit, len(list))
+	report.Best = list[:n]
+	report.Worst = make([]models.ProductSales, n)
+	for i := 0; i < n; i++ {
+		report.Worst[i] = list[len(list)-1-i]
+	}
+
+	return report, nil
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+// Produce registra una tanda: descuenta los insumos de la receta para
+// b.Quantity unidades y suma b.Yield unidades al stock del producto.
+func (s *ProductService) Produce(b *models.ProductionBatch) (err error) {
+	if b.Quantity <= 0 || b.Yield < 0 {
+		return ErrInvalidInput
+	}
+	if b.Yield == 0 {
+		b.Yield = b.Quantity
+	}
+	b.Notes = strings.TrimSpace(b.Notes)
+	b.Date = time.Now().Format("2006-01-02 15:04")
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	var tmpID int64
+	err = tx.QueryRow(`SELECT id FROM productos WHERE id = ? AND archived_at IS NULL`, b.ProductID).Scan(&tmpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`
+		SELECT i.id, i.nombre, pi.cantidad_insumo * ?
+		FROM producto_insumos pi
+		JOIN insumos i ON i.id = pi.insumo_id
+		WHERE pi.producto_id = ?
+		ORDER BY i.id
+	`, b.Quantity, b.ProductID)
+	if err != nil {
+		return err
+	}
+	b.Insumos = []models.ProductionBatchInsumo{}
+	for rows.Next() {
+		var ins models.ProductionBatchInsumo
+		if err = rows.Scan(&ins.InsumoID, &ins.Name, &ins.Quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		b.Insumos = append(b.Insumos, ins)
+	}
+	if err = rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	// Sin receta no hay nada que convertir
+	if len(b.Insumos) == 0 {
+		return ErrInvalidInput
+	}
+
+	// La tanda cuesta lo que costaron los lotes que se gastaron, que con FIFO
+	// no es el precio actual del insumo
+	b.Cost = 0
+	for i := range b.Insumos {
+		ins := &b.Insumos[i]
+		var cost models.Money
+		if cost, err = consumeInsumo(tx, ins.InsumoID, ins.Quantity); err != nil {
+			return err
+		}
+
+		if ins.Quantity > 0 {
+			ins.UnitCost = cost.MulQty(1 / ins.Quantity)
+		}
+		b.Cost += cost
+	}
+	b.UnitCost = b.Cost.MulQty(1 / float64(b.Yield))
+
+	res, err := tx.Exec(`
+		INSERT INTO production_batches (product_id, quantity, yield, cost, unit_cost, notes, created_by, date)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, b.ProductID, b.Quantity, b.Yield, b.Cost, b.UnitCost, b.Notes, b.CreatedBy, b.Date)
+	if err != nil {
+		return err
+	}
+	b.ID, _ = res.LastInsertId()
+
+	for _, ins := range b.Insumos {
+		_, err = tx.Exec(`
+			INSERT INTO production_batch_insumos (batch_id, insumo_id, quantity, unit_cost)
+			VALUES (?, ?, ?, ?)
+		`, b.ID, ins.InsumoID, ins.Quantity, ins.UnitCost)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE productos SET stock_actual = stock_actual + ? WHERE id = ?
+	`, b.Yield, b.ProductID)
+	if err != nil {
+		return err
+	}
+
+	return writeAudit(tx, b.CreatedBy, models.AuditCreate, models.AuditProduction, b.ID, nil, b)
+}
+
+// stockUnitCost es el costo por unidad de lo que sale del stock: el promedio
+// ponderado de las tandas que siguen en bodega. El stock se va gastando de lo
+// más viejo, así que lo que queda son las últimas tandas hasta completar
+// stock_actual. Si el producto nunca se fabricó por tandas queda recipeCost,
+// el costo de la receta.
+func stockUnitCost(tx *sql.Tx, productID int64, recipeCost models.Money) (models.Money, error) {
+	var stock int64
+	err := tx.QueryRow(`SELECT stock_actual FROM productos WHERE id = ?`, productID).Scan(&stock)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT yield, unit_cost FROM production_batches
+		WHERE product_id = ?
+		ORDER BY date DESC, id DESC
+	`, productID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var total models.Money
+	var units int64
+	for units < stock && rows.Next() {
+		var yield int64
+		var cost models.Money
+		if err := rows.Scan(&yield, &cost); err != nil {
+			return 0, err
+		}
+		take := min(yield, stock-units)
+		total += cost * models.Money(take)
+		units += take
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if units == 0 {
+		return recipeCost, nil
+	}
+	return total.MulQty(1 / float64(units)), nil
+}
+
+// GetBatches lista las tandas de un producto, la más reciente primero.
+func (s *ProductService) GetBatches(productID int64) ([]models.ProductionBatch, error) {
+	var tmpID int64
+	err := s.DB.QueryRow(`SELECT id FROM productos WHERE id = ?`, productID).Scan(&tmpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT id, product_id, quantity, yield, cost, unit_cost, notes, created_by, date
+		FROM production_batches
+		WHERE product_id = ?
+		ORDER BY date DESC, id DESC
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.ProductionBatch{}
+	for rows.Next() {
+		var b models.ProductionBatch
+		err := rows.Scan(&b.ID, &b.ProductID, &b.Quantity, &b.Yield, &b.Cost, &b.UnitCost, &b.Notes, &b.CreatedBy, &b.Date)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range list {
+		list[i].Insumos, err = s.batchInsumos(list[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return list, nil
+}
+
+func (s *ProductService) batchInsumos(batchID int64) ([]models.ProductionBatchInsumo, error) {
+	rows, err := s.DB.Query(`
+		SELECT bi.insumo_id, COALESCE(i.nombre, ''), bi.quantity, bi.unit_cost
+		FROM production_batch_insumos bi
+		LEFT JOIN insumos i ON i.id = bi.insumo_id
+		WHERE bi.batch_id = ?
+		ORDER BY bi.insumo_id
+	`, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.ProductionBatchInsumo{}
+	for rows.Next() {
+		var ins models.ProductionBatchInsumo
+		if err := rows.Scan(&ins.InsumoID, &ins.Name, &ins.Quantity, &ins.UnitCost); err != nil {
+			return nil, err
+		}
+		list = append(list, ins)
+	}
+
+	return list, rows.Err()
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+type productionFixture struct {
+	db       *sql.DB
+	products *ProductService
+	product  int64
+	insumo   int64
+}
+
+// newProductionFixture arma un producto que gasta 3 g de esencia por unidad.
+// La esencia es FIFO: 10 g iniciales a 10 pesos y un surtido de 10 g a 30.
+func newProductionFixture(t *testing.T) *productionFixture {
+	t.Helper()
+	database := testDB(t)
+
+	essence := models.Insumo{Name: "Esencia", Um: "g", Stock: 10, UnitPrice: 1000, CostMethod: models.CostFIFO}
+	if err := NewInsumoService(database).Create(&essence, testUser); err != nil {
+		t.Fatal(err)
+	}
+	err := NewMoveService(database).Supply(models.Supply{IdInsumo: essence.ID, Amount: 10, TotalAmount: 30000}, testUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	products := NewProductService(database)
+	perfume := models.Product{
+		Name:          "Perfume",
+		Price:         20000,
+		SellFromStock: true,
+		Insumos:       []models.ProductInsumo{{InsumoID: essence.ID, Quantity: 3}},
+	}
+	if err := products.Create(&perfume, testUser); err != nil {
+		t.Fatal(err)
+	}
+
+	return &productionFixture{db: database, products: products, product: perfume.ID, insumo: essence.ID}
+}
+
+func TestProduceCostsConsumedLots(t *testing.T) {
+	f := newProductionFixture(t)
+
+	// 5 unidades gastan 15 g: los 10 del lote viejo y 5 del nuevo
+	b := models.ProductionBatch{ProductID: f.product, Quantity: 5, CreatedBy: testUser}
+	if err := f.products.Produce(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	expectMoney(t, "costo de la tanda", b.Cost, 10*1000+5*3000)
+	expectMoney(t, "costo unitario", b.UnitCost, 5000)
+	if len(b.Insumos) != 1 {
+		t.Fatalf("%d insumos en la tanda, se esperaba 1", len(b.Insumos))
+	}
+	expectMoney(t, "costo por gramo", b.Insumos[0].UnitCost, 1667)
+
+	// Lo guardado coincide con lo que se devolvió
+	batches, err := f.products.GetBatches(f.product)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectMoney(t, "costo guardado", batches[0].Cost, b.Cost)
+	expectMoney(t, "costo por gramo guardado", batches[0].Insumos[0].UnitCost, 1667)
+
+	// La siguiente tanda ya solo tiene lotes de 30
+	b = models.ProductionBatch{ProductID: f.product, Quantity: 1, CreatedBy: testUser}
+	if err := f.products.Produce(&b); err != nil {
+		t.Fatal(err)
+	}
+	expectMoney(t, "costo de la segunda tanda", b.Cost, 3*3000)
+}
+
+func TestStockSaleCostsBatchesOnHand(t *testing.T) {
+	f := newProductionFixture(t)
+
+	// Tanda de 5 a 50 pesos (lote viejo y nuevo) y tanda de 1 a 90 (solo lote nuevo)
+	for _, qty := range []int64{5, 1} {
+		b := models.ProductionBatch{ProductID: f.product, Quantity: qty, CreatedBy: testUser}
+		if err := f.products.Produce(&b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := models.Client{Name: "Ana"}
+	if err := NewClientService(f.db).Create(&client, testUser); err != nil {
+		t.Fatal(err)
+	}
+	moves := NewMoveService(f.db)
+
+	// Cada venta se cuesta al promedio de lo que hay en bodega; lo que queda
+	// son las tandas más nuevas
+	for _, want := range []models.Money{
+		5667, // 6 en bodega: (5×50 + 90) / 6
+		5800, // 5 en bodega: la de 90 y 4 de la de 50
+		6000, // 4 en bodega: la de 90 y 3 de la de 50
+	} {
+		id, err := moves.Sell(models.Sale{
+			ClientId: client.ID,
+			Items:    []models.SaleItem{{ProductID: f.product, Quantity: 1}},
+		}, testUser)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := queryMoney(t, f.db, `SELECT unit_cost FROM sale_items WHERE sale_id = ?`, id)
+		expectMoney(t, "costo de la venta", got, want)
+	}
+}
@@ -0,0 +1,425 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+var ErrOrderClosed = errors.New("la orden ya fue recibida o cancelada")
+
+type PurchaseOrderService struct {
+	DB *sql.DB
+}
+
+func NewPurchaseOrderService(db *sql.DB) *PurchaseOrderService {
+	return &PurchaseOrderService{DB: db}
+}
+
+// GetAll lista las órdenes. status vacío trae todas; "pendiente" trae las
+// abiertas y las recibidas a medias.
+func (s *PurchaseOrderService) GetAll(status string) ([]models.PurchaseOrder, error) {
+	where := "1 = 1"
+	args := []any{}
+	switch status {
+	case "":
+	case "pendiente":
+		where = "o.status IN ('abierta', 'parcial')"
+	default:
+		where = "o.status = ?"
+		args = append(args, status)
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT o.id, o.supplier_id, COALESCE(sp.name, ''), o.status, o.notes, o.created_by, o.created_at, o.closed_at
+		FROM purchase_orders o
+		LEFT JOIN suppliers sp ON sp.id = o.supplier_id
+		WHERE `+where+`
+		ORDER BY o.created_at DESC, o.id DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.PurchaseOrder{}
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range list {
+		if err := s.loadItems(&list[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return list, nil
+}
+
+func (s *PurchaseOrderService) GetById(id int64) (models.PurchaseOrder, error) {
+	row := s.DB.QueryRow(`
+		SELECT o.id, o.supplier_id, COALESCE(sp.name, ''), o.status, o.notes, o.created_by, o.created_at, o.closed_at
+		FROM purchase_orders o
+		LEFT JOIN suppliers sp ON sp.id = o.supplier_id
+		WHERE o.id = ?
+	`, id)
+
+	o, err := scanOrder(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.PurchaseOrder{}, ErrNotFound
+	}
+	if err != nil {
+		return models.PurchaseOrder{}, err
+	}
+
+	err = s.loadItems(&o)
+	return o, err
+}
+
+func scanOrder(r rowScanner) (models.PurchaseOrder, error) {
+	var o models.PurchaseOrder
+	var supplierID sql.NullInt64
+	var closedAt sql.NullString
+
+	err := r.Scan(&o.ID, &supplierID, &o.SupplierName, &o.Status, &o.Notes, &o.CreatedBy, &o.CreatedAt, &closedAt)
+	if err != nil {
+		return o, err
+	}
+	if supplierID.Valid {
+		o.SupplierID = &supplierID.Int64
+	}
+	if closedAt.Valid {
+		o.ClosedAt = &closedAt.String
+	}
+	return o, nil
+}
+
+func (s *PurchaseOrderService) loadItems(o *models.PurchaseOrder) error {
+	rows, err := s.DB.Query(`
+		SELECT oi.id, oi.insumo_id, COALESCE(i.nombre, ''), COALESCE(i.unidad_medida, ''),
+			oi.quantity, oi.unit_price, oi.received
+		FROM purchase_order_items oi
+		LEFT JOIN insumos i ON i.id = oi.insumo_id
+		WHERE oi.order_id = ?
+		ORDER BY oi.id
+	`, o.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	o.Items = []models.PurchaseOrderItem{}
+	o.Total = 0
+	for rows.Next() {
+		var it models.PurchaseOrderItem
+		if err := rows.Scan(&it.ID, &it.InsumoID, &it.InsumoName, &it.Um, &it.Quantity, &it.UnitPrice, &it.Received); err != nil {
+			return err
+		}
+		if o.Status != models.OrderCancelled {
+			it.Pending = max(it.Quantity-it.Received, 0)
+		}
+		o.Total += it.UnitPrice.MulQty(it.Quantity)
+		o.Items = append(o.Items, it)
+	}
+
+	return rows.Err()
+}
+
+// Create guarda la orden. Un unit_price en 0 toma el precio actual del insumo.
+func (s *PurchaseOrderService) Create(o *models.PurchaseOrder) (err error) {
+	if len(o.Items) == 0 {
+		return ErrInvalidInput
+	}
+	seen := map[int64]bool{}
+	for _, it := range o.Items {
+		if it.Quantity <= 0 || it.UnitPrice < 0 || seen[it.InsumoID] {
+			return ErrInvalidInput
+		}
+		seen[it.InsumoID] = true
+	}
+
+	o.Status = models.OrderOpen
+	o.Notes = strings.TrimSpace(o.Notes)
+	o.CreatedAt = time.Now().Format("2006-01-02 15:04")
+	o.ClosedAt = nil
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	o.SupplierName = ""
+	if o.SupplierID != nil && *o.SupplierID > 0 {
+		err = tx.QueryRow(`SELECT name FROM suppliers WHERE id = ?`, *o.SupplierID).Scan(&o.SupplierName)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSupplierNotFound
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		o.SupplierID = nil
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO purchase_orders (supplier_id, status, notes, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, o.SupplierID, o.Status, o.Notes, o.CreatedBy, o.CreatedAt)
+	if err != nil {
+		return err
+	}
+	o.ID, _ = res.LastInsertId()
+
+	o.Total = 0
+	for i := range o.Items {
+		it := &o.Items[i]
+
+		var price models.Money
+		err = tx.QueryRow(`
+			SELECT nombre, unidad_medida, precio_unitario FROM insumos WHERE id = ? AND archived_at IS NULL
+		`, it.InsumoID).Scan(&it.InsumoName, &it.Um, &price)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if it.UnitPrice == 0 {
+			it.UnitPrice = price
+		}
+
+		res, err := tx.Exec(`
+			INSERT INTO purchase_order_items (order_id, insumo_id, quantity, unit_price)
+			VALUES (?, ?, ?, ?)
+		`, o.ID, it.InsumoID, it.Quantity, it.UnitPrice)
+		if err != nil {
+			return err
+		}
+		it.ID, _ = res.LastInsertId()
+		it.Received = 0
+		it.Pending = it.Quantity
+		o.Total += it.UnitPrice.MulQty(it.Quantity)
+	}
+
+	return writeAudit(tx, o.CreatedBy, models.AuditCreate, models.AuditOrder, o.ID, nil, o)
+}
+
+// Receive registra lo que llegó. Cada línea pasa por el mismo surtido de
+// siempre (stock, lote, precio y egreso de la cuenta) y queda auditada como
+// un surtido más.
+func (s *PurchaseOrderService) Receive(id int64, rc models.PurchaseReceipt, user string) (err error) {
+	if len(rc.Items) == 0 {
+		return ErrInvalidInput
+	}
+	now := time.Now().Format("2006-01-02 15:04")
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	var status string
+	var supplierID sql.NullInt64
+	err = tx.QueryRow(`
+		SELECT status, supplier_id FROM purchase_orders WHERE id = ?
+	`, id).Scan(&status, &supplierID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status == models.OrderReceived || status == models.OrderCancelled {
+		return ErrOrderClosed
+	}
+
+	for _, ri := range rc.Items {
+		if ri.Quantity <= 0 || ri.TotalAmount < 0 {
+			return ErrInvalidInput
+		}
+
+		var insumoID int64
+		var pending float64
+		var price models.Money
+		err = tx.QueryRow(`
+			SELECT insumo_id, quantity - received, unit_price
+			FROM purchase_order_items WHERE id = ? AND order_id = ?
+		`, ri.ItemID, id).Scan(&insumoID, &pending, &price)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidInput
+		}
+		if err != nil {
+			return err
+		}
+		if ri.Quantity > pending+lotEpsilon {
+			return ErrInvalidInput
+		}
+
+		total := ri.TotalAmount
+		if total == 0 {
+			total = price.MulQty(ri.Quantity)
+		}
+
+		supply := models.Supply{
+			IdInsumo:    insumoID,
+			Amount:      ri.Quantity,
+			TotalAmount: total,
+			Date:        now,
+			AccountID:   rc.AccountID,
+			SupplierID:  supplierID.Int64,
+			OrderItemID: ri.ItemID,
+			IsCredit:    rc.IsCredit,
+			DueDate:     rc.DueDate,
+		}
+		lotID, err := supplyTx(tx, supply)
+		if err != nil {
+			return err
+		}
+		if err = writeAudit(tx, user, models.AuditCreate, models.AuditSupply, lotID, nil, supply); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			UPDATE purchase_order_items SET received = received + ? WHERE id = ?
+		`, ri.Quantity, ri.ItemID)
+		if err != nil {
+			return err
+		}
+	}
+
+	var open int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM purchase_order_items
+		WHERE order_id = ? AND quantity - received > ?
+	`, id, lotEpsilon).Scan(&open)
+	if err != nil {
+		return err
+	}
+
+	if open == 0 {
+		_, err = tx.Exec(`
+			UPDATE purchase_orders SET status = ?, closed_at = ? WHERE id = ?
+		`, models.OrderReceived, now, id)
+	} else {
+		_, err = tx.Exec(`UPDATE purchase_orders SET status = ? WHERE id = ?`, models.OrderPartial, id)
+	}
+	return err
+}
+
+// Cancel cierra una orden pendiente; lo que ya se recibió se queda.
+func (s *PurchaseOrderService) Cancel(id int64, user string) (err error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	var status string
+	err = tx.QueryRow(`SELECT status FROM purchase_orders WHERE id = ?`, id).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status != models.OrderOpen && status != models.OrderPartial {
+		return ErrOrderClosed
+	}
+
+	now := time.Now().Format("2006-01-02 15:04")
+	_, err = tx.Exec(`
+		UPDATE purchase_orders SET status = ?, closed_at = ? WHERE id = ?
+	`, models.OrderCancelled, now, id)
+	if err != nil {
+		return err
+	}
+
+	return writeAudit(tx, user, models.AuditUpdate, models.AuditOrder, id,
+		map[string]any{"status": status},
+		map[string]any{"status": models.OrderCancelled, "closed_at": now})
+}
+
+// Pending suma por insumo lo que falta por llegar en las órdenes abiertas.
+func (s *PurchaseOrderService) Pending() ([]models.PendingInsumo, error) {
+	rows, err := s.DB.Query(`
+		SELECT oi.insumo_id, COALESCE(i.nombre, ''), COALESCE(i.unidad_medida, ''),
+			SUM(oi.quantity - oi.received), GROUP_CONCAT(DISTINCT oi.order_id)
+		FROM purchase_order_items oi
+		JOIN purchase_orders o ON o.id = oi.order_id
+		LEFT JOIN insumos i ON i.id = oi.insumo_id
+		WHERE o.status IN ('abierta', 'parcial') AND oi.quantity - oi.received > ?
+		GROUP BY oi.insumo_id
+		ORDER BY i.nombre
+	`, lotEpsilon)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.PendingInsumo{}
+	for rows.Next() {
+		var p models.PendingInsumo
+		var ids string
+		if err := rows.Scan(&p.InsumoID, &p.InsumoName, &p.Um, &p.Pending, &ids); err != nil {
+			return nil, err
+		}
+		p.OrderIDs = []int64{}
+		for _, v := range strings.Split(ids, ",") {
+			if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+				p.OrderIDs = append(p.OrderIDs, id)
+			}
+		}
+		list = append(list, p)
+	}
+
+	return list, rows.Err()
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+// ReconcileService compara los saldos que se mantienen con UPDATEs
+// incrementales contra lo que dicen sus tablas de origen:
+//
+//   - clientes.deuda contra la suma de credit_sales.remaining_balance
+//   - credit_sales.remaining_balance contra total - abonos + reembolsos
+//   - accounts.saldo contra la suma de sus movimientos, sin las correcciones
+//     que dejó Repair
+//
+// Repair corrige clientes y cuentas. Un fiado descuadrado solo se reporta:
+// los abonos viejos no siempre quedaron en credit_payments, así que
+// remaining_balance puede ser el dato bueno.
+type ReconcileService struct {
+	DB *sql.DB
+}
+
+func NewReconcileService(db *sql.DB) *ReconcileService {
+	return &ReconcileService{DB: db}
+}
+
+func (s *ReconcileService) Check() (models.ReconcileReport, error) {
+	report := models.ReconcileReport{RunAt: time.Now().Format("2006-01-02 15:04")}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return report, err
+	}
+	defer tx.Rollback()
+
+	report.Drifts, err = findDrifts(tx)
+	return report, err
+}
+
+// Repair deja deuda y saldos como los calculados y registra cada cambio en
+// reconcile_log y en la bitácora con el usuario que lo pidió. La corrección de
+// una cuenta queda como movimiento de conciliación, para que el historial y
+// la sesión de caja sumen el saldo nuevo.
+func (s *ReconcileService) Repair(actor string) (report models.ReconcileReport, err error) {
+	report = models.ReconcileReport{RunAt: time.Now().Format("2006-01-02 15:04"), Repaired: true}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return report, err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	report.Drifts, err = findDrifts(tx)
+	if err != nil {
+		return report, err
+	}
+
+	for _, d := range report.Drifts {
+		switch d.Entity {
+		case models.DriftClient:
+			if _, err = tx.Exec(`UPDATE clientes SET deuda = ? WHERE id = ?`, d.Computed, d.EntityID); err != nil {
+				return report, err
+			}
+			err = writeAudit(tx, actor, models.AuditUpdate, models.AuditClient, d.EntityID,
+				map[string]any{"deuda": d.Stored}, map[string]any{"deuda": d.Computed})
+		case models.DriftAccount:
+			tipo, amount := models.MoveIngreso, d.Computed-d.Stored
+			if amount < 0 {
+				tipo, amount = models.MoveEgreso, -amount
+			}
+			err = recordMove(tx, moveRecord{
+				Description: "Conciliación de saldo de " + d.Name,
+				Type:        tipo,
+				Origin:      models.OriginReconcile,
+				Amount:      amount,
+				Date:        report.RunAt,
+				AccountID:   d.EntityID,
+			})
+			if err != nil {
+				return report, err
+			}
+			err = writeAudit(tx, actor, models.AuditUpdate, models.AuditAdjustment, d.EntityID,
+				map[string]any{"account_id": d.EntityID, "balance": d.Stored},
+				map[string]any{"account_id": d.EntityID, "balance": d.Computed})
+		default:
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO reconcile_log (date, actor, entity, entity_id, before, after)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, report.RunAt, actor, d.Entity, d.EntityID, d.Stored, d.Computed)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+func (s *ReconcileService) GetLog() ([]models.ReconcileEntry, error) {
+	rows, err := s.DB.Query(`
+		SELECT id, date, actor, entity, entity_id, before, after
+		FROM reconcile_log
+		ORDER BY id DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.ReconcileEntry{}
+	for rows.Next() {
+		var e models.ReconcileEntry
+		if err := rows.Scan(&e.ID, &e.Date, &e.Actor, &e.Entity, &e.EntityID, &e.Before, &e.After); err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+func findDrifts(tx *sql.Tx) ([]models.Drift, error) {
+	drifts := []models.Drift{}
+
+	checks := []struct {
+		entity  string
+		fixable bool
+		query   string
+	}{
+		{models.DriftClient, true, `
+			SELECT id, nombre, deuda, computed FROM (
+				SELECT c.id, c.nombre, c.deuda,
+					COALESCE((SELECT SUM(remaining_balance) FROM credit_sales WHERE client_id = c.id), 0) AS computed
+				FROM clientes c
+			) WHERE deuda <> computed
+			ORDER BY id`},
+		{models.DriftCreditSale, false, `
+			SELECT id, nombre, remaining_balance, computed FROM (
+				SELECT cs.id, c.nombre, cs.remaining_balance,
+					cs.total
+					- COALESCE((SELECT SUM(amount) FROM credit_payments WHERE credit_sale_id = cs.id), 0)
+					+ COALESCE((
+						SELECT SUM(sr.refunded + sr.credited) FROM sale_returns sr
+						JOIN sales sa ON sa.id = sr.sale_id
+						WHERE sa.credit_sale_id = cs.id
+					), 0) AS computed
+				FROM credit_sales cs
+				JOIN clientes c ON c.id = cs.client_id
+			) WHERE remaining_balance <> computed
+			ORDER BY id`},
+		{models.DriftAccount, true, `
+			SELECT id, name, saldo, computed FROM (
+				SELECT a.id, a.name, a.saldo,
+					COALESCE((
+						SELECT SUM(CASE tipo WHEN 'egreso' THEN -monto ELSE monto END)
+						FROM movimientos
+						WHERE COALESCE(cuenta_id, 1) = a.id AND tipo IN ('ingreso', 'egreso', 'transferencia')
+							AND origen <> 'conciliacion'
+					), 0) AS computed
+				FROM accounts a
+			) WHERE saldo <> computed
+			ORDER BY id`},
+	}
+
+	for _, c := range checks {
+		rows, err := tx.Query(c.query)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			d := models.Drift{Entity: c.entity, Fixable: c.fixable}
+			if err := rows.Scan(&d.EntityID, &d.Name, &d.Stored, &d.Computed); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			d.Diff = d.Stored - d.Computed
+			drifts = append(drifts, d)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return drifts, nil
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+func TestRepairAuditsAndRecordsMove(t *testing.T) {
+	database := testDB(t)
+	reconcile := NewReconcileService(database)
+
+	// Caja con 500 de más sin movimiento y un cliente con deuda sin fiados
+	mustExec(t, database, `INSERT INTO clientes (id, nombre, deuda) VALUES (1, 'Ana', 30000)`)
+	mustExec(t, database, `UPDATE accounts SET saldo = 50000 WHERE id = ?`, models.CashAccountID)
+
+	report, err := reconcile.Repair(testUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Drifts) != 2 {
+		t.Fatalf("%d descuadres, se esperaban 2: %+v", len(report.Drifts), report.Drifts)
+	}
+
+	expectMoney(t, "deuda", queryMoney(t, database, `SELECT deuda FROM clientes WHERE id = 1`), 0)
+	expectMoney(t, "saldo de caja",
+		queryMoney(t, database, `SELECT saldo FROM accounts WHERE id = ?`, models.CashAccountID), 0)
+
+	// La corrección de la caja queda en su historial como egreso
+	expectMoney(t, "movimiento de conciliación", queryMoney(t, database, `
+		SELECT monto FROM movimientos WHERE origen = ? AND tipo = ?
+	`, models.OriginReconcile, models.MoveEgreso), 50000)
+
+	for _, entity := range []string{models.AuditClient, models.AuditAdjustment} {
+		n := countRows(t, database, `SELECT COUNT(*) FROM audit_log WHERE entity = ? AND actor = ?`, entity, testUser)
+		if n != 1 {
+			t.Errorf("%d registros de %s en la bitácora, se esperaba 1", n, entity)
+		}
+	}
+
+	// Ya cuadra: la conciliación no cuenta como descuadre nuevo
+	again, err := reconcile.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Drifts) != 0 {
+		t.Errorf("después de reparar quedan descuadres: %+v", again.Drifts)
+	}
+}
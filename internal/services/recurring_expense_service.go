@@ -0,0 +1,504 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+// Usuario con el que quedan los gastos que registra el programador
+const schedulerActor = "programador"
+
+type RecurringExpenseService struct {
+	DB *sql.DB
+}
+
+func NewRecurringExpenseService(db *sql.DB) *RecurringExpenseService {
+	return &RecurringExpenseService{DB: db}
+}
+
+const recurringColumns = `
+	r.id, r.category_id, c.name, r.account_id, r.amount, r.notes, r.frequency,
+	r.next_date, r.requires_confirmation, r.active, r.created_by`
+
+func scanRecurring(row rowScanner) (models.RecurringExpense, error) {
+	var r models.RecurringExpense
+	err := row.Scan(&r.ID, &r.CategoryID, &r.CategoryName, &r.AccountID, &r.Amount, &r.Notes, &r.Frequency,
+		&r.NextDate, &r.RequiresConfirmation, &r.Active, &r.CreatedBy)
+	return r, err
+}
+
+func (s *RecurringExpenseService) GetAll() ([]models.RecurringExpense, error) {
+	rows, err := s.DB.Query(`
+		SELECT ` + recurringColumns + `
+		FROM recurring_expenses r
+		JOIN expense_categories c ON c.id = r.category_id
+		ORDER BY r.active DESC, r.next_date ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.RecurringExpense{}
+	for rows.Next() {
+		r, err := scanRecurring(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+func (s *RecurringExpenseService) GetById(id int64) (models.RecurringExpense, error) {
+	return recurringByID(s.DB, id)
+}
+
+// recurringByID lee la plantilla con q, que puede ser la transacción en curso.
+func recurringByID(q Queryer, id int64) (models.RecurringExpense, error) {
+	r, err := scanRecurring(q.QueryRow(`
+		SELECT `+recurringColumns+`
+		FROM recurring_expenses r
+		JOIN expense_categories c ON c.id = r.category_id
+		WHERE r.id = ?
+	`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return r, ErrNotFound
+	}
+	return r, err
+}
+
+// validate revisa la plantilla y devuelve el día del mes de NextDate.
+// Una fecha en el pasado solo se acepta si no cambió (prev).
+func (s *RecurringExpenseService) validate(r *models.RecurringExpense, prev string) (int, error) {
+	if r.Amount <= 0 || r.CategoryID <= 0 {
+		return 0, ErrInvalidInput
+	}
+	if r.Frequency != models.FrequencyWeekly && r.Frequency != models.FrequencyMonthly {
+		return 0, ErrInvalidInput
+	}
+	next, err := time.Parse("2006-01-02", r.NextDate)
+	if err != nil {
+		return 0, ErrInvalidInput
+	}
+	if r.NextDate != prev && r.NextDate < time.Now().Format("2006-01-02") {
+		return 0, ErrInvalidInput
+	}
+	if r.AccountID == 0 {
+		r.AccountID = models.CashAccountID
+	}
+
+	var n int
+	err = s.DB.QueryRow(`SELECT COUNT(*) FROM expense_categories WHERE id = ?`, r.CategoryID).Scan(&n)
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, ErrNotFound
+	}
+	err = s.DB.QueryRow(`SELECT COUNT(*) FROM accounts WHERE id = ?`, r.AccountID).Scan(&n)
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, ErrAccountNotFound
+	}
+
+	return next.Day(), nil
+}
+
+func (s *RecurringExpenseService) Create(r *models.RecurringExpense, user string) (err error) {
+	day, err := s.validate(r, "")
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	res, err := tx.Exec(`
+		INSERT INTO recurring_expenses
+			(category_id, account_id, amount, notes, frequency, day, next_date, requires_confirmation, active, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1, ?)
+	`, r.CategoryID, r.AccountID, r.Amount, r.Notes, r.Frequency, day, r.NextDate, r.RequiresConfirmation, user)
+	if err != nil {
+		return err
+	}
+	id, _ := res.LastInsertId()
+
+	created, err := recurringByID(tx, id)
+	if err != nil {
+		return err
+	}
+	*r = created
+	return writeAudit(tx, user, models.AuditCreate, models.AuditRecurring, id, nil, created)
+}
+
+// Update reemplaza la plantilla. Con active = false deja de programarse; lo
+// que ya quedó pendiente sigue ahí hasta que se confirme o descarte.
+func (s *RecurringExpenseService) Update(r *models.RecurringExpense, user string) (err error) {
+	before, err := s.GetById(r.ID)
+	if err != nil {
+		return err
+	}
+
+	day, err := s.validate(r, before.NextDate)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	_, err = tx.Exec(`
+		UPDATE recurring_expenses
+		SET category_id = ?, account_id = ?, amount = ?, notes = ?, frequency = ?, day = ?,
+			next_date = ?, requires_confirmation = ?, active = ?
+		WHERE id = ?
+	`, r.CategoryID, r.AccountID, r.Amount, r.Notes, r.Frequency, day,
+		r.NextDate, r.RequiresConfirmation, r.Active, r.ID)
+	if err != nil {
+		return err
+	}
+
+	updated, err := recurringByID(tx, r.ID)
+	if err != nil {
+		return err
+	}
+	*r = updated
+	return writeAudit(tx, user, models.AuditUpdate, models.AuditRecurring, r.ID, before, updated)
+}
+
+// nextDue calcula la fecha siguiente. Los mensuales vuelven a caer en day,
+// o en el último día si el mes es más corto.
+func nextDue(date, frequency string, day int) (string, error) {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return "", err
+	}
+	if frequency == models.FrequencyWeekly {
+		return t.AddDate(0, 0, 7).Format("2006-01-02"), nil
+	}
+
+	first := time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	if day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC).Format("2006-01-02"), nil
+}
+
+// PostDue procesa todas las fechas vencidas hasta now, incluidas las que se
+// pasaron con el servidor apagado. Devuelve cuántas fechas procesó.
+func (s *RecurringExpenseService) PostDue(now time.Time) (int, error) {
+	today := now.Format("2006-01-02")
+
+	rows, err := s.DB.Query(`
+		SELECT id FROM recurring_expenses
+		WHERE active = 1 AND next_date <= ?
+		ORDER BY next_date
+	`, today)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// Una plantilla con problemas no frena a las demás
+	total := 0
+	var errs []error
+	for _, id := range ids {
+		n, err := s.postRecurring(id, today)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("gasto recurrente %d: %w", id, err))
+			continue
+		}
+		total += n
+	}
+	return total, errors.Join(errs...)
+}
+
+func (s *RecurringExpenseService) postRecurring(id int64, today string) (n int, err error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	var r models.RecurringExpense
+	var day int
+	err = tx.QueryRow(`
+		SELECT category_id, account_id, amount, notes, frequency, day, next_date, requires_confirmation
+		FROM recurring_expenses WHERE id = ? AND active = 1
+	`, id).Scan(&r.CategoryID, &r.AccountID, &r.Amount, &r.Notes, &r.Frequency, &day, &r.NextDate, &r.RequiresConfirmation)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	for r.NextDate <= today {
+		var exists int
+		err = tx.QueryRow(`
+			SELECT COUNT(*) FROM scheduled_expenses WHERE recurring_id = ? AND due_date = ?
+		`, id, r.NextDate).Scan(&exists)
+		if err != nil {
+			return n, err
+		}
+
+		if exists == 0 {
+			if r.RequiresConfirmation {
+				_, err = tx.Exec(`
+					INSERT INTO scheduled_expenses (recurring_id, due_date, amount, status)
+					VALUES (?, ?, ?, ?)
+				`, id, r.NextDate, r.Amount, models.ScheduledPending)
+			} else {
+				_, err = postScheduled(tx, r, id, r.NextDate, schedulerActor)
+			}
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+
+		r.NextDate, err = nextDue(r.NextDate, r.Frequency, day)
+		if err != nil {
+			return n, err
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE recurring_expenses SET next_date = ? WHERE id = ?`, r.NextDate, id)
+	return n, err
+}
+
+// postScheduled registra el gasto de una fecha y la deja como registrada.
+// Devuelve el id del gasto creado.
+func postScheduled(tx *sql.Tx, r models.RecurringExpense, recurringID int64, due, user string) (int64, error) {
+	e := models.Expense{
+		CategoryID: r.CategoryID,
+		AccountID:  r.AccountID,
+		Amount:     r.Amount,
+		Notes:      r.Notes + " (recurrente " + due + ")",
+	}
+	if err := insertExpense(tx, &e, user); err != nil {
+		return 0, err
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO scheduled_expenses (recurring_id, due_date, amount, status, expense_id, resolved_at, resolved_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (recurring_id, due_date) DO UPDATE SET
+			amount = excluded.amount, status = excluded.status, expense_id = excluded.expense_id,
+			resolved_at = excluded.resolved_at, resolved_by = excluded.resolved_by
+	`, recurringID, due, r.Amount, models.ScheduledPosted, e.ID, e.Date, user)
+	return e.ID, err
+}
+
+// GetScheduled lista las fechas programadas; status vacío = pendientes, "todos" = todas.
+func (s *RecurringExpenseService) GetScheduled(status string) ([]models.ScheduledExpense, error) {
+	switch status {
+	case "":
+		status = models.ScheduledPending
+	case "todos", models.ScheduledPending, models.ScheduledPosted, models.ScheduledDiscarded:
+	default:
+		return nil, ErrInvalidInput
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT se.id, se.recurring_id, r.category_id, c.name, r.account_id, r.notes,
+			se.due_date, se.amount, se.status, se.expense_id, se.resolved_at, se.resolved_by
+		FROM scheduled_expenses se
+		JOIN recurring_expenses r ON r.id = se.recurring_id
+		JOIN expense_categories c ON c.id = r.category_id
+		WHERE ? = 'todos' OR se.status = ?
+		ORDER BY se.due_date ASC, se.id ASC
+	`, status, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.ScheduledExpense{}
+	for rows.Next() {
+		var se models.ScheduledExpense
+		err := rows.Scan(&se.ID, &se.RecurringID, &se.CategoryID, &se.CategoryName, &se.AccountID, &se.Notes,
+			&se.DueDate, &se.Amount, &se.Status, &se.ExpenseID, &se.ResolvedAt, &se.ResolvedBy)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, se)
+	}
+	return list, rows.Err()
+}
+
+// Confirm registra un gasto pendiente. amount > 0 reemplaza el de la
+// plantilla (servicios que cambian cada mes).
+func (s *RecurringExpenseService) Confirm(id int64, amount models.Money, user string) (expenseID int64, err error) {
+	if amount < 0 {
+		return 0, ErrInvalidInput
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	var r models.RecurringExpense
+	var recurringID int64
+	var due, status string
+	err = tx.QueryRow(`
+		SELECT se.recurring_id, se.due_date, se.amount, se.status, r.category_id, r.account_id, r.notes
+		FROM scheduled_expenses se
+		JOIN recurring_expenses r ON r.id = se.recurring_id
+		WHERE se.id = ?
+	`, id).Scan(&recurringID, &due, &r.Amount, &status, &r.CategoryID, &r.AccountID, &r.Notes)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if status != models.ScheduledPending {
+		return 0, ErrAlreadyResolved
+	}
+	if amount > 0 {
+		r.Amount = amount
+	}
+
+	return postScheduled(tx, r, recurringID, due, user)
+}
+
+// Discard deja una fecha pendiente sin registrar; no mueve dinero.
+func (s *RecurringExpenseService) Discard(id int64, user string) (err error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	var recurringID int64
+	var due, status string
+	var amount models.Money
+	err = tx.QueryRow(`
+		SELECT recurring_id, due_date, amount, status FROM scheduled_expenses WHERE id = ?
+	`, id).Scan(&recurringID, &due, &amount, &status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status != models.ScheduledPending {
+		return ErrAlreadyResolved
+	}
+
+	_, err = tx.Exec(`
+		UPDATE scheduled_expenses SET status = ?, resolved_at = ?, resolved_by = ?
+		WHERE id = ?
+	`, models.ScheduledDiscarded, time.Now().Format("2006-01-02 15:04"), user, id)
+	if err != nil {
+		return err
+	}
+
+	before := map[string]any{"scheduled_id": id, "due_date": due, "amount": amount, "status": status}
+	after := map[string]any{"scheduled_id": id, "due_date": due, "amount": amount, "status": models.ScheduledDiscarded}
+	return writeAudit(tx, user, models.AuditUpdate, models.AuditRecurring, recurringID, before, after)
+}
+
+// RunScheduler procesa los gastos recurrentes al arrancar y después cada
+// interval. Se ejecuta en su propia goroutine mientras viva el servidor.
+func (s *RecurringExpenseService) RunScheduler(interval time.Duration) {
+	for {
+		n, err := s.PostDue(time.Now())
+		if err != nil {
+			log.Printf("Error procesando gastos recurrentes: %v", err)
+		} else if n > 0 {
+			log.Printf("Gastos recurrentes: %d fechas procesadas", n)
+		}
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+func TestNextDue(t *testing.T) {
+	tests := []struct {
+		date      string
+		frequency string
+		day       int
+		want      string
+	}{
+		// Los mensuales del 31 caen en el último día de los meses cortos y
+		// vuelven al 31 cuando se puede
+		{"2025-01-31", models.FrequencyMonthly, 31, "2025-02-28"},
+		{"2025-02-28", models.FrequencyMonthly, 31, "2025-03-31"},
+		{"2025-03-31", models.FrequencyMonthly, 31, "2025-04-30"},
+		{"2024-01-31", models.FrequencyMonthly, 31, "2024-02-29"}, // bisiesto
+		{"2025-12-31", models.FrequencyMonthly, 31, "2026-01-31"},
+		{"2025-02-28", models.FrequencyMonthly, 30, "2025-03-30"},
+		{"2025-01-15", models.FrequencyMonthly, 15, "2025-02-15"},
+
+		// Los semanales suman 7 días, también entre meses y años
+		{"2025-01-27", models.FrequencyWeekly, 27, "2025-02-03"},
+		{"2025-12-29", models.FrequencyWeekly, 29, "2026-01-05"},
+		{"2024-02-26", models.FrequencyWeekly, 26, "2024-03-04"},
+	}
+
+	for _, tt := range tests {
+		got, err := nextDue(tt.date, tt.frequency, tt.day)
+		if err != nil {
+			t.Errorf("nextDue(%s, %s, %d): %v", tt.date, tt.frequency, tt.day, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("nextDue(%s, %s, %d) = %s, se esperaba %s", tt.date, tt.frequency, tt.day, got, tt.want)
+		}
+	}
+
+	if _, err := nextDue("31/01/2025", models.FrequencyMonthly, 31); err == nil {
+		t.Error("nextDue con fecha inválida: se esperaba error")
+	}
+}
+
+// addRecurring guarda una plantilla directo en la base, porque Create no
+// acepta fechas en el pasado.
+func addRecurring(t *testing.T, database *sql.DB, frequency, next string, day int, amount models.Money, confirm bool) int64 {
+	t.Helper()
+	res, err := database.Exec(`
+		INSERT INTO recurring_expenses (category_id, account_id, amount, frequency, day, next_date, requires_confirmation)
+		VALUES (1, 1, ?, ?, ?, ?, ?)
+	`, amount, frequency, day, next, confirm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := res.LastInsertId()
+	return id
+}
+
+func countRows(t *testing.T, database *sql.DB, query string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := database.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+func nextDate(t *testing.T, database *sql.DB, id int64) string {
+	t.Helper()
+	var d string
+	if err := database.QueryRow(`SELECT next_date FROM recurring_expenses WHERE id = ?`, id).Scan(&d); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestPostDueCatchesUpAndSkipsDuplicates(t *testing.T) {
+	database := testDB(t)
+	recurring := NewRecurringExpenseService(database)
+
+	// Arriendo del 31 que se registra solo; internet semanal que pide confirmación
+	rent := addRecurring(t, database, models.FrequencyMonthly, "2025-01-31", 31, 50000, false)
+	internet := addRecurring(t, database, models.FrequencyWeekly, "2025-03-31", 31, 10000, true)
+	// Una inactiva no se toca aunque esté vencida
+	paused := addRecurring(t, database, models.FrequencyMonthly, "2025-01-01", 1, 99900, false)
+	mustExec(t, database, `UPDATE recurring_expenses SET active = 0 WHERE id = ?`, paused)
+
+	// El servidor estuvo apagado desde enero hasta el 15 de abril
+	now := time.Date(2025, 4, 15, 9, 0, 0, 0, time.Local)
+	n, err := recurring.PostDue(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Arriendo: 31/01, 28/02, 31/03. Internet: 31/03, 07/04, 14/04
+	if n != 6 {
+		t.Errorf("se procesaron %d fechas, se esperaban 6", n)
+	}
+
+	for _, tt := range []struct {
+		id     int64
+		status string
+		dates  []string
+	}{
+		{rent, models.ScheduledPosted, []string{"2025-01-31", "2025-02-28", "2025-03-31"}},
+		{internet, models.ScheduledPending, []string{"2025-03-31", "2025-04-07", "2025-04-14"}},
+	} {
+		rows, err := database.Query(`
+			SELECT due_date FROM scheduled_expenses WHERE recurring_id = ? AND status = ? ORDER BY due_date
+		`, tt.id, tt.status)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for rows.Next() {
+			var d string
+			if err := rows.Scan(&d); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, d)
+		}
+		rows.Close()
+		if len(got) != len(tt.dates) {
+			t.Fatalf("plantilla %d: fechas %v, se esperaban %v", tt.id, got, tt.dates)
+		}
+		for i := range got {
+			if got[i] != tt.dates[i] {
+				t.Errorf("plantilla %d: fechas %v, se esperaban %v", tt.id, got, tt.dates)
+				break
+			}
+		}
+	}
+
+	if d := nextDate(t, database, rent); d != "2025-04-30" {
+		t.Errorf("próxima fecha del arriendo %s, se esperaba 2025-04-30", d)
+	}
+	if d := nextDate(t, database, internet); d != "2025-04-21" {
+		t.Errorf("próxima fecha del internet %s, se esperaba 2025-04-21", d)
+	}
+	if d := nextDate(t, database, paused); d != "2025-01-01" {
+		t.Errorf("la inactiva cambió a %s", d)
+	}
+
+	// Solo el arriendo mueve dinero; lo que pide confirmación queda pendiente
+	if got := countRows(t, database, `SELECT COUNT(*) FROM expenses`); got != 3 {
+		t.Errorf("%d gastos, se esperaban 3", got)
+	}
+	expectMoney(t, "saldo de caja",
+		queryMoney(t, database, `SELECT saldo FROM accounts WHERE id = ?`, models.CashAccountID), -150000)
+
+	// Correr otra vez el mismo día no repite nada
+	n, err = recurring.PostDue(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("la segunda corrida procesó %d fechas", n)
+	}
+
+	// Aunque la plantilla vuelva a una fecha ya programada, no se duplica
+	mustExec(t, database, `UPDATE recurring_expenses SET next_date = '2025-03-31' WHERE id IN (?, ?)`, rent, internet)
+	n, err = recurring.PostDue(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("con fechas repetidas se procesaron %d", n)
+	}
+	if got := countRows(t, database, `SELECT COUNT(*) FROM scheduled_expenses`); got != 6 {
+		t.Errorf("%d fechas programadas, se esperaban 6", got)
+	}
+	if got := countRows(t, database, `SELECT COUNT(*) FROM expenses`); got != 3 {
+		t.Errorf("%d gastos, se esperaban 3", got)
+	}
+	expectMoney(t, "saldo de caja",
+		queryMoney(t, database, `SELECT saldo FROM accounts WHERE id = ?`, models.CashAccountID), -150000)
+}
+
+func TestConfirmAndDiscardScheduled(t *testing.T) {
+	database := testDB(t)
+	recurring := NewRecurringExpenseService(database)
+
+	addRecurring(t, database, models.FrequencyWeekly, "2025-04-07", 7, 10000, true)
+	if _, err := recurring.PostDue(time.Date(2025, 4, 14, 9, 0, 0, 0, time.Local)); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := recurring.GetScheduled("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("%d pendientes, se esperaban 2", len(pending))
+	}
+
+	// El recibo de este mes llegó más caro
+	if _, err := recurring.Confirm(pending[0].ID, 12500, testUser); err != nil {
+		t.Fatal(err)
+	}
+	if err := recurring.Discard(pending[1].ID, testUser); err != nil {
+		t.Fatal(err)
+	}
+
+	expectMoney(t, "gastos", queryMoney(t, database, `SELECT COALESCE(SUM(amount), 0) FROM expenses`), 12500)
+	n := countRows(t, database, `
+		SELECT COUNT(*) FROM audit_log WHERE entity = ? AND actor = ? AND after LIKE '%descartado%'
+	`, models.AuditRecurring, testUser)
+	if n != 1 {
+		t.Errorf("%d descartes en la bitácora, se esperaba 1", n)
+	}
+	expectMoney(t, "saldo de caja",
+		queryMoney(t, database, `SELECT saldo FROM accounts WHERE id = ?`, models.CashAccountID), -12500)
+
+	if _, err := recurring.Confirm(pending[0].ID, 0, testUser); !errors.Is(err, ErrAlreadyResolved) {
+		t.Errorf("confirmar dos veces: se esperaba ErrAlreadyResolved, llegó %v", err)
+	}
+	if _, err := recurring.Confirm(pending[1].ID, 0, testUser); !errors.Is(err, ErrAlreadyResolved) {
+		t.Errorf("confirmar uno descartado: se esperaba ErrAlreadyResolved, llegó %v", err)
+	}
+	if err := recurring.Discard(999, testUser); !errors.Is(err, ErrNotFound) {
+		t.Errorf("descartar uno que no existe: se esperaba ErrNotFound, llegó %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+type ReportService struct {
+	DB *sql.DB
+}
+
+func NewReportService(db *sql.DB) *ReportService {
+	return &ReportService{DB: db}
+}
+
+// reportRange completa el filtro: por defecto desde el primero del mes hasta
+// hoy, agrupado por mes.
+func reportRange(f models.ReportFilter) (models.ReportFilter, error) {
+	now := time.Now()
+	if f.To == "" {
+		f.To = now.Format("2006-01-02")
+	}
+	if f.From == "" {
+		f.From = now.Format("2006-01") + "-01"
+	}
+	if f.From > f.To {
+		return f, ErrInvalidInput
+	}
+
+	switch f.GroupBy {
+	case "":
+		f.GroupBy = models.GroupMonth
+	case models.GroupDay, models.GroupWeek, models.GroupMonth:
+	default:
+		return f, ErrInvalidInput
+	}
+	return f, nil
+}
+
+// periodExpr es la expresión SQL que lleva la fecha col a su período.
+func periodExpr(col, group string) string {
+	switch group {
+	case models.GroupDay:
+		return "date(" + col + ")"
+	case models.GroupWeek:
+		return "date(" + col + ", 'weekday 0', '-6 days')"
+	default:
+		return "strftime('%Y-%m', " + col + ")"
+	}
+}
+
+// PnL arma el estado de resultados del rango: ventas al precio y costo
+// congelados en cada línea, devoluciones en la fecha en que se hicieron y
+// gastos operativos por categoría. Los surtidos no cuentan como gasto; su
+// costo entra al resultado a través del costo de lo vendido.
+func (s *ReportService) PnL(f models.ReportFilter) (models.PnLReport, error) {
+	f, err := reportRange(f)
+	if err != nil {
+		return models.PnLReport{}, err
+	}
+
+	lines := map[string]*models.PnLLine{}
+	line := func(period string) *models.PnLLine {
+		l, ok := lines[period]
+		if !ok {
+			l = &models.PnLLine{Period: period, ByCategory: []models.PnLExpense{}}
+			lines[period] = l
+		}
+		return l
+	}
+
+	// Ventas, incluidas las que después se anularon
+	rows, err := s.DB.Query(`
+		SELECT `+periodExpr("s.date", f.GroupBy)+`,
+			COALESCE(SUM(si.quantity * si.unit_price), 0),
+			COALESCE(SUM(si.quantity * si.unit_cost), 0)
+		FROM sales s
+		JOIN sale_items si ON si.sale_id = s.id
+		WHERE date(s.date) BETWEEN date(?) AND date(?)
+		GROUP BY 1
+	`, f.From, f.To)
+	if err != nil {
+		return models.PnLReport{}, err
+	}
+	for rows.Next() {
+		var period string
+		var sales, cost models.Money
+		if err := rows.Scan(&period, &sales, &cost); err != nil {
+			rows.Close()
+			return models.PnLReport{}, err
+		}
+		l := line(period)
+		l.GrossSales += sales
+		l.COGS += cost
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.PnLReport{}, err
+	}
+
+	// Devoluciones y anulaciones: restan ingreso y costo de lo que volvió
+	rows, err = s.DB.Query(`
+		SELECT `+periodExpr("r.date", f.GroupBy)+`,
+			COALESCE(SUM(ri.quantity * si.unit_price), 0),
+			COALESCE(SUM(ri.quantity * si.unit_cost), 0)
+		FROM sale_returns r
+		JOIN sale_return_items ri ON ri.return_id = r.id
+		JOIN sale_items si ON si.id = ri.sale_item_id
+		WHERE date(r.date) BETWEEN date(?) AND date(?)
+		GROUP BY 1
+	`, f.From, f.To)
+	if err != nil {
+		return models.PnLReport{}, err
+	}
+	for rows.Next() {
+		var period string
+		var returned, cost models.Money
+		if err := rows.Scan(&period, &returned, &cost); err != nil {
+			rows.Close()
+			return models.PnLReport{}, err
+		}
+		l := line(period)
+		l.Returns += returned
+		l.COGS -= cost
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.PnLReport{}, err
+	}
+
+	// Gastos operativos
+	rows, err = s.DB.Query(`
+		SELECT `+periodExpr("e.date", f.GroupBy)+`, e.category_id, c.name, SUM(e.amount)
+		FROM expenses e
+		JOIN expense_categories c ON c.id = e.category_id
+		WHERE date(e.date) BETWEEN date(?) AND date(?)
+		GROUP BY 1, e.category_id
+		ORDER BY 1, SUM(e.amount) DESC
+	`, f.From, f.To)
+	if err != nil {
+		return models.PnLReport{}, err
+	}
+	totalsByCategory := map[int64]*models.PnLExpense{}
+	var categoryOrder []int64
+	for rows.Next() {
+		var period string
+		var e models.PnLExpense
+		if err := rows.Scan(&period, &e.CategoryID, &e.CategoryName, &e.Amount); err != nil {
+			rows.Close()
+			return models.PnLReport{}, err
+		}
+		l := line(period)
+		l.Expenses += e.Amount
+		l.ByCategory = append(l.ByCategory, e)
+
+		t, ok := totalsByCategory[e.CategoryID]
+		if !ok {
+			t = &models.PnLExpense{CategoryID: e.CategoryID, CategoryName: e.CategoryName}
+			totalsByCategory[e.CategoryID] = t
+			categoryOrder = append(categoryOrder, e.CategoryID)
+		}
+		t.Amount += e.Amount
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.PnLReport{}, err
+	}
+
+	report := models.PnLReport{
+		From:    f.From,
+		To:      f.To,
+		GroupBy: f.GroupBy,
+		Periods: []models.PnLLine{},
+		Totals:  models.PnLLine{Period: "total", ByCategory: []models.PnLExpense{}},
+	}
+
+	for _, l := range lines {
+		finishPnL(l)
+		report.Periods = append(report.Periods, *l)
+
+		report.Totals.GrossSales += l.GrossSales
+		report.Totals.Returns += l.Returns
+		report.Totals.COGS += l.COGS
+		report.Totals.Expenses += l.Expenses
+	}
+	sort.Slice(report.Periods, func(i, j int) bool { return report.Periods[i].Period < report.Periods[j].Period })
+
+	for _, id := range categoryOrder {
+		report.Totals.ByCategory = append(report.Totals.ByCategory, *totalsByCategory[id])
+	}
+	sort.SliceStable(report.Totals.ByCategory, func(i, j int) bool {
+		return report.Totals.ByCategory[i].Amount > report.Totals.ByCategory[j].Amount
+	})
+	finishPnL(&report.Totals)
+
+	return report, nil
+}
+
+// finishPnL calcula los campos derivados de una línea.
+func finishPnL(l *models.PnLLine) {
+	l.Revenue = l.GrossSales - l.Returns
+	l.GrossMargin = l.Revenue - l.COGS
+	l.Net = l.GrossMargin - l.Expenses
+	l.MarginPct = pct(l.GrossMargin, l.Revenue)
+}
+
+// pct es part/whole en porcentaje con dos decimales; 0 si whole es 0.
+func pct(part, whole models.Money) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(whole)*10000) / 100
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+// seedPnL arma ventas de 1000 con costo 400 por unidad en marzo y abril de
+// 2025, una devolución la semana siguiente a su venta y gastos en varias
+// categorías. El 3 y el 10 de marzo de 2025 son lunes.
+func seedPnL(t *testing.T, database *sql.DB) {
+	t.Helper()
+
+	mustExec(t, database, `INSERT INTO clientes (id, nombre) VALUES (1, 'Ana')`)
+	mustExec(t, database, `INSERT INTO productos (id, nombre, costo_total, precio) VALUES (1, 'Pan', 40000, 100000)`)
+
+	sales := []struct {
+		id   int64
+		date string
+		qty  int64
+	}{
+		{1, "2025-03-05 10:00", 2}, // miércoles
+		{2, "2025-03-09 18:00", 1}, // domingo: todavía semana del 3
+		{3, "2025-04-01 09:00", 1}, // martes: semana del 31 de marzo
+	}
+	for _, s := range sales {
+		mustExec(t, database, `
+			INSERT INTO sales (id, client_id, total, cost, is_credit, date) VALUES (?, 1, ?, ?, 0, ?)
+		`, s.id, 100000*s.qty, 40000*s.qty, s.date)
+		mustExec(t, database, `
+			INSERT INTO sale_items (id, sale_id, product_id, quantity, unit_price, unit_cost) VALUES (?, ?, 1, ?, 100000, 40000)
+		`, s.id, s.id, s.qty)
+	}
+
+	// Un pan de la venta 1 vuelve el lunes siguiente
+	mustExec(t, database, `
+		INSERT INTO sale_returns (id, sale_id, kind, amount, refunded, date)
+		VALUES (1, 1, 'devolucion', 100000, 100000, '2025-03-10 08:00')
+	`)
+	mustExec(t, database, `INSERT INTO sale_return_items (return_id, sale_item_id, quantity) VALUES (1, 1, 1)`)
+	mustExec(t, database, `UPDATE sale_items SET returned_quantity = 1 WHERE id = 1`)
+
+	expenses := []struct {
+		category int64
+		amount   models.Money
+		date     string
+	}{
+		{1, 50000, "2025-03-04 12:00"}, // arriendo
+		{2, 15000, "2025-03-12 12:00"}, // servicios
+		{2, 20000, "2025-04-02 12:00"}, // servicios
+	}
+	for _, e := range expenses {
+		mustExec(t, database, `
+			INSERT INTO expenses (category_id, account_id, amount, date) VALUES (?, 1, ?, ?)
+		`, e.category, e.amount, e.date)
+	}
+}
+
+func TestPeriodExpr(t *testing.T) {
+	database := testDB(t)
+
+	tests := []struct {
+		date  string
+		group string
+		want  string
+	}{
+		// La semana va de lunes a domingo y se nombra por su lunes
+		{"2025-03-03 00:00", models.GroupWeek, "2025-03-03"}, // lunes
+		{"2025-03-05 10:00", models.GroupWeek, "2025-03-03"},
+		{"2025-03-09 23:59", models.GroupWeek, "2025-03-03"}, // domingo
+		{"2025-03-10 00:00", models.GroupWeek, "2025-03-10"},
+		{"2025-01-01 12:00", models.GroupWeek, "2024-12-30"}, // cruza el año
+		{"2025-03-09 23:59", models.GroupDay, "2025-03-09"},
+		{"2025-03-31 23:59", models.GroupMonth, "2025-03"},
+		{"2025-04-01 00:00", models.GroupMonth, "2025-04"},
+	}
+
+	for _, tt := range tests {
+		var got string
+		if err := database.QueryRow(`SELECT `+periodExpr("?", tt.group), tt.date).Scan(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s de %s = %s, se esperaba %s", tt.group, tt.date, got, tt.want)
+		}
+	}
+}
+
+type pnlWant struct {
+	period      string
+	grossSales  models.Money
+	returns     models.Money
+	revenue     models.Money
+	cogs        models.Money
+	grossMargin models.Money
+	expenses    models.Money
+	net         models.Money
+}
+
+func expectPnLLine(t *testing.T, got models.PnLLine, want pnlWant) {
+	t.Helper()
+	if got.Period != want.period {
+		t.Errorf("período %q, se esperaba %q", got.Period, want.period)
+		return
+	}
+	expectMoney(t, want.period+" ventas", got.GrossSales, want.grossSales)
+	expectMoney(t, want.period+" devoluciones", got.Returns, want.returns)
+	expectMoney(t, want.period+" ingresos", got.Revenue, want.revenue)
+	expectMoney(t, want.period+" costo", got.COGS, want.cogs)
+	expectMoney(t, want.period+" margen bruto", got.GrossMargin, want.grossMargin)
+	expectMoney(t, want.period+" gastos", got.Expenses, want.expenses)
+	expectMoney(t, want.period+" neto", got.Net, want.net)
+}
+
+func TestPnLGrouping(t *testing.T) {
+	database := testDB(t)
+	seedPnL(t, database)
+	reports := NewReportService(database)
+
+	total := pnlWant{"total", 400000, 100000, 300000, 120000, 180000, 85000, 95000}
+
+	tests := []struct {
+		group string
+		from  string
+		to    string
+		want  []pnlWant
+		total *pnlWant
+	}{
+		{
+			group: models.GroupWeek,
+			from:  "2025-03-01",
+			to:    "2025-04-30",
+			want: []pnlWant{
+				// Lunes de cada semana; el domingo 9 cae en la del 3
+				{"2025-03-03", 300000, 0, 300000, 120000, 180000, 50000, 130000},
+				// La devolución resta en su semana, aunque la venta fue antes
+				{"2025-03-10", 0, 100000, -100000, -40000, -60000, 15000, -75000},
+				{"2025-03-31", 100000, 0, 100000, 40000, 60000, 20000, 40000},
+			},
+			total: &total,
+		},
+		{
+			group: models.GroupMonth,
+			from:  "2025-03-01",
+			to:    "2025-04-30",
+			want: []pnlWant{
+				{"2025-03", 300000, 100000, 200000, 80000, 120000, 65000, 55000},
+				{"2025-04", 100000, 0, 100000, 40000, 60000, 20000, 40000},
+			},
+			total: &total,
+		},
+		{
+			group: models.GroupDay,
+			from:  "2025-03-04",
+			to:    "2025-03-10",
+			want: []pnlWant{
+				{"2025-03-04", 0, 0, 0, 0, 0, 50000, -50000},
+				{"2025-03-05", 200000, 0, 200000, 80000, 120000, 0, 120000},
+				{"2025-03-09", 100000, 0, 100000, 40000, 60000, 0, 60000},
+				{"2025-03-10", 0, 100000, -100000, -40000, -60000, 0, -60000},
+			},
+		},
+		{
+			// Fuera del rango de la venta, su devolución igual cuenta
+			group: models.GroupDay,
+			from:  "2025-03-10",
+			to:    "2025-03-31",
+			want: []pnlWant{
+				{"2025-03-10", 0, 100000, -100000, -40000, -60000, 0, -60000},
+				{"2025-03-12", 0, 0, 0, 0, 0, 15000, -15000},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.group+" "+tt.from, func(t *testing.T) {
+			report, err := reports.PnL(models.ReportFilter{From: tt.from, To: tt.to, GroupBy: tt.group})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.Periods) != len(tt.want) {
+				t.Fatalf("%d períodos, se esperaban %d: %+v", len(report.Periods), len(tt.want), report.Periods)
+			}
+			for i, want := range tt.want {
+				expectPnLLine(t, report.Periods[i], want)
+			}
+			if tt.total != nil {
+				expectPnLLine(t, report.Totals, *tt.total)
+			}
+		})
+	}
+}
+
+func TestPnLExpensesByCategory(t *testing.T) {
+	database := testDB(t)
+	seedPnL(t, database)
+
+	report, err := NewReportService(database).PnL(models.ReportFilter{From: "2025-03-01", To: "2025-04-30"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.GroupBy != models.GroupMonth {
+		t.Errorf("agrupación por defecto %q, se esperaba %q", report.GroupBy, models.GroupMonth)
+	}
+
+	// En el total, la categoría con más gasto va primero
+	got := report.Totals.ByCategory
+	if len(got) != 2 {
+		t.Fatalf("%d categorías, se esperaban 2: %+v", len(got), got)
+	}
+	if got[0].CategoryName != "arriendo" || got[0].Amount != 50000 {
+		t.Errorf("primera categoría %+v, se esperaba arriendo 500", got[0])
+	}
+	if got[1].CategoryName != "servicios" || got[1].Amount != 35000 {
+		t.Errorf("segunda categoría %+v, se esperaba servicios 350", got[1])
+	}
+	if pct := report.Totals.MarginPct; pct != 60 {
+		t.Errorf("margen %v%%, se esperaba 60%%", pct)
+	}
+}
+
+func TestPnLInvalidFilter(t *testing.T) {
+	reports := NewReportService(testDB(t))
+
+	for _, f := range []models.ReportFilter{
+		{From: "2025-04-01", To: "2025-03-01"},
+		{From: "2025-03-01", To: "2025-03-31", GroupBy: "anio"},
+	} {
+		if _, err := reports.PnL(f); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("PnL(%+v): se esperaba ErrInvalidInput, llegó %v", f, err)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+type SaleService struct {
+	DB *sql.DB
+}
+
+func NewSaleService(db *sql.DB) *SaleService {
+	return &SaleService{DB: db}
+}
+
+// GetAll lista las ventas que cumplen el filtro. Sin fechas, los últimos 30 días.
+func (s *SaleService) GetAll(f models.SaleFilter) ([]models.SaleDetail, error) {
+	where := []string{}
+	args := []any{}
+
+	if f.From == "" && f.To == "" {
+		where = append(where, "date(s.date) >= date('now', '-30 days')")
+	}
+	if f.From != "" {
+		where = append(where, "date(s.date) >= date(?)")
+		args = append(args, f.From)
+	}
+	if f.To != "" {
+		where = append(where, "date(s.date) <= date(?)")
+		args = append(args, f.To)
+	}
+	if f.ClientID > 0 {
+		where = append(where, "s.client_id = ?")
+		args = append(args, f.ClientID)
+	}
+	if f.ProductID > 0 {
+		where = append(where, "EXISTS (SELECT 1 FROM sale_items si WHERE si.sale_id = s.id AND si.product_id = ?)")
+		args = append(args, f.ProductID)
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT s.id, s.client_id, c.nombre, s.total, s.cost, s.is_credit, s.credit_sale_id, s.date, s.voided_at
+		FROM sales s
+		JOIN clientes c ON c.id = s.client_id
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY s.date DESC, s.id DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sales := []models.SaleDetail{}
+	for rows.Next() {
+		sale, err := scanSale(rows)
+		if err != nil {
+			return nil, err
+		}
+		sales = append(sales, sale)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range sales {
+		sales[i].Items, err = s.saleLines(sales[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sales, nil
+}
+
+func (s *SaleService) GetById(id int64) (models.SaleDetail, error) {
+	row := s.DB.QueryRow(`
+		SELECT s.id, s.client_id, c.nombre, s.total, s.cost, s.is_credit, s.credit_sale_id, s.date, s.voided_at
+		FROM sales s
+		JOIN clientes c ON c.id = s.client_id
+		WHERE s.id = ?
+	`, id)
+
+	sale, err := scanSale(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.SaleDetail{}, ErrNotFound
+	}
+	if err != nil {
+		return models.SaleDetail{}, err
+	}
+
+	sale.Items, err = s.saleLines(sale.ID)
+	if err != nil {
+		return models.SaleDetail{}, err
+	}
+	return sale, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSale(r rowScanner) (models.SaleDetail, error) {
+	var sale models.SaleDetail
+	var creditID sql.NullInt64
+	var voidedAt sql.NullString
+
+	err := r.Scan(&sale.ID, &sale.ClientID, &sale.ClientName, &sale.Total, &sale.Cost,
+		&sale.IsCredit, &creditID, &sale.Date, &voidedAt)
+	if err != nil {
+		return models.SaleDetail{}, err
+	}
+	if creditID.Valid {
+		sale.CreditSaleID = &creditID.Int64
+	}
+	if voidedAt.Valid {
+		sale.VoidedAt = &voidedAt.String
+	}
+	return sale, nil
+}
+
+func (s *SaleService) saleLines(saleID int64) ([]models.SaleLine, error) {
+	rows, err := s.DB.Query(`
+		SELECT si.id, si.product_id, COALESCE(p.nombre, ''), si.quantity, si.unit_price, si.unit_cost, si.returned_quantity, si.source
+		FROM sale_items si
+		LEFT JOIN productos p ON p.id = si.product_id
+		WHERE si.sale_id = ?
+		ORDER BY si.id
+	`, saleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []models.SaleLine{}
+	for rows.Next() {
+		var l models.SaleLine
+		if err := rows.Scan(&l.ID, &l.ProductID, &l.ProductName, &l.Quantity, &l.UnitPrice, &l.UnitCost, &l.Returned, &l.Source); err != nil {
+			return nil, err
+		}
+		l.Subtotal = l.UnitPrice * models.Money(l.Quantity)
+		lines = append(lines, l)
+	}
+
+	return lines, rows.Err()
+}
+
+// Void anula la venta completa: devuelve todo lo que no se haya devuelto antes.
+func (s *SaleService) Void(saleID int64, req models.SaleReturn) error {
+	return s.returnItems(saleID, req, true)
+}
+
+// Return registra una devolución parcial de las líneas indicadas.
+func (s *SaleService) Return(saleID int64, req models.SaleReturn) error {
+	if len(req.Items) == 0 {
+		return ErrInvalidInput
+	}
+	return s.returnItems(saleID, req, false)
+}
+
+// returnItems repone los insumos de lo devuelto y revierte su efecto en caja
+// o en la deuda del cliente. En una venta fiada lo devuelto primero descuenta
+// del saldo pendiente; si supera ese saldo, la diferencia ya fue abonada: lo
+// que se pagó con saldo a favor vuelve al saldo a favor y el resto solo se
+// devuelve de caja con req.Refund.
+func (s *SaleService) returnItems(saleID int64, req models.SaleReturn, void bool) (err error) {
+	now := time.Now().Format("2006-01-02 15:04")
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		} else if err != nil {
+			_ = tx.Rollback()
+		} else {
+			if cerr := tx.Commit(); cerr != nil {
+				err = cerr
+			}
+		}
+	}()
+
+	var clientID int64
+	var isCredit bool
+	var creditID sql.NullInt64
+	var voidedAt sql.NullString
+	err = tx.QueryRow(`
+		SELECT client_id, is_credit, credit_sale_id, voided_at FROM sales WHERE id = ?
+	`, saleID).Scan(&clientID, &isCredit, &creditID, &voidedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if voidedAt.Valid {
+		return ErrSaleVoided
+	}
+
+	type itemState struct {
+		productID int64
+		pending   int64 // vendido menos lo ya devuelto
+		unitPrice models.Money
+		source    string
+	}
+	items := map[int64]*itemState{}
+
+	rows, err := tx.Query(`
+		SELECT id, product_id, quantity - returned_quantity, unit_price, source
+		FROM sale_items WHERE sale_id = ?
+	`, saleID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int64
+		st := &itemState{}
+		if err = rows.Scan(&id, &st.productID, &st.pending, &st.unitPrice, &st.source); err != nil {
+			rows.Close()
+			return err
+		}
+		items[id] = st
+	}
+	if err = rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	lines := req.Items
+	if void {
+		lines = nil
+		for id, st := range items {
+			if st.pending > 0 {
+				lines = append(lines, models.SaleReturnItem{SaleItemID: id, Quantity: st.pending})
+			}
+		}
+		sort.Slice(lines, func(i, j int) bool { return lines[i].SaleItemID < lines[j].SaleItemID })
+	}
+
+	var amount models.Money
+	for _, l := range lines {
+		st, ok := items[l.SaleItemID]
+		if !ok || l.Quantity <= 0 || l.Quantity > st.pending {
+			return ErrInvalidInput
+		}
+		st.pending -= l.Quantity
+		amount += st.unitPrice * models.Money(l.Quantity)
+
+		if st.source == models.SourceStock {
+			_, err = tx.Exec(`
+				UPDATE productos SET stock_actual = stock_actual + ? WHERE id = ?
+			`, l.Quantity, st.productID)
+		} else {
+			err = restockInsumos(tx, st.productID, l.Quantity)
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			UPDATE sale_items SET returned_quantity = returned_quantity + ? WHERE id = ?
+		`, l.Quantity, l.SaleItemID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// La anulación también cierra una venta a la que ya le devolvieron todo
+	allReturned := true
+	for _, st := range items {
+		if st.pending > 0 {
+			allReturned = false
+		}
+	}
+
+	label := "Devolución"
+	kind := "devolucion"
+	if void {
+		label = "Anulación"
+		kind = "anulacion"
+	}
+	label += " venta #" + strconv.FormatInt(saleID, 10)
+
+	refund := amount
+	var credited models.Money
+	if isCredit {
+		var remaining, storeCredit models.Money
+		var payments int
+		err = tx.QueryRow(`
+			SELECT remaining_balance,
+				(SELECT COUNT(*) FROM credit_payments WHERE credit_sale_id = credit_sales.id AND from_credit = 0),
+				(SELECT COALESCE(SUM(amount), 0) FROM credit_payments WHERE credit_sale_id = credit_sales.id AND from_credit = 1)
+				- (SELECT COALESCE(SUM(credited), 0) FROM sale_returns WHERE sale_id = ?)
+			FROM credit_sales WHERE id = ?
+		`, saleID, creditID.Int64).Scan(&remaining, &payments, &storeCredit)
+		if err != nil {
+			return err
+		}
+
+		forgiven := amount
+		if forgiven > remaining {
+			forgiven = remaining
+		}
+		credited = min(amount-forgiven, storeCredit)
+		refund = amount - forgiven - credited
+
+		if (refund > 0 || (void && payments > 0)) && !req.Refund {
+			return ErrRefundRequired
+		}
+
+		_, err = tx.Exec(`
+			UPDATE credit_sales
+			SET total = total - ?, remaining_balance = remaining_balance - ?
+			WHERE id = ?
+		`, amount, forgiven, creditID.Int64)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			UPDATE clientes SET deuda = deuda - ?, saldo_favor = saldo_favor + ? WHERE id = ?
+		`, forgiven, credited, clientID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if refund > 0 {
+		err = recordMove(tx, moveRecord{
+			Description: label,
+			Type:        models.MoveEgreso,
+			Origin:      models.OriginReturn,
+			Amount:      refund,
+			Date:        now,
+			ClientID:    clientID,
+			SaleID:      saleID,
+			AccountID:   req.AccountID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO sale_returns (sale_id, kind, amount, refunded, credited, reason, date)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, saleID, kind, amount, refund, credited, req.Reason, now)
+	if err != nil {
+		return err
+	}
+	returnID, _ := res.LastInsertId()
+
+	for _, l := range lines {
+		_, err = tx.Exec(`
+			INSERT INTO sale_return_items (return_id, sale_item_id, quantity) VALUES (?, ?, ?)
+		`, returnID, l.SaleItemID, l.Quantity)
+		if err != nil {
+			return err
+		}
+	}
+
+	if void || allReturned {
+		_, err = tx.Exec(`UPDATE sales SET voided_at = ? WHERE id = ?`, now, saleID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restockInsumos devuelve al inventario los insumos que consumió qty unidades
+// del producto, según su receta actual.
+func restockInsumos(tx *sql.Tx, productID, qty int64) error {
+	recipe, err := productRecipe(tx, productID)
+	if err != nil {
+		return err
+	}
+
+	date := time.Now().Format("2006-01-02 15:04")
+	for _, ri := range recipe {
+		if err := restoreInsumo(tx, ri.insumoID, ri.quantity*float64(qty), date); err != nil {
+			return err
+		}
+	}
+	return nil
+}
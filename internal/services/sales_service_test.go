@@ -0,0 +1,257 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+// Pan a 1000 pesos; cada unidad gasta 2 g de harina y hay 100 g en inventario.
+const (
+	breadPrice     models.Money = 100000
+	flourPerBread               = 2.0
+	flourStartQty               = 100.0
+	flourUnitPrice models.Money = 1000
+)
+
+type saleFixture struct {
+	db       *sql.DB
+	sales    *SaleService
+	moves    *MovementService
+	clients  *ClientService
+	clientID int64
+	product  int64
+	insumo   int64
+}
+
+func newSaleFixture(t *testing.T) *saleFixture {
+	t.Helper()
+	database := testDB(t)
+	f := &saleFixture{
+		db:      database,
+		sales:   NewSaleService(database),
+		moves:   NewMoveService(database),
+		clients: NewClientService(database),
+	}
+
+	flour := models.Insumo{Name: "Harina", Um: "g", Stock: flourStartQty, UnitPrice: flourUnitPrice}
+	if err := NewInsumoService(database).Create(&flour, testUser); err != nil {
+		t.Fatal(err)
+	}
+	f.insumo = flour.ID
+
+	bread := models.Product{
+		Name:    "Pan",
+		Price:   breadPrice,
+		Insumos: []models.ProductInsumo{{InsumoID: flour.ID, Quantity: flourPerBread}},
+	}
+	if err := NewProductService(database).Create(&bread, testUser); err != nil {
+		t.Fatal(err)
+	}
+	f.product = bread.ID
+
+	client := models.Client{Name: "Ana"}
+	if err := f.clients.Create(&client, testUser); err != nil {
+		t.Fatal(err)
+	}
+	f.clientID = client.ID
+
+	return f
+}
+
+// sell vende qty panes y devuelve la venta con sus líneas.
+func (f *saleFixture) sell(t *testing.T, qty int64, credit bool) models.SaleDetail {
+	t.Helper()
+	id, err := f.moves.Sell(models.Sale{
+		ClientId: f.clientID,
+		IsCredit: credit,
+		Items:    []models.SaleItem{{ProductID: f.product, Quantity: qty}},
+	}, testUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sale, err := f.sales.GetById(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sale
+}
+
+type saleState struct {
+	debt      models.Money // clientes.deuda
+	credit    models.Money // clientes.saldo_favor
+	remaining models.Money // credit_sales.remaining_balance; solo en fiados
+	cash      models.Money // saldo de la caja
+	flour     float64      // harina en inventario
+}
+
+func (f *saleFixture) expect(t *testing.T, sale models.SaleDetail, want saleState) {
+	t.Helper()
+	expectMoney(t, "deuda", queryMoney(t, f.db, `SELECT deuda FROM clientes WHERE id = ?`, f.clientID), want.debt)
+	expectMoney(t, "saldo a favor", queryMoney(t, f.db, `SELECT saldo_favor FROM clientes WHERE id = ?`, f.clientID), want.credit)
+	expectMoney(t, "saldo de caja", queryMoney(t, f.db, `SELECT saldo FROM accounts WHERE id = ?`, models.CashAccountID), want.cash)
+	if sale.CreditSaleID != nil {
+		expectMoney(t, "remaining_balance",
+			queryMoney(t, f.db, `SELECT remaining_balance FROM credit_sales WHERE id = ?`, *sale.CreditSaleID), want.remaining)
+	}
+	if got := queryFloat(t, f.db, `SELECT stock_actual FROM insumos WHERE id = ?`, f.insumo); got != want.flour {
+		t.Errorf("harina = %v, se esperaba %v", got, want.flour)
+	}
+}
+
+func (f *saleFixture) voided(t *testing.T, saleID int64) bool {
+	t.Helper()
+	sale, err := f.sales.GetById(saleID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sale.VoidedAt != nil
+}
+
+func TestReturnCashSalePartial(t *testing.T) {
+	f := newSaleFixture(t)
+	sale := f.sell(t, 3, false)
+	line := sale.Items[0].ID
+
+	f.expect(t, sale, saleState{cash: 3 * breadPrice, flour: flourStartQty - 3*flourPerBread})
+
+	// De contado lo devuelto sale de caja sin pedir confirmación
+	err := f.sales.Return(sale.ID, models.SaleReturn{Items: []models.SaleReturnItem{{SaleItemID: line, Quantity: 1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, sale, saleState{cash: 2 * breadPrice, flour: flourStartQty - 2*flourPerBread})
+	if f.voided(t, sale.ID) {
+		t.Error("una devolución parcial no debe anular la venta")
+	}
+
+	// No se puede devolver más de lo que queda
+	err = f.sales.Return(sale.ID, models.SaleReturn{Items: []models.SaleReturnItem{{SaleItemID: line, Quantity: 3}}})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("devolver de más: se esperaba ErrInvalidInput, llegó %v", err)
+	}
+
+	// Devolver lo que queda anula la venta
+	err = f.sales.Return(sale.ID, models.SaleReturn{Items: []models.SaleReturnItem{{SaleItemID: line, Quantity: 2}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, sale, saleState{cash: 0, flour: flourStartQty})
+	if !f.voided(t, sale.ID) {
+		t.Error("devolver todo debe dejar la venta anulada")
+	}
+
+	err = f.sales.Void(sale.ID, models.SaleReturn{})
+	if !errors.Is(err, ErrSaleVoided) {
+		t.Errorf("anular dos veces: se esperaba ErrSaleVoided, llegó %v", err)
+	}
+}
+
+func TestReturnCreditSaleWithPayments(t *testing.T) {
+	f := newSaleFixture(t)
+	sale := f.sell(t, 3, true)
+	line := sale.Items[0].ID
+
+	if err := f.moves.PayCredit(*sale.CreditSaleID, 250000, 0, testUser); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, sale, saleState{debt: 50000, remaining: 50000, cash: 250000, flour: flourStartQty - 6})
+
+	// Lo devuelto (1000) supera lo pendiente (500): los otros 500 ya se
+	// pagaron y solo salen de caja con refund
+	req := models.SaleReturn{Items: []models.SaleReturnItem{{SaleItemID: line, Quantity: 1}}}
+	if err := f.sales.Return(sale.ID, req); !errors.Is(err, ErrRefundRequired) {
+		t.Fatalf("sin refund: se esperaba ErrRefundRequired, llegó %v", err)
+	}
+	f.expect(t, sale, saleState{debt: 50000, remaining: 50000, cash: 250000, flour: flourStartQty - 6})
+
+	req.Refund = true
+	if err := f.sales.Return(sale.ID, req); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, sale, saleState{debt: 0, remaining: 0, cash: 200000, flour: flourStartQty - 4})
+	expectMoney(t, "total del fiado",
+		queryMoney(t, f.db, `SELECT total FROM credit_sales WHERE id = ?`, *sale.CreditSaleID), 2*breadPrice)
+	if f.voided(t, sale.ID) {
+		t.Error("una devolución parcial no debe anular la venta")
+	}
+}
+
+func TestReturnCreditSaleWithinBalance(t *testing.T) {
+	f := newSaleFixture(t)
+	sale := f.sell(t, 3, true)
+
+	if err := f.moves.PayCredit(*sale.CreditSaleID, 50000, 0, testUser); err != nil {
+		t.Fatal(err)
+	}
+
+	// Lo devuelto cabe en lo pendiente: solo baja la deuda, no hace falta refund
+	err := f.sales.Return(sale.ID, models.SaleReturn{Items: []models.SaleReturnItem{{SaleItemID: sale.Items[0].ID, Quantity: 2}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, sale, saleState{debt: 50000, remaining: 50000, cash: 50000, flour: flourStartQty - 2})
+}
+
+func TestVoidCreditSaleWithPayments(t *testing.T) {
+	f := newSaleFixture(t)
+	sale := f.sell(t, 2, true)
+
+	if err := f.moves.PayCredit(*sale.CreditSaleID, 50000, 0, testUser); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, sale, saleState{debt: 150000, remaining: 150000, cash: 50000, flour: flourStartQty - 4})
+
+	// Con abonos, anular exige decidir qué pasa con la plata
+	if err := f.sales.Void(sale.ID, models.SaleReturn{}); !errors.Is(err, ErrRefundRequired) {
+		t.Fatalf("sin refund: se esperaba ErrRefundRequired, llegó %v", err)
+	}
+	f.expect(t, sale, saleState{debt: 150000, remaining: 150000, cash: 50000, flour: flourStartQty - 4})
+	if f.voided(t, sale.ID) {
+		t.Fatal("la anulación rechazada no debe marcar la venta")
+	}
+
+	if err := f.sales.Void(sale.ID, models.SaleReturn{Refund: true}); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, sale, saleState{debt: 0, remaining: 0, cash: 0, flour: flourStartQty})
+	if !f.voided(t, sale.ID) {
+		t.Error("la venta debe quedar anulada")
+	}
+}
+
+func TestVoidCreditSaleWithoutPayments(t *testing.T) {
+	f := newSaleFixture(t)
+	sale := f.sell(t, 2, true)
+
+	// Sin abonos no hay nada que devolver: basta con perdonar la deuda
+	if err := f.sales.Void(sale.ID, models.SaleReturn{}); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, sale, saleState{debt: 0, remaining: 0, cash: 0, flour: flourStartQty})
+	if !f.voided(t, sale.ID) {
+		t.Error("la venta debe quedar anulada")
+	}
+}
+
+func TestVoidCreditSalePaidWithStoreCredit(t *testing.T) {
+	f := newSaleFixture(t)
+
+	// 300 de saldo a favor que el cliente dejó en caja
+	_, err := f.clients.ReceivePayment(f.clientID, models.ClientPayment{Amount: 30000, KeepCredit: true}, testUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sale := f.sell(t, 1, true)
+	f.expect(t, sale, saleState{debt: 70000, remaining: 70000, cash: 30000, flour: flourStartQty - 2})
+
+	// El saldo a favor usado vuelve al saldo a favor, no sale de caja, así
+	// que no hace falta refund
+	if err := f.sales.Void(sale.ID, models.SaleReturn{}); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, sale, saleState{debt: 0, credit: 30000, remaining: 0, cash: 30000, flour: flourStartQty})
+}
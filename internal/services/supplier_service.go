@@ -0,0 +1,207 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+type SupplierService struct {
+	DB *sql.DB
+}
+
+func NewSupplierService(db *sql.DB) *SupplierService {
+	return &SupplierService{DB: db}
+}
+
+func (s *SupplierService) GetAll() ([]models.Supplier, error) {
+	rows, err := s.DB.Query(`
+		SELECT id, name, phone, notes
+		FROM suppliers ORDER BY name ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.Supplier{}
+	for rows.Next() {
+		var sp models.Supplier
+		if err := rows.Scan(&sp.ID, &sp.Name, &sp.Phone, &sp.Notes); err != nil {
+			return nil, err
+		}
+		list = append(list, sp)
+	}
+
+	return list, rows.Err()
+}
+
+func (s *SupplierService) GetById(id int64) (models.Supplier, error) {
+	var sp models.Supplier
+	err := s.DB.QueryRow(`
+		SELECT id, name, phone, notes FROM suppliers WHERE id = ?
+	`, id).Scan(&sp.ID, &sp.Name, &sp.Phone, &sp.Notes)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Supplier{}, ErrNotFound
+	}
+	return sp, err
+}
+
+func (s *SupplierService) Create(sp *models.Supplier) error {
+	sp.Name = strings.TrimSpace(sp.Name)
+	if sp.Name == "" {
+		return ErrInvalidInput
+	}
+
+	res, err := s.DB.Exec(`
+		INSERT INTO suppliers (name, phone, notes) VALUES (?, ?, ?)
+	`, sp.Name, sp.Phone, sp.Notes)
+	if err != nil {
+		return err
+	}
+
+	sp.ID, _ = res.LastInsertId()
+	return nil
+}
+
+func (s *SupplierService) Update(sp *models.Supplier) error {
+	sp.Name = strings.TrimSpace(sp.Name)
+	if sp.Name == "" {
+		return ErrInvalidInput
+	}
+
+	res, err := s.DB.Exec(`
+		UPDATE suppliers SET name = ?, phone = ?, notes = ? WHERE id = ?
+	`, sp.Name, sp.Phone, sp.Notes, sp.ID)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete solo borra proveedores sin compras, órdenes ni cuentas por pagar,
+// para no perder el historial.
+func (s *SupplierService) Delete(id int64) error {
+	var n int
+	err := s.DB.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM supplies WHERE supplier_id = ?)
+			+ (SELECT COUNT(*) FROM purchase_orders WHERE supplier_id = ?)
+			+ (SELECT COUNT(*) FROM credit_purchases WHERE supplier_id = ?)
+	`, id, id, id).Scan(&n)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrInUse
+	}
+
+	res, err := s.DB.Exec(`DELETE FROM suppliers WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetPurchases devuelve lo comprado al proveedor, lo más reciente primero.
+func (s *SupplierService) GetPurchases(id int64, from, to string) ([]models.SupplierPurchase, error) {
+	if _, err := s.GetById(id); err != nil {
+		return nil, err
+	}
+
+	where := []string{"s.supplier_id = ?"}
+	args := []any{id}
+	if from != "" {
+		where = append(where, "date(s.date) >= date(?)")
+		args = append(args, from)
+	}
+	if to != "" {
+		where = append(where, "date(s.date) <= date(?)")
+		args = append(args, to)
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT s.id, s.insumo_id, COALESCE(i.nombre, ''), s.quantity, s.unit_cost, s.total, s.date
+		FROM supplies s
+		LEFT JOIN insumos i ON i.id = s.insumo_id
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY s.date DESC, s.id DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.SupplierPurchase{}
+	for rows.Next() {
+		var p models.SupplierPurchase
+		if err := rows.Scan(&p.ID, &p.InsumoID, &p.InsumoName, &p.Quantity, &p.UnitCost, &p.Total, &p.Date); err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+
+	return list, rows.Err()
+}
+
+// PriceComparison agrupa por insumo, proveedor y mes lo que costó cada
+// unidad, para ver quién vende más barato y cómo cambió con el tiempo.
+func (s *SupplierService) PriceComparison(f models.SupplierPriceFilter) ([]models.SupplierPrice, error) {
+	where := []string{"s.supplier_id IS NOT NULL", "s.quantity > 0"}
+	args := []any{}
+	if f.InsumoID > 0 {
+		where = append(where, "s.insumo_id = ?")
+		args = append(args, f.InsumoID)
+	}
+	if f.From != "" {
+		where = append(where, "date(s.date) >= date(?)")
+		args = append(args, f.From)
+	}
+	if f.To != "" {
+		where = append(where, "date(s.date) <= date(?)")
+		args = append(args, f.To)
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT s.insumo_id, COALESCE(i.nombre, ''), s.supplier_id, sp.name,
+			substr(s.date, 1, 7) AS period,
+			COUNT(*), SUM(s.quantity), SUM(s.total),
+			MIN(s.unit_cost), MAX(s.unit_cost)
+		FROM supplies s
+		JOIN suppliers sp ON sp.id = s.supplier_id
+		LEFT JOIN insumos i ON i.id = s.insumo_id
+		WHERE `+strings.Join(where, " AND ")+`
+		GROUP BY s.insumo_id, s.supplier_id, period
+		ORDER BY i.nombre, period, sp.name
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.SupplierPrice{}
+	for rows.Next() {
+		var p models.SupplierPrice
+		var total models.Money
+		err := rows.Scan(&p.InsumoID, &p.InsumoName, &p.SupplierID, &p.SupplierName, &p.Period,
+			&p.Purchases, &p.Quantity, &total, &p.MinUnitCost, &p.MaxUnitCost)
+		if err != nil {
+			return nil, err
+		}
+		p.AvgUnitCost = total.MulQty(1 / p.Quantity)
+		list = append(list, p)
+	}
+
+	return list, rows.Err()
+}
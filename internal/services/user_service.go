@@ -0,0 +1,128 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mgdavidd/server-Eme-Mar/internal/auth"
+	"github.com/mgdavidd/server-Eme-Mar/internal/models"
+)
+
+var ErrBadCredentials = errors.New("usuario o contraseña incorrectos")
+
+type UserService struct {
+	DB *sql.DB
+}
+
+func NewUserService(db *sql.DB) *UserService {
+	return &UserService{DB: db}
+}
+
+func (s *UserService) GetAll() ([]models.User, error) {
+	rows, err := s.DB.Query(`
+		SELECT id, username, role, created_at FROM users ORDER BY username
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.User{}
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.Username, &u.Role, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, u)
+	}
+	return list, rows.Err()
+}
+
+func (s *UserService) Count() (int, error) {
+	var n int
+	err := s.DB.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&n)
+	return n, err
+}
+
+// Create guarda el usuario con la contraseña hasheada y limpia u.Password.
+func (s *UserService) Create(u *models.User) error {
+	u.Username = strings.TrimSpace(u.Username)
+	if u.Username == "" || len(u.Password) < 6 || !models.ValidRole(u.Role) {
+		return ErrInvalidInput
+	}
+
+	hash, err := auth.HashPassword(u.Password)
+	if err != nil {
+		return err
+	}
+	u.Password = ""
+	u.CreatedAt = time.Now().Format("2006-01-02 15:04")
+
+	res, err := s.DB.Exec(`
+		INSERT INTO users (username, password_hash, role, created_at)
+		VALUES (?, ?, ?, ?)
+	`, u.Username, hash, u.Role, u.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return ErrInvalidInput
+		}
+		return err
+	}
+
+	u.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// Lookup devuelve el usuario vigente para el middleware de auth.
+func (s *UserService) Lookup(id int64) (models.User, error) {
+	var u models.User
+	err := s.DB.QueryRow(`
+		SELECT id, username, role, created_at FROM users WHERE id = ?
+	`, id).Scan(&u.ID, &u.Username, &u.Role, &u.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.User{}, auth.ErrUserGone
+	}
+	return u, err
+}
+
+func (s *UserService) Delete(id int) error {
+	res, err := s.DB.Exec(`DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Authenticate verifica usuario y contraseña.
+func (s *UserService) Authenticate(username, password string) (models.User, error) {
+	var u models.User
+	var hash string
+
+	err := s.DB.QueryRow(`
+		SELECT id, username, password_hash, role, created_at
+		FROM users WHERE username = ?
+	`, strings.TrimSpace(username)).Scan(&u.ID, &u.Username, &hash, &u.Role, &u.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.User{}, ErrBadCredentials
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+
+	ok, err := auth.CheckPassword(hash, password)
+	if err != nil {
+		return models.User{}, err
+	}
+	if !ok {
+		return models.User{}, ErrBadCredentials
+	}
+
+	return u, nil
+}
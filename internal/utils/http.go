@@ -1,20 +1,28 @@
-package utils
-
-import (
-	"encoding/json"
-	"net/http"
-)
-
-// RespondJSON writes a JSON response with the given status code and data.
-func RespondJSON(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(data)
-}
-
-// RespondError writes a JSON error response with the given status code and message.
-func RespondError(w http.ResponseWriter, status int, message string) {
-	RespondJSON(w, status, map[string]string{
-		"error": message,
-	})
-}
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// RespondJSON writes a JSON response with the given status code and data.
+func RespondJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+// RespondError writes a JSON error response with the given status code and message.
+func RespondError(w http.ResponseWriter, status int, message string) {
+	RespondJSON(w, status, map[string]string{
+		"error": message,
+	})
+}
+
+// RespondFile writes raw bytes as a downloadable file.
+func RespondFile(w http.ResponseWriter, contentType, filename string, data []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(200)
+	w.Write(data)
+}
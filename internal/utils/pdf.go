@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	pdfLinesPerPage = 60
+	pdfFontSize     = 10
+	pdfLeading      = 12
+)
+
+// TextPDF arma un PDF mínimo (A4, Courier) con una línea de texto por
+// renglón, partiendo en páginas cuando hace falta. Sirve para exportes
+// sencillos sin depender de una librería.
+func TextPDF(lines []string) []byte {
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+
+	var pages [][]string
+	for len(lines) > 0 {
+		n := min(pdfLinesPerPage, len(lines))
+		pages = append(pages, lines[:n])
+		lines = lines[n:]
+	}
+
+	// Objetos: 1 catálogo, 2 páginas, 3 fuente, luego página + contenido por cada una
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+i*2)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+	)
+
+	for i, page := range pages {
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d TL 40 800 Td\n", pdfFontSize, pdfLeading)
+		for _, l := range page {
+			content.WriteString("(")
+			content.Write(pdfEscape(l))
+			content.WriteString(") Tj T*\n")
+		}
+		content.WriteString("ET")
+
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+i*2),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()),
+		)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return out.Bytes()
+}
+
+// pdfEscape pasa el texto a WinAnsi (tildes y ñ incluidas) y escapa lo que
+// PDF no acepta dentro de un string literal.
+func pdfEscape(s string) []byte {
+	var b bytes.Buffer
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x80:
+			b.WriteByte(byte(r))
+		case r >= 0xA0 && r <= 0xFF:
+			b.WriteByte(byte(r))
+		case r == '€':
+			b.WriteByte(0x80)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.Bytes()
+}